@@ -2,16 +2,24 @@ package main
 
 import (
 	"bytes"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
 	"github.com/mattermost/mattermost/server/public/shared/mlog"
+	"github.com/pkg/errors"
 )
 
 const externalAPICacheTTL = 3600000
@@ -25,6 +33,14 @@ type StartMeetingRequest struct {
 	Topic     string `json:"topic"`
 	Personal  bool   `json:"personal"`
 	MeetingID int    `json:"meeting_id"`
+	Lobby     bool   `json:"lobby"`
+	Password  string `json:"password"`
+	E2EE      bool   `json:"e2ee"`
+}
+
+// InviteUsersRequest lists the users to invite to a meeting.
+type InviteUsersRequest struct {
+	UserIDs []string `json:"user_ids"`
 }
 
 type StartMeetingFromAction struct {
@@ -45,13 +61,95 @@ func (p *Plugin) ServeHTTP(_ *plugin.Context, w http.ResponseWriter, r *http.Req
 		p.handleStartMeeting(w, r)
 	case "/api/v1/config":
 		p.handleConfig(w, r)
+	case "/api/v1/jwks.json":
+		p.handleJWKS(w, r)
+	case "/api/v1/events":
+		p.handleEvents(w, r)
 	case "/jitsi_meet_external_api.js":
 		p.handleExternalAPIjs(w, r)
 	default:
+		if params, ok := matchRoute("/api/v1/meetings/{id}/end", path); ok {
+			p.handleEndMeeting(w, r, params[0])
+			return
+		}
+		if params, ok := matchRoute("/api/v1/channels/{channel_id}/meetings", path); ok {
+			p.handleChannelMeetings(w, r, params[0])
+			return
+		}
+		if params, ok := matchRoute("/api/v1/meetings/{id}/token", path); ok {
+			p.handleMeetingToken(w, r, params[0])
+			return
+		}
+		if params, ok := matchRoute("/api/v1/meetings/{id}/refresh", path); ok {
+			p.handleRefreshMeetingLink(w, r, params[0])
+			return
+		}
+		if params, ok := matchRoute("/api/v1/meetings/{id}/password", path); ok {
+			p.handleMeetingPassword(w, r, params[0])
+			return
+		}
+		if params, ok := matchRoute("/api/v1/meetings/{id}/revoke", path); ok {
+			p.handleRevokeMeeting(w, r, params[0])
+			return
+		}
+		if params, ok := matchRoute("/api/v1/meetings/{id}/invitations", path); ok {
+			p.handleMeetingInvitations(w, r, params[0])
+			return
+		}
+		if params, ok := matchRoute("/api/v1/meetings/{id}/invitations/{answer}", path); ok {
+			p.handleInvitationAnswer(w, r, params[0], params[1])
+			return
+		}
+		if params, ok := matchRoute("/api/v1/meetings/{id}/ring/{answer}", path); ok {
+			p.handleRingAnswer(w, r, params[0], params[1])
+			return
+		}
+		if params, ok := matchRoute("/api/v1/tokens/{jti}/status", path); ok {
+			p.handleTokenStatus(w, r, params[0])
+			return
+		}
+		if params, ok := matchRoute("/api/v1/meetings/{id}/invite.ics", path); ok {
+			p.handleMeetingInvite(w, r, params[0])
+			return
+		}
+		if params, ok := matchRoute("/api/v1/asap/{key}", path); ok && strings.HasSuffix(params[0], ".pem") {
+			p.handleASAPKey(w, r, strings.TrimSuffix(params[0], ".pem"))
+			return
+		}
+		if params, ok := matchRoute("/api/v1/calendar/{token}", path); ok && strings.HasSuffix(params[0], ".ics") {
+			p.handleCalendarFeed(w, r, strings.TrimSuffix(params[0], ".ics"))
+			return
+		}
 		http.NotFound(w, r)
 	}
 }
 
+// matchRoute matches path against a route such as "/api/v1/meetings/{id}/end" and returns the
+// values of the route parameters in the order they appear.
+func matchRoute(route string, path string) ([]string, bool) {
+	routeParts := strings.Split(route, "/")
+	pathParts := strings.Split(path, "/")
+	if len(routeParts) != len(pathParts) {
+		return nil, false
+	}
+
+	var params []string
+	for i, part := range routeParts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			if pathParts[i] == "" {
+				return nil, false
+			}
+			params = append(params, pathParts[i])
+			continue
+		}
+		if part != pathParts[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
 func (p *Plugin) handleConfig(w http.ResponseWriter, r *http.Request) {
 	userID := r.Header.Get("Mattermost-User-Id")
 
@@ -66,8 +164,10 @@ func (p *Plugin) handleConfig(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Internal error", http.StatusInternalServerError)
 		return
 	}
+	response := UserConfigResponse{UserConfig: config, UserSettings: savedSettings(config)}
+	p.applyDefaults(config)
 
-	b, err := json.Marshal(config)
+	b, err := json.Marshal(response)
 	if err != nil {
 		mlog.Error("Error marshaling the Config to json", mlog.Err(err))
 		http.Error(w, "Internal error", http.StatusInternalServerError)
@@ -198,14 +298,56 @@ func (p *Plugin) handleStartMeeting(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userConfig, err := p.getUserConfig(userID)
+	userConfig, channelConfig, err := p.resolveUserConfig(userID, channelID)
 	if err != nil {
 		mlog.Error("Error getting user config", mlog.Err(err))
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	if userConfig.NamingScheme == jitsiNameSchemeAsk && action.PostId == "" {
+	if err = p.checkStartPolicy(user, channel, channelConfig); err != nil {
+		if !isStartPolicyError(err) {
+			mlog.Error("Error checking the meeting policy", mlog.Err(err))
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, p.startPolicyMessage(p.b.GetUserLocalizer(userID), err), http.StatusForbidden)
+		return
+	}
+
+	options := startMeetingOptions{
+		Topic:    req.Topic,
+		RootID:   action.Context.RootID,
+		Lobby:    req.Lobby,
+		Password: req.Password,
+		E2EE:     req.E2EE,
+	}
+
+	// Users asking for a topic or access restrictions start a new meeting instead of joining the
+	// running one.
+	var running *Meeting
+	if !options.explicit() {
+		unlock, err := p.lockMeetingStart(channelID, action.Context.RootID)
+		if err != nil {
+			mlog.Error("Error locking the meeting start", mlog.Err(err))
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+		defer unlock()
+		options.Unlock = unlock
+
+		running, err = p.getJoinableMeeting(channelID, action.Context.RootID)
+		if err != nil && !errors.Is(err, errMeetingNotFound) {
+			mlog.Error("Error getting the running meeting", mlog.Err(err))
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// The options restricting the access to the meeting are not carried through the question, and
+	// channels with a fixed room have nothing to ask.
+	hasAccessOptions := req.Lobby || req.Password != "" || req.E2EE
+	if running == nil && *userConfig.NamingScheme == jitsiNameSchemeAsk && action.PostId == "" && !hasAccessOptions && !p.hasChannelRoom(channelConfig) {
 		err = p.askMeetingType(user, channel, "")
 		if err != nil {
 			mlog.Error("Error asking the user for meeting name type", mlog.Err(err))
@@ -220,24 +362,58 @@ func (p *Plugin) handleStartMeeting(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if running != nil {
+		// The question is not needed anymore once the meeting is running.
+		if action.PostId != "" {
+			p.deleteEphemeralPost(action.UserId, action.PostId)
+		}
+		p.sendMeetingAlreadyRunning(userID, running, channelID, action.Context.RootID)
+		p.writeStartMeetingResponse(w, running.Room)
+		return
+	}
+
+	if err = p.checkStartRateLimit(userID, channelID); err != nil {
+		var limitErr *rateLimitError
+		if !errors.As(err, &limitErr) {
+			mlog.Error("Error checking the rate limits", mlog.Err(err))
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(limitErr.RetryAfterSeconds()))
+		http.Error(w, p.rateLimitMessage(p.b.GetUserLocalizer(userID), limitErr), http.StatusTooManyRequests)
+		return
+	}
+
 	var meetingID string
-	if userConfig.NamingScheme == jitsiNameSchemeAsk && action.PostId != "" {
-		meetingID, err = p.startMeeting(user, channel, action.Context.MeetingID, action.Context.MeetingTopic, action.Context.Personal, "")
+	if *userConfig.NamingScheme == jitsiNameSchemeAsk && action.PostId != "" {
+		var meeting *Meeting
+		meeting, err = p.startMeetingWithOptions(user, channel, startMeetingOptions{
+			MeetingID: action.Context.MeetingID,
+			Topic:     action.Context.MeetingTopic,
+			Unlock:    options.Unlock,
+		})
 		if err != nil {
 			mlog.Error("Error starting a new meeting from ask response", mlog.Err(err))
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		meetingID = meeting.Room
 		p.deleteEphemeralPost(action.UserId, action.PostId)
 	} else {
-		meetingID, err = p.startMeeting(user, channel, "", req.Topic, req.Personal, action.Context.RootID)
+		var meeting *Meeting
+		meeting, err = p.startMeetingWithOptions(user, channel, options)
 		if err != nil {
 			mlog.Error("Error starting a new meeting", mlog.Err(err))
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		meetingID = meeting.Room
 	}
 
+	p.writeStartMeetingResponse(w, meetingID)
+}
+
+func (p *Plugin) writeStartMeetingResponse(w http.ResponseWriter, meetingID string) {
 	b, err := json.Marshal(map[string]string{"meeting_id": meetingID})
 	if err != nil {
 		mlog.Error("Error marshaling the MeetingID to json", mlog.Err(err))
@@ -269,6 +445,7 @@ func (p *Plugin) handleEnrichMeetingJwt(w http.ResponseWriter, r *http.Request)
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		mlog.Debug("Unable to read request body", mlog.Err(err))
 		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	var user *model.User
@@ -276,6 +453,7 @@ func (p *Plugin) handleEnrichMeetingJwt(w http.ResponseWriter, r *http.Request)
 	user, err = p.API.GetUser(userID)
 	if err != nil {
 		http.Error(w, err.Error(), err.StatusCode)
+		return
 	}
 
 	JWTMeeting := p.getConfiguration().JitsiJWT
@@ -286,6 +464,14 @@ func (p *Plugin) handleEnrichMeetingJwt(w http.ResponseWriter, r *http.Request)
 	}
 
 	meetingJWT, err2 := p.updateJwtUserInfo(req.Jwt, user)
+	if errors.Is(err2, errTokenRevoked) || errors.Is(err2, errNotChannelMember) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err2, errMeetingAlreadyEnded) {
+		http.Error(w, err2.Error(), http.StatusConflict)
+		return
+	}
 	if err2 != nil {
 		mlog.Error("Error updating JWT context", mlog.Err(err2))
 		http.Error(w, "Internal error", http.StatusInternalServerError)
@@ -304,3 +490,863 @@ func (p *Plugin) handleEnrichMeetingJwt(w http.ResponseWriter, r *http.Request)
 		mlog.Warn("Unable to write response body", mlog.String("handler", "handleEnrichMeetingJwt"), mlog.Err(err))
 	}
 }
+
+// handleMeetingToken mints a JWT for the requesting user to join a meeting, and sets their in-call
+// custom status if they opted in. Only members of the meeting channel and invited users are given
+// one.
+func (p *Plugin) handleMeetingToken(w http.ResponseWriter, r *http.Request, meetingID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := p.getConfiguration().IsValid(); err != nil {
+		mlog.Error("Invalid plugin configuration", mlog.Err(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	userID := r.Header.Get("Mattermost-User-Id")
+	if userID == "" || !p.getConfiguration().JitsiJWT {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	meeting, err := p.findMeeting(meetingID)
+	if errors.Is(err, errMeetingNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		mlog.Error("Error getting the meeting", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	if !p.canJoinMeeting(userID, meeting) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		http.Error(w, appErr.Error(), appErr.StatusCode)
+		return
+	}
+
+	token, validUntil, err := p.issueMeetingToken(user, meeting)
+	if errors.Is(err, errMeetingAlreadyEnded) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		mlog.Error("Error issuing the meeting token", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	// The webapp asks for the token of the user when they click to join the meeting.
+	if err = p.setInCallStatus(user, meeting); err != nil {
+		mlog.Warn("Unable to set the in-call custom status", mlog.String("user_id", user.Id), mlog.Err(err))
+	}
+
+	b, err := json.Marshal(MeetingTokenResponse{JWT: token, ExpiresAt: validUntil.UnixMilli()})
+	if err != nil {
+		mlog.Error("Error marshaling the JWT json", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	if err != nil {
+		mlog.Warn("Unable to write response body", mlog.String("handler", "handleMeetingToken"), mlog.Err(err))
+	}
+}
+
+// handleRefreshMeetingLink mints a new JWT for the requesting user to join a running meeting,
+// bringing an expired meeting back, and refreshes the meeting post. It backs the "Refresh link"
+// post action, which answers with an ephemeral join link, and is also usable on its own.
+func (p *Plugin) handleRefreshMeetingLink(w http.ResponseWriter, r *http.Request, meetingID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := p.getConfiguration().IsValid(); err != nil {
+		mlog.Error("Invalid plugin configuration", mlog.Err(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	userID := r.Header.Get("Mattermost-User-Id")
+	if userID == "" || !p.getConfiguration().JitsiJWT {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Requests from the post action identify the post, plain API requests have no body.
+	var action model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&action); err != nil && !errors.Is(err, io.EOF) {
+		mlog.Debug("Unable to decode the refresh link request", mlog.Err(err))
+		http.Error(w, "Unable to decode your request", http.StatusBadRequest)
+		return
+	}
+
+	meeting, err := p.findMeeting(meetingID)
+	if errors.Is(err, errMeetingNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		mlog.Error("Error getting the meeting", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	if !p.canJoinMeeting(userID, meeting) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		http.Error(w, appErr.Error(), appErr.StatusCode)
+		return
+	}
+
+	token, validUntil, err := p.issueMeetingToken(user, meeting)
+	if errors.Is(err, errMeetingAlreadyEnded) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		mlog.Error("Error issuing the meeting token", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	if err = p.setInCallStatus(user, meeting); err != nil {
+		mlog.Warn("Unable to set the in-call custom status", mlog.String("user_id", user.Id), mlog.Err(err))
+	}
+
+	if err = p.refreshMeetingPost(meeting); err != nil {
+		// The new token is usable even if the post still shows the previous link.
+		mlog.Warn("Unable to refresh the meeting post", mlog.String("meeting_id", meeting.ID), mlog.Err(err))
+	}
+
+	var response any = MeetingTokenResponse{JWT: token, ExpiresAt: validUntil.UnixMilli()}
+	if action.PostId != "" {
+		l := p.b.GetUserLocalizer(userID)
+		userConfig, channelConfig, err := p.resolveUserConfig(userID, meeting.ChannelID)
+		if err != nil {
+			mlog.Warn("Unable to get the user config", mlog.Err(err))
+		}
+		meetingURL := p.getMeetingLink(meeting.Room) + "?jwt=" + url.QueryEscape(token) + p.meetingURLConfig(meeting, channelConfig, userConfig).Hash()
+		response = model.PostActionIntegrationResponse{
+			EphemeralText: p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "jitsi.refresh_link.join_meeting",
+					Other: "Your link to join the meeting, valid until {{.Datetime}}: [Join Meeting]({{.MeetingURL}})",
+				},
+				TemplateData: map[string]string{
+					"Datetime":   validUntil.In(user.GetTimezoneLocation()).Format("Mon Jan 2 15:04 MST 2006"),
+					"MeetingURL": meetingURL,
+				},
+			}),
+		}
+	}
+
+	b, err := json.Marshal(response)
+	if err != nil {
+		mlog.Error("Error marshaling the refresh link response to json", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	if err != nil {
+		mlog.Warn("Unable to write response body", mlog.String("handler", "handleRefreshMeetingLink"), mlog.Err(err))
+	}
+}
+
+func (p *Plugin) handleEndMeeting(w http.ResponseWriter, r *http.Request, meetingID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Header.Get("Mattermost-User-Id")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	meeting, err := p.findMeeting(meetingID)
+	if errors.Is(err, errMeetingNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		mlog.Error("Error getting the meeting", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	if !p.canManageMeeting(userID, meeting) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	meeting, err = p.endMeeting(meeting.ID)
+	if errors.Is(err, errMeetingAlreadyEnded) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		mlog.Error("Error ending the meeting", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	meeting.Sanitize()
+	b, err := json.Marshal(meeting)
+	if err != nil {
+		mlog.Error("Error marshaling the meeting to json", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	if err != nil {
+		mlog.Warn("Unable to write response body", mlog.String("handler", "handleEndMeeting"), mlog.Err(err))
+	}
+}
+
+// handleMeetingPassword sends the password of a meeting to the requesting channel member in an
+// ephemeral post. It backs the "Show password" post action.
+func (p *Plugin) handleMeetingPassword(w http.ResponseWriter, r *http.Request, meetingID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Header.Get("Mattermost-User-Id")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	meeting, err := p.findMeeting(meetingID)
+	if errors.Is(err, errMeetingNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		mlog.Error("Error getting the meeting", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	if _, appErr := p.API.GetChannelMember(meeting.ChannelID, userID); appErr != nil {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	if meeting.Password == "" {
+		http.NotFound(w, r)
+		return
+	}
+	p.sendMeetingPassword(meeting, userID)
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write([]byte("{}"))
+	if err != nil {
+		mlog.Warn("Unable to write response body", mlog.String("handler", "handleMeetingPassword"), mlog.Err(err))
+	}
+}
+
+// handleRingAnswer records the answer of a user called when a meeting started in a direct or group
+// message channel, "accept" or "decline". It backs the actions of the call notification, which
+// answer an accepted call with an ephemeral join link.
+func (p *Plugin) handleRingAnswer(w http.ResponseWriter, r *http.Request, meetingID string, answer string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Header.Get("Mattermost-User-Id")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	state := RingStateAccepted
+	switch answer {
+	case "accept":
+	case "decline":
+		state = RingStateDeclined
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	// Requests from the post actions identify the post, plain API requests have no body.
+	var action model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&action); err != nil && !errors.Is(err, io.EOF) {
+		mlog.Debug("Unable to decode the ring answer request", mlog.Err(err))
+		http.Error(w, "Unable to decode your request", http.StatusBadRequest)
+		return
+	}
+
+	meeting, err := p.findMeeting(meetingID)
+	if errors.Is(err, errMeetingNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		mlog.Error("Error getting the meeting", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	meeting, err = p.answerRing(meeting.ID, userID, state)
+	if errors.Is(err, errNotRung) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, errMeetingAlreadyEnded) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		mlog.Error("Error answering the call", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	response := model.PostActionIntegrationResponse{}
+	if state == RingStateAccepted && action.PostId != "" {
+		meetingURL, err := p.meetingJoinURL(userID, meeting)
+		if err != nil {
+			mlog.Error("Error building the link to join the meeting", mlog.Err(err))
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+		response.EphemeralText = p.b.LocalizeWithConfig(p.b.GetUserLocalizer(userID), &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.ring.join_meeting",
+				Other: "[Join Meeting]({{.MeetingURL}})",
+			},
+			TemplateData: map[string]string{"MeetingURL": meetingURL},
+		})
+	}
+
+	b, err := json.Marshal(response)
+	if err != nil {
+		mlog.Error("Error marshaling the ring answer response to json", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	if err != nil {
+		mlog.Warn("Unable to write response body", mlog.String("handler", "handleRingAnswer"), mlog.Err(err))
+	}
+}
+
+// handleMeetingInvitations lists the invitations to a meeting, with GET, or invites users to it,
+// with POST. Only the meeting creator and channel admins can invite users, who then can join the
+// meeting without being members of its channel.
+func (p *Plugin) handleMeetingInvitations(w http.ResponseWriter, r *http.Request, meetingID string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Header.Get("Mattermost-User-Id")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	meeting, err := p.findMeeting(meetingID)
+	if errors.Is(err, errMeetingNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		mlog.Error("Error getting the meeting", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	if !p.canManageMeeting(userID, meeting) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		var req InviteUsersRequest
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.UserIDs) == 0 {
+			http.Error(w, "Unable to decode your request", http.StatusBadRequest)
+			return
+		}
+
+		var users []*model.User
+		for _, inviteeID := range req.UserIDs {
+			user, appErr := p.API.GetUser(inviteeID)
+			if appErr != nil || user.IsBot || user.DeleteAt > 0 {
+				http.Error(w, "Unknown user "+inviteeID, http.StatusBadRequest)
+				return
+			}
+			users = append(users, user)
+		}
+
+		inviter, appErr := p.API.GetUser(userID)
+		if appErr != nil {
+			http.Error(w, appErr.Error(), appErr.StatusCode)
+			return
+		}
+
+		meeting, err = p.inviteUsers(inviter, meeting, users)
+		if errors.Is(err, errMeetingAlreadyEnded) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			mlog.Error("Error inviting the users to the meeting", mlog.Err(err))
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	invitations := meeting.Invitations
+	if invitations == nil {
+		invitations = []*MeetingInvitation{}
+	}
+	b, err := json.Marshal(invitations)
+	if err != nil {
+		mlog.Error("Error marshaling the invitations to json", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	if err != nil {
+		mlog.Warn("Unable to write response body", mlog.String("handler", "handleMeetingInvitations"), mlog.Err(err))
+	}
+}
+
+// handleInvitationAnswer records the answer of a user invited to a meeting, "accept" or
+// "decline". It backs the actions of the invitation, which answer an accepted invitation with an
+// ephemeral join link.
+func (p *Plugin) handleInvitationAnswer(w http.ResponseWriter, r *http.Request, meetingID string, answer string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Header.Get("Mattermost-User-Id")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	state := InvitationStateAccepted
+	switch answer {
+	case "accept":
+	case "decline":
+		state = InvitationStateDeclined
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	// Requests from the post actions identify the post, plain API requests have no body.
+	var action model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&action); err != nil && !errors.Is(err, io.EOF) {
+		mlog.Debug("Unable to decode the invitation answer request", mlog.Err(err))
+		http.Error(w, "Unable to decode your request", http.StatusBadRequest)
+		return
+	}
+
+	meeting, err := p.findMeeting(meetingID)
+	if errors.Is(err, errMeetingNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		mlog.Error("Error getting the meeting", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	meeting, err = p.answerInvitation(meeting.ID, userID, state)
+	if errors.Is(err, errNotInvited) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, errMeetingAlreadyEnded) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		mlog.Error("Error answering the invitation", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	response := model.PostActionIntegrationResponse{}
+	if state == InvitationStateAccepted && action.PostId != "" {
+		meetingURL, err := p.meetingJoinURL(userID, meeting)
+		if err != nil {
+			mlog.Error("Error building the link to join the meeting", mlog.Err(err))
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+		response.EphemeralText = p.b.LocalizeWithConfig(p.b.GetUserLocalizer(userID), &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.invitation.join_meeting",
+				Other: "[Join Meeting]({{.MeetingURL}})",
+			},
+			TemplateData: map[string]string{"MeetingURL": meetingURL},
+		})
+	}
+
+	b, err := json.Marshal(response)
+	if err != nil {
+		mlog.Error("Error marshaling the invitation answer response to json", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	if err != nil {
+		mlog.Warn("Unable to write response body", mlog.String("handler", "handleInvitationAnswer"), mlog.Err(err))
+	}
+}
+
+// handleRevokeMeeting revokes every token issued so far for a meeting. Channel members can still
+// request a new token afterwards, unless the meeting has ended.
+func (p *Plugin) handleRevokeMeeting(w http.ResponseWriter, r *http.Request, meetingID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Header.Get("Mattermost-User-Id")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	meeting, err := p.findMeeting(meetingID)
+	if errors.Is(err, errMeetingNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		mlog.Error("Error getting the meeting", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	if !p.canManageMeeting(userID, meeting) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	revoked, err := p.revokeMeetingTokens(meeting.ID)
+	if err != nil {
+		mlog.Error("Error revoking the meeting tokens", mlog.String("meeting_id", meeting.ID), mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	b, err := json.Marshal(map[string]int{"revoked": revoked})
+	if err != nil {
+		mlog.Error("Error marshaling the revocation result to json", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	if err != nil {
+		mlog.Warn("Unable to write response body", mlog.String("handler", "handleRevokeMeeting"), mlog.Err(err))
+	}
+}
+
+// handleTokenStatus tells whether a token minted by the plugin was revoked. It is meant for the
+// Jitsi server, so it does not require a Mattermost session: token IDs are random and the response
+// carries nothing else about the meeting.
+func (p *Plugin) handleTokenStatus(w http.ResponseWriter, r *http.Request, tokenID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	issued, err := p.getIssuedToken(tokenID)
+	if errors.Is(err, errTokenNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		mlog.Error("Error getting the issued token", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	b, err := json.Marshal(TokenStatus{
+		ID:        issued.ID,
+		Status:    issued.Status(time.Now()),
+		ExpiresAt: issued.ExpiresAt,
+		RevokedAt: issued.RevokedAt,
+	})
+	if err != nil {
+		mlog.Error("Error marshaling the token status to json", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	if err != nil {
+		mlog.Warn("Unable to write response body", mlog.String("handler", "handleTokenStatus"), mlog.Err(err))
+	}
+}
+
+func (p *Plugin) handleChannelMeetings(w http.ResponseWriter, r *http.Request, channelID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Header.Get("Mattermost-User-Id")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := defaultHistoryLength
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxHistoryLength)
+	}
+
+	meetings, err := p.getChannelMeetingHistory(channelID, userID, limit)
+	if errors.Is(err, errNotChannelMember) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		mlog.Error("Error getting the channel meetings", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	for _, meeting := range meetings {
+		meeting.Sanitize()
+	}
+	b, err := json.Marshal(meetings)
+	if err != nil {
+		mlog.Error("Error marshaling the meetings to json", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	if err != nil {
+		mlog.Warn("Unable to write response body", mlog.String("handler", "handleChannelMeetings"), mlog.Err(err))
+	}
+}
+
+func (p *Plugin) handleMeetingInvite(w http.ResponseWriter, r *http.Request, meetingID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Header.Get("Mattermost-User-Id")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	meeting, err := p.findMeeting(meetingID)
+	if errors.Is(err, errMeetingNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		mlog.Error("Error getting the meeting", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	if !p.canJoinMeeting(userID, meeting) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	calendar := icsCalendar{Events: []icsEvent{p.meetingEvent(meeting)}}
+	w.Header().Set("Content-Type", icsContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="invite.ics"`)
+	_, err = w.Write([]byte(calendar.String()))
+	if err != nil {
+		mlog.Warn("Unable to write response body", mlog.String("handler", "handleMeetingInvite"), mlog.Err(err))
+	}
+}
+
+// handleCalendarFeed serves the calendar feed of a user. Calendar applications fetch it without a
+// Mattermost session, the secret token in the URL identifies the user instead.
+func (p *Plugin) handleCalendarFeed(w http.ResponseWriter, r *http.Request, token string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := p.getCalendarTokenUser(token)
+	if errors.Is(err, errCalendarTokenNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		mlog.Error("Error getting the calendar token", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil || user.DeleteAt != 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	events, err := p.getUserCalendarEvents(userID)
+	if err != nil {
+		mlog.Error("Error getting the calendar events", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	calendar := icsCalendar{Name: "Jitsi", Events: events}
+	w.Header().Set("Content-Type", icsContentType)
+	_, err = w.Write([]byte(calendar.String()))
+	if err != nil {
+		mlog.Warn("Unable to write response body", mlog.String("handler", "handleCalendarFeed"), mlog.Err(err))
+	}
+}
+
+// handleJWKS serves the public keys of the tokens signed with an asymmetric algorithm. The Jitsi
+// server fetches them without a Mattermost session.
+func (p *Plugin) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	keys, err := p.getPublishedJWTKeys()
+	if err != nil {
+		mlog.Error("Error getting the published JWT keys", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	b, err := json.Marshal(JWKS{Keys: keys})
+	if err != nil {
+		mlog.Error("Error marshaling the JWKS to json", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	if err != nil {
+		mlog.Warn("Unable to write response body", mlog.String("handler", "handleJWKS"), mlog.Err(err))
+	}
+}
+
+// handleASAPKey serves a public key in the PEM format, as fetched by the Prosody
+// token_verification module from its asap_key_server.
+func (p *Plugin) handleASAPKey(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	keys, err := p.getPublishedJWTKeys()
+	if err != nil {
+		mlog.Error("Error getting the published JWT keys", mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	for _, key := range keys {
+		if asapKeyName(key.KeyID) != name {
+			continue
+		}
+
+		public, err := key.PublicKey()
+		if err != nil {
+			mlog.Error("Error decoding the JWT key", mlog.Err(err))
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+		der, err := x509.MarshalPKIXPublicKey(public)
+		if err != nil {
+			mlog.Error("Error encoding the JWT key", mlog.Err(err))
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-pem-file")
+		_, err = w.Write(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+		if err != nil {
+			mlog.Warn("Unable to write response body", mlog.String("handler", "handleASAPKey"), mlog.Err(err))
+		}
+		return
+	}
+
+	http.NotFound(w, r)
+}
+
+// handleEvents receives the events of the Jitsi server, see handleJitsiEvent. The endpoint is
+// disabled until an events secret is configured.
+func (p *Plugin) handleEvents(w http.ResponseWriter, r *http.Request) {
+	secret := p.getConfiguration().JitsiEventsSecret
+	if secret == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxEventSize))
+	if err != nil {
+		http.Error(w, "Unable to read the event", http.StatusBadRequest)
+		return
+	}
+
+	if err = verifyEventSignature(secret, body, r.Header.Get(eventSignatureHeader), r.Header.Get(eventTimestampHeader), r.Header.Get("Authorization")); err != nil {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	var event jitsiEvent
+	if err = json.Unmarshal(body, &event); err != nil {
+		http.Error(w, "Unable to decode the event", http.StatusBadRequest)
+		return
+	}
+
+	if err = p.handleJitsiEvent(&event); err != nil {
+		mlog.Error("Error handling the Jitsi event", mlog.String("event", event.EventName), mlog.String("room", event.RoomName), mlog.Err(err))
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
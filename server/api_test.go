@@ -0,0 +1,632 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchRoute(t *testing.T) {
+	params, ok := matchRoute("/api/v1/meetings/{id}/end", "/api/v1/meetings/abc/end")
+	require.True(t, ok)
+	require.Equal(t, []string{"abc"}, params)
+
+	_, ok = matchRoute("/api/v1/meetings/{id}/end", "/api/v1/meetings//end")
+	require.False(t, ok)
+
+	_, ok = matchRoute("/api/v1/meetings/{id}/end", "/api/v1/meetings/abc/other")
+	require.False(t, ok)
+
+	_, ok = matchRoute("/api/v1/meetings/{id}/end", "/api/v1/meetings/abc/end/more")
+	require.False(t, ok)
+}
+
+func TestHandleEndMeeting(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL: "http://test",
+		},
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("GetBundlePath").Return("..", nil)
+	config := model.Config{}
+	config.SetDefaults()
+	apiMock.On("GetConfig").Return(&config, nil).Maybe()
+	mockMeetingStore(&apiMock)
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	meeting := &Meeting{Room: "test-room", ChannelID: "test-channel", CreatorID: "creator"}
+	require.Nil(t, p.createMeeting(meeting))
+
+	serve := func(method string, userID string, meetingID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, "/api/v1/meetings/"+meetingID+"/end", nil)
+		if userID != "" {
+			r.Header.Set("Mattermost-User-Id", userID)
+		}
+		p.ServeHTTP(&plugin.Context{}, w, r)
+		return w
+	}
+
+	t.Run("wrong method", func(t *testing.T) {
+		require.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodGet, "creator", meeting.ID).Code)
+	})
+
+	t.Run("anonymous user", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, serve(http.MethodPost, "", meeting.ID).Code)
+	})
+
+	t.Run("unknown meeting", func(t *testing.T) {
+		require.Equal(t, http.StatusNotFound, serve(http.MethodPost, "creator", "unknown").Code)
+	})
+
+	t.Run("regular channel member", func(t *testing.T) {
+		apiMock.On("HasPermissionToChannel", "member", "test-channel", model.PermissionManageChannelRoles).Return(false)
+		require.Equal(t, http.StatusForbidden, serve(http.MethodPost, "member", meeting.ID).Code)
+	})
+
+	t.Run("channel admin", func(t *testing.T) {
+		apiMock.On("HasPermissionToChannel", "admin", "test-channel", model.PermissionManageChannelRoles).Return(true)
+		require.Equal(t, http.StatusOK, serve(http.MethodPost, "admin", meeting.ID).Code)
+	})
+
+	t.Run("meeting already ended", func(t *testing.T) {
+		require.Equal(t, http.StatusConflict, serve(http.MethodPost, "creator", meeting.ID).Code)
+	})
+}
+
+func TestHandleMeetingToken(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL:           "http://test",
+			JitsiJWT:           true,
+			JitsiAppID:         "test-app-id",
+			JitsiAppSecret:     "test-secret",
+			JitsiLinkValidTime: 5,
+		},
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("GetBundlePath").Return("..", nil)
+	config := model.Config{}
+	config.SetDefaults()
+	config.PrivacySettings.ShowEmailAddress = model.NewPointer(false)
+	apiMock.On("GetConfig").Return(&config, nil)
+	mockMeetingStore(&apiMock)
+	store := mockKVStore(&apiMock, "config_", callStatusKeyPrefix)
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	meeting := &Meeting{Room: "test-room", ChannelID: "test-channel", CreatorID: "creator"}
+	require.Nil(t, p.createMeeting(meeting))
+
+	apiMock.On("GetChannelMember", "test-channel", "member").Return(&model.ChannelMember{}, nil)
+	apiMock.On("GetChannelMember", "test-channel", "outsider").Return(nil, &model.AppError{})
+	apiMock.On("GetUser", "member").Return(&model.User{Id: "member", Username: "member-username", Email: "member@test"}, nil)
+	apiMock.On("HasPermissionToChannel", "member", "test-channel", model.PermissionManageChannelRoles).Return(false)
+
+	serve := func(method string, userID string, meetingID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, "/api/v1/meetings/"+meetingID+"/token", nil)
+		if userID != "" {
+			r.Header.Set("Mattermost-User-Id", userID)
+		}
+		p.ServeHTTP(&plugin.Context{}, w, r)
+		return w
+	}
+
+	t.Run("wrong method", func(t *testing.T) {
+		require.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodPost, "member", meeting.ID).Code)
+	})
+
+	t.Run("anonymous user", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "", meeting.ID).Code)
+	})
+
+	t.Run("unknown meeting", func(t *testing.T) {
+		require.Equal(t, http.StatusNotFound, serve(http.MethodGet, "member", "unknown").Code)
+	})
+
+	t.Run("not a channel member", func(t *testing.T) {
+		require.Equal(t, http.StatusForbidden, serve(http.MethodGet, "outsider", meeting.ID).Code)
+	})
+
+	t.Run("channel member by room name", func(t *testing.T) {
+		before := time.Now()
+		w := serve(http.MethodGet, "member", "test-room")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response MeetingTokenResponse
+		require.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.GreaterOrEqual(t, response.ExpiresAt, before.Add(5*time.Minute).UnixMilli())
+
+		claims, err := verifyJwt("test-secret", response.JWT)
+		require.Nil(t, err)
+		require.Equal(t, "test-room", claims.Room)
+		require.Equal(t, "test", claims.Subject)
+		require.Equal(t, "member", claims.Context.User.ID)
+		require.Equal(t, "member-username", claims.Context.User.Name)
+		// The email address is hidden by the privacy settings.
+		require.Empty(t, claims.Context.User.Email)
+		require.False(t, claims.Context.User.Moderator)
+		require.Equal(t, jwtAffiliationMember, claims.Context.User.Affiliation)
+
+		stored, err := p.getMeeting(meeting.ID)
+		require.Nil(t, err)
+		require.Equal(t, response.ExpiresAt, stored.JWTExpiresAt)
+	})
+
+	t.Run("meeting creator", func(t *testing.T) {
+		apiMock.On("GetChannelMember", "test-channel", "creator").Return(&model.ChannelMember{}, nil)
+		apiMock.On("GetUser", "creator").Return(&model.User{Id: "creator", Username: "creator-username"}, nil)
+
+		w := serve(http.MethodGet, "creator", meeting.ID)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response MeetingTokenResponse
+		require.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		claims, err := verifyJwt("test-secret", response.JWT)
+		require.Nil(t, err)
+		require.True(t, claims.Context.User.Moderator)
+		require.Equal(t, jwtAffiliationOwner, claims.Context.User.Affiliation)
+	})
+
+	t.Run("in-call status", func(t *testing.T) {
+		store["config_member"] = []byte(`{"in_call_status": true}`)
+		defer delete(store, "config_member")
+		apiMock.On("UpdateUserCustomStatus", "member", mock.MatchedBy(func(status *model.CustomStatus) bool {
+			return status.Emoji == inCallStatusEmoji
+		})).Return(nil).Once()
+
+		require.Equal(t, http.StatusOK, serve(http.MethodGet, "member", meeting.ID).Code)
+
+		stored, err := p.getMeeting(meeting.ID)
+		require.Nil(t, err)
+		require.Equal(t, []string{"member"}, stored.StatusUserIDs)
+		require.Contains(t, store, callStatusKeyPrefix+"member")
+	})
+
+	t.Run("meeting already ended", func(t *testing.T) {
+		_, err := p.updateMeeting(meeting.ID, func(meeting *Meeting) error {
+			meeting.State = MeetingStateEnded
+			return nil
+		})
+		require.Nil(t, err)
+		require.Equal(t, http.StatusConflict, serve(http.MethodGet, "member", meeting.ID).Code)
+	})
+
+	t.Run("JWT authentication disabled", func(t *testing.T) {
+		p.configuration.JitsiJWT = false
+		defer func() { p.configuration.JitsiJWT = true }()
+		require.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "member", meeting.ID).Code)
+	})
+}
+
+func TestHandleRefreshMeetingLink(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL:           "http://test",
+			JitsiJWT:           true,
+			JitsiAppID:         "test-app-id",
+			JitsiAppSecret:     "test-secret",
+			JitsiLinkValidTime: 5,
+		},
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("GetBundlePath").Return("..", nil)
+	config := model.Config{}
+	config.SetDefaults()
+	apiMock.On("GetConfig").Return(&config, nil)
+	mockMeetingStore(&apiMock)
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	// The meeting link expired a while ago.
+	meeting := &Meeting{Room: "test-room", ChannelID: "test-channel", CreatorID: "creator", Topic: "Test topic", PostID: "test-post",
+		JWTExpiresAt: time.Now().Add(-time.Hour).UnixMilli()}
+	require.Nil(t, p.createMeeting(meeting))
+
+	apiMock.On("GetChannelMember", "test-channel", "member").Return(&model.ChannelMember{}, nil)
+	apiMock.On("GetChannelMember", "test-channel", "outsider").Return(nil, &model.AppError{})
+	apiMock.On("GetUser", "member").Return(&model.User{Id: "member", Username: "member", Locale: "en"}, nil)
+	apiMock.On("HasPermissionToChannel", "member", "test-channel", model.PermissionManageChannelRoles).Return(false)
+	userConfig, err := json.Marshal(&UserConfig{StartWithAudioMuted: model.NewPointer(true), InCallStatus: true})
+	require.Nil(t, err)
+	apiMock.On("KVGet", "config_member").Return(userConfig, nil)
+	mockKVStore(&apiMock, callStatusKeyPrefix)
+	apiMock.On("UpdateUserCustomStatus", "member", mock.Anything).Return(nil)
+
+	// A post created before participants had their own tokens.
+	apiMock.On("GetPost", "test-post").Return(&model.Post{Id: "test-post", Type: "custom_jitsi", Props: model.StringInterface{
+		"meeting_id":              "test-room",
+		"meeting_jwt":             "expired-token",
+		"jwt_meeting_valid_until": 1234,
+	}}, nil)
+	apiMock.On("UpdatePost", mock.MatchedBy(func(post *model.Post) bool {
+		attachment := post.GetProp("attachments").([]*model.SlackAttachment)[0]
+		return post.GetProp("meeting_jwt") == nil && post.GetProp("jwt_meeting_valid_until") == nil &&
+			post.GetProp("jwt_meeting") == true && post.GetProp("meeting_id") == "test-room" && post.GetProp("meeting_record_id") == meeting.ID &&
+			attachment.Title == "Test topic" && strings.Contains(attachment.Text, "[Join Meeting](http://test/test-room#") &&
+			len(attachment.Actions) == 1
+	})).Return(&model.Post{}, nil)
+
+	serve := func(method string, userID string, meetingID string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, "/api/v1/meetings/"+meetingID+"/refresh", strings.NewReader(body))
+		if userID != "" {
+			r.Header.Set("Mattermost-User-Id", userID)
+		}
+		p.ServeHTTP(&plugin.Context{}, w, r)
+		return w
+	}
+
+	t.Run("wrong method", func(t *testing.T) {
+		require.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodGet, "member", meeting.ID, "").Code)
+	})
+
+	t.Run("anonymous user", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, serve(http.MethodPost, "", meeting.ID, "").Code)
+	})
+
+	t.Run("unknown meeting", func(t *testing.T) {
+		require.Equal(t, http.StatusNotFound, serve(http.MethodPost, "member", "unknown", "").Code)
+	})
+
+	t.Run("not a channel member", func(t *testing.T) {
+		require.Equal(t, http.StatusForbidden, serve(http.MethodPost, "outsider", meeting.ID, "").Code)
+	})
+
+	t.Run("API request", func(t *testing.T) {
+		before := time.Now()
+		w := serve(http.MethodPost, "member", meeting.ID, "")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response MeetingTokenResponse
+		require.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.GreaterOrEqual(t, response.ExpiresAt, before.Add(5*time.Minute).UnixMilli())
+		claims, err := verifyJwt("test-secret", response.JWT)
+		require.Nil(t, err)
+		require.Equal(t, "test-room", claims.Room)
+		require.Equal(t, "member", claims.Context.User.ID)
+
+		// The meeting is no longer expired.
+		stored, err := p.getMeeting(meeting.ID)
+		require.Nil(t, err)
+		require.Equal(t, MeetingStateActive, stored.State)
+		require.Equal(t, []string{"member"}, stored.StatusUserIDs)
+	})
+
+	t.Run("post action", func(t *testing.T) {
+		w := serve(http.MethodPost, "member", "test-room", `{"user_id": "member", "post_id": "test-post", "context": {}}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response model.PostActionIntegrationResponse
+		require.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Contains(t, response.EphemeralText, "[Join Meeting](http://test/test-room?jwt=")
+		// The link carries the settings of the user.
+		require.Contains(t, response.EphemeralText, "#config.subject=%22Test%20topic%22&config.callDisplayName=%22Test%20topic%22&config.startWithAudioMuted=true)")
+	})
+
+	t.Run("meeting already ended", func(t *testing.T) {
+		_, err := p.updateMeeting(meeting.ID, func(meeting *Meeting) error {
+			meeting.State = MeetingStateEnded
+			return nil
+		})
+		require.Nil(t, err)
+		require.Equal(t, http.StatusConflict, serve(http.MethodPost, "member", meeting.ID, "").Code)
+	})
+}
+
+func TestHandleMeetingPassword(t *testing.T) {
+	p := Plugin{configuration: &configuration{JitsiURL: "http://test"}, botID: "test-bot-id"}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("GetBundlePath").Return("..", nil)
+	mockMeetingStore(&apiMock)
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	meeting := &Meeting{Room: "test-room", ChannelID: "test-channel", CreatorID: "creator", Password: "s3cret"}
+	require.Nil(t, p.createMeeting(meeting))
+	open := &Meeting{Room: "open-room", ChannelID: "test-channel", CreatorID: "creator"}
+	require.Nil(t, p.createMeeting(open))
+
+	apiMock.On("GetChannelMember", "test-channel", "member").Return(&model.ChannelMember{}, nil)
+	apiMock.On("GetChannelMember", "test-channel", "outsider").Return(nil, &model.AppError{})
+	apiMock.On("GetUser", "member").Return(&model.User{Id: "member", Locale: "en"}, nil)
+
+	serve := func(userID string, meetingID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/meetings/"+meetingID+"/password", nil)
+		if userID != "" {
+			r.Header.Set("Mattermost-User-Id", userID)
+		}
+		p.ServeHTTP(&plugin.Context{}, w, r)
+		return w
+	}
+
+	t.Run("anonymous user", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, serve("", meeting.ID).Code)
+	})
+
+	t.Run("not a channel member", func(t *testing.T) {
+		require.Equal(t, http.StatusForbidden, serve("outsider", meeting.ID).Code)
+	})
+
+	t.Run("meeting without password", func(t *testing.T) {
+		require.Equal(t, http.StatusNotFound, serve("member", open.ID).Code)
+	})
+
+	t.Run("channel member", func(t *testing.T) {
+		apiMock.On("SendEphemeralPost", "member", mock.MatchedBy(func(post *model.Post) bool {
+			return post.UserId == "test-bot-id" && post.ChannelId == "test-channel" &&
+				post.Message == "The password of the meeting `test-room` is `s3cret`. Please do not share it outside of this channel."
+		})).Return(nil).Once()
+		require.Equal(t, http.StatusOK, serve("member", "test-room").Code)
+	})
+}
+
+func TestHandleRevokeMeeting(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL:           "http://test",
+			JitsiJWT:           true,
+			JitsiAppID:         "test-app-id",
+			JitsiAppSecret:     "test-secret",
+			JitsiLinkValidTime: 5,
+		},
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	config := model.Config{}
+	config.SetDefaults()
+	apiMock.On("GetConfig").Return(&config, nil)
+	mockMeetingStore(&apiMock)
+	p.SetAPI(&apiMock)
+
+	meeting := &Meeting{Room: "test-room", ChannelID: "test-channel", CreatorID: "creator"}
+	require.Nil(t, p.createMeeting(meeting))
+	apiMock.On("HasPermissionToChannel", "member", "test-channel", model.PermissionManageChannelRoles).Return(false)
+
+	token, _, err := p.issueMeetingToken(&model.User{Id: "member", Username: "member"}, meeting)
+	require.Nil(t, err)
+	claims, err := verifyJwt("test-secret", token)
+	require.Nil(t, err)
+
+	serve := func(method string, path string, userID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, path, nil)
+		if userID != "" {
+			r.Header.Set("Mattermost-User-Id", userID)
+		}
+		p.ServeHTTP(&plugin.Context{}, w, r)
+		return w
+	}
+
+	status := func(t *testing.T, tokenID string) TokenStatus {
+		w := serve(http.MethodGet, "/api/v1/tokens/"+tokenID+"/status", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var response TokenStatus
+		require.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Equal(t, tokenID, response.ID)
+		return response
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		require.Equal(t, tokenStatusValid, status(t, claims.ID).Status)
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		require.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/api/v1/tokens/unknown/status", "").Code)
+	})
+
+	t.Run("wrong method", func(t *testing.T) {
+		require.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodGet, "/api/v1/meetings/"+meeting.ID+"/revoke", "creator").Code)
+	})
+
+	t.Run("anonymous user", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, serve(http.MethodPost, "/api/v1/meetings/"+meeting.ID+"/revoke", "").Code)
+	})
+
+	t.Run("unknown meeting", func(t *testing.T) {
+		require.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/api/v1/meetings/unknown/revoke", "creator").Code)
+	})
+
+	t.Run("not allowed to manage the meeting", func(t *testing.T) {
+		require.Equal(t, http.StatusForbidden, serve(http.MethodPost, "/api/v1/meetings/"+meeting.ID+"/revoke", "member").Code)
+		require.Equal(t, tokenStatusValid, status(t, claims.ID).Status)
+	})
+
+	t.Run("meeting creator", func(t *testing.T) {
+		w := serve(http.MethodPost, "/api/v1/meetings/"+meeting.ID+"/revoke", "creator")
+		require.Equal(t, http.StatusOK, w.Code)
+		require.JSONEq(t, `{"revoked": 1}`, w.Body.String())
+
+		response := status(t, claims.ID)
+		require.Equal(t, tokenStatusRevoked, response.Status)
+		require.NotZero(t, response.RevokedAt)
+
+		// Revoked tokens are not counted twice.
+		w = serve(http.MethodPost, "/api/v1/meetings/"+meeting.ID+"/revoke", "creator")
+		require.Equal(t, http.StatusOK, w.Code)
+		require.JSONEq(t, `{"revoked": 0}`, w.Body.String())
+	})
+}
+
+func TestHandleChannelMeetings(t *testing.T) {
+	p := Plugin{}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	mockMeetingStore(&apiMock)
+	p.SetAPI(&apiMock)
+
+	require.Nil(t, p.createMeeting(&Meeting{Room: "first-room", ChannelID: "test-channel", CreatorID: "test-user"}))
+	require.Nil(t, p.createMeeting(&Meeting{Room: "second-room", ChannelID: "test-channel", CreatorID: "test-user"}))
+	require.Nil(t, p.createMeeting(&Meeting{Room: "scheduled-room", ChannelID: "test-channel", CreatorID: "test-user", State: MeetingStateScheduled}))
+
+	apiMock.On("GetChannelMember", "test-channel", "member").Return(&model.ChannelMember{}, nil)
+	apiMock.On("GetChannelMember", "test-channel", "outsider").Return(nil, &model.AppError{})
+
+	serve := func(userID string, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/channels/test-channel/meetings"+query, nil)
+		r.Header.Set("Mattermost-User-Id", userID)
+		p.ServeHTTP(&plugin.Context{}, w, r)
+		return w
+	}
+
+	t.Run("not a channel member", func(t *testing.T) {
+		require.Equal(t, http.StatusForbidden, serve("outsider", "").Code)
+	})
+
+	t.Run("invalid limit", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, serve("member", "?limit=0").Code)
+	})
+
+	t.Run("list meetings", func(t *testing.T) {
+		w := serve("member", "?limit=5")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var meetings []*Meeting
+		require.Nil(t, json.Unmarshal(w.Body.Bytes(), &meetings))
+		require.Len(t, meetings, 2)
+		require.Equal(t, "second-room", meetings[0].Room)
+		require.Equal(t, "first-room", meetings[1].Room)
+	})
+}
+
+func TestHandleMeetingInvite(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL: "http://test",
+		},
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("GetBundlePath").Return("..", nil)
+	config := model.Config{}
+	config.SetDefaults()
+	apiMock.On("GetConfig").Return(&config, nil).Maybe()
+	mockMeetingStore(&apiMock)
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	meeting := &Meeting{Room: "test-room", ChannelID: "test-channel", CreatorID: "creator", Topic: "Sprint review", State: MeetingStateScheduled, StartAt: time.Now().Add(time.Hour).UnixMilli()}
+	require.Nil(t, p.createMeeting(meeting))
+
+	serve := func(userID string, meetingID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/meetings/"+meetingID+"/invite.ics", nil)
+		if userID != "" {
+			r.Header.Set("Mattermost-User-Id", userID)
+		}
+		p.ServeHTTP(&plugin.Context{}, w, r)
+		return w
+	}
+
+	t.Run("anonymous user", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, serve("", meeting.ID).Code)
+	})
+
+	t.Run("unknown meeting", func(t *testing.T) {
+		require.Equal(t, http.StatusNotFound, serve("member", "unknown").Code)
+	})
+
+	t.Run("not a channel member", func(t *testing.T) {
+		apiMock.On("GetChannelMember", "test-channel", "stranger").Return(nil, &model.AppError{}).Once()
+		require.Equal(t, http.StatusForbidden, serve("stranger", meeting.ID).Code)
+	})
+
+	t.Run("channel member", func(t *testing.T) {
+		apiMock.On("GetChannelMember", "test-channel", "member").Return(&model.ChannelMember{}, nil).Once()
+		w := serve("member", meeting.Room)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, icsContentType, w.Header().Get("Content-Type"))
+		require.Contains(t, w.Body.String(), "UID:"+meeting.ID+calendarEventUIDSuffix+"\r\n")
+		require.Contains(t, w.Body.String(), "SUMMARY:Sprint review\r\n")
+		require.Contains(t, w.Body.String(), "URL:http://test/test-room\r\n")
+	})
+}
+
+func TestHandleCalendarFeed(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL: "http://test",
+		},
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("GetBundlePath").Return("..", nil)
+	config := model.Config{}
+	config.SetDefaults()
+	apiMock.On("GetConfig").Return(&config, nil).Maybe()
+	mockMeetingStore(&apiMock)
+	mockKVStore(&apiMock, calendarTokenKeyPrefix, userCalendarTokenKeyPrefix)
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	require.Nil(t, p.createMeeting(&Meeting{Room: "test-room", ChannelID: "test-channel", Topic: "Sprint review", State: MeetingStateScheduled, StartAt: time.Now().Add(time.Hour).UnixMilli()}))
+	token, err := p.getCalendarToken("test-user")
+	require.Nil(t, err)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		p.ServeHTTP(&plugin.Context{}, w, r)
+		return w
+	}
+
+	t.Run("unknown token", func(t *testing.T) {
+		require.Equal(t, http.StatusNotFound, serve("/api/v1/calendar/unknown.ics").Code)
+	})
+
+	t.Run("missing extension", func(t *testing.T) {
+		require.Equal(t, http.StatusNotFound, serve("/api/v1/calendar/"+token).Code)
+	})
+
+	t.Run("deactivated user", func(t *testing.T) {
+		apiMock.On("GetUser", "test-user").Return(&model.User{Id: "test-user", DeleteAt: 1}, nil).Once()
+		require.Equal(t, http.StatusNotFound, serve("/api/v1/calendar/"+token+".ics").Code)
+	})
+
+	t.Run("valid token", func(t *testing.T) {
+		apiMock.On("GetUser", "test-user").Return(&model.User{Id: "test-user"}, nil).Once()
+		apiMock.On("GetChannelMember", "test-channel", "test-user").Return(&model.ChannelMember{}, nil).Once()
+		w := serve("/api/v1/calendar/" + token + ".ics")
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, icsContentType, w.Header().Get("Content-Type"))
+		require.Contains(t, w.Body.String(), "SUMMARY:Sprint review\r\n")
+	})
+}
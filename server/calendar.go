@@ -0,0 +1,193 @@
+package main
+
+import (
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/pkg/errors"
+)
+
+const (
+	calendarTokenKeyPrefix     = "calendar_token_"
+	userCalendarTokenKeyPrefix = "calendar_user_"
+	calendarEventUIDSuffix     = "@mattermost-plugin-jitsi"
+)
+
+var errCalendarTokenNotFound = errors.New("calendar token not found")
+
+// getCalendarToken returns the secret token of the user calendar feed, creating it on first use.
+// Calendar applications cannot authenticate against Mattermost, so the token alone grants access
+// to the feed.
+func (p *Plugin) getCalendarToken(userID string) (string, error) {
+	data, appErr := p.API.KVGet(userCalendarTokenKeyPrefix + userID)
+	if appErr != nil {
+		return "", appErr
+	}
+	if data != nil {
+		return string(data), nil
+	}
+
+	token := model.NewId()
+	if appErr = p.API.KVSet(calendarTokenKeyPrefix+token, []byte(userID)); appErr != nil {
+		return "", appErr
+	}
+	saved, appErr := p.API.KVCompareAndSet(userCalendarTokenKeyPrefix+userID, nil, []byte(token))
+	if appErr != nil {
+		return "", appErr
+	}
+	if !saved {
+		// Another request created the token in the meantime.
+		if appErr = p.API.KVDelete(calendarTokenKeyPrefix + token); appErr != nil {
+			return "", appErr
+		}
+		return p.getCalendarToken(userID)
+	}
+
+	return token, nil
+}
+
+// resetCalendarToken replaces the token of the user calendar feed, revoking the previous one.
+func (p *Plugin) resetCalendarToken(userID string) (string, error) {
+	previous, appErr := p.API.KVGet(userCalendarTokenKeyPrefix + userID)
+	if appErr != nil {
+		return "", appErr
+	}
+
+	token := model.NewId()
+	if appErr = p.API.KVSet(calendarTokenKeyPrefix+token, []byte(userID)); appErr != nil {
+		return "", appErr
+	}
+	if appErr = p.API.KVSet(userCalendarTokenKeyPrefix+userID, []byte(token)); appErr != nil {
+		return "", appErr
+	}
+	if previous != nil {
+		if appErr = p.API.KVDelete(calendarTokenKeyPrefix + string(previous)); appErr != nil {
+			return "", appErr
+		}
+	}
+
+	return token, nil
+}
+
+// getCalendarTokenUser returns the ID of the user owning a calendar feed token.
+func (p *Plugin) getCalendarTokenUser(token string) (string, error) {
+	data, appErr := p.API.KVGet(calendarTokenKeyPrefix + token)
+	if appErr != nil {
+		return "", appErr
+	}
+	if data == nil {
+		return "", errCalendarTokenNotFound
+	}
+	return string(data), nil
+}
+
+func (p *Plugin) getCalendarFeedURL(token string) string {
+	return *p.API.GetConfig().ServiceSettings.SiteURL + "/plugins/jitsi/api/v1/calendar/" + token + ".ics"
+}
+
+func (p *Plugin) getMeetingInviteURL(meetingID string) string {
+	return *p.API.GetConfig().ServiceSettings.SiteURL + "/plugins/jitsi/api/v1/meetings/" + meetingID + "/invite.ics"
+}
+
+func (p *Plugin) calendarEventDescription(meetingLink string) string {
+	return p.b.LocalizeWithConfig(p.b.GetServerLocalizer(), &i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "jitsi.calendar.event_description",
+			Other: "Join the Jitsi meeting: {{.MeetingURL}}",
+		},
+		TemplateData: map[string]string{"MeetingURL": meetingLink},
+	})
+}
+
+// meetingEvent returns the calendar event of a meeting. The join URL does not carry a JWT, which
+// would expire long before the event is opened.
+func (p *Plugin) meetingEvent(meeting *Meeting) icsEvent {
+	meetingLink := p.getMeetingLink(meeting.Room)
+
+	start := meeting.StartTime()
+	end := start.Add(meeting.PlannedDuration())
+	if meeting.State == MeetingStateEnded && meeting.EndAt > 0 {
+		end = time.UnixMilli(meeting.EndAt)
+	}
+
+	status := icsStatusConfirmed
+	if meeting.State == MeetingStateEnded && meeting.PostID == "" {
+		// The meeting was cancelled before it started.
+		status = icsStatusCancelled
+	}
+
+	return icsEvent{
+		UID:         meeting.ID + calendarEventUIDSuffix,
+		Summary:     meeting.Topic,
+		Description: p.calendarEventDescription(meetingLink),
+		URL:         meetingLink,
+		Start:       start,
+		End:         end,
+		Status:      status,
+		Created:     time.UnixMilli(meeting.CreateAt),
+	}
+}
+
+// recurringMeetingEvent returns the calendar event of a recurring meeting, repeating with its
+// recurrence rule.
+func (p *Plugin) recurringMeetingEvent(recurring *RecurringMeeting) icsEvent {
+	meetingLink := p.getMeetingLink(recurring.Room)
+	start := recurring.FirstOccurrence()
+	exDates := make([]time.Time, 0, len(recurring.ExDates))
+	for _, exDate := range recurring.ExDates {
+		exDates = append(exDates, time.UnixMilli(exDate))
+	}
+
+	return icsEvent{
+		UID:         recurring.ID + calendarEventUIDSuffix,
+		Summary:     recurring.Topic,
+		Description: p.calendarEventDescription(meetingLink),
+		URL:         meetingLink,
+		Start:       start,
+		End:         start.Add(time.Duration(recurring.Duration) * time.Millisecond),
+		RRule:       recurring.Rule,
+		ExDates:     exDates,
+		Status:      icsStatusConfirmed,
+		Created:     time.UnixMilli(recurring.CreateAt),
+	}
+}
+
+// getUserCalendarEvents returns the upcoming scheduled meetings and the recurring meetings of
+// every channel the user is a member of.
+func (p *Plugin) getUserCalendarEvents(userID string) ([]icsEvent, error) {
+	memberships := map[string]bool{}
+	isMember := func(channelID string) bool {
+		if member, ok := memberships[channelID]; ok {
+			return member
+		}
+		_, appErr := p.API.GetChannelMember(channelID, userID)
+		memberships[channelID] = appErr == nil
+		return memberships[channelID]
+	}
+
+	events := []icsEvent{}
+
+	scheduled, err := p.getIndexedMeetings(scheduledMeetingsKey, 0, func(meeting *Meeting) bool {
+		// Occurrences of recurring meetings are part of the recurring meeting event.
+		return meeting.State == MeetingStateScheduled && meeting.RecurringID == "" && isMember(meeting.ChannelID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, meeting := range scheduled {
+		events = append(events, p.meetingEvent(meeting))
+	}
+
+	recurringMeetings, err := p.getRecurringMeetings(recurringMeetingsKey)
+	if err != nil {
+		return nil, err
+	}
+	for _, recurring := range recurringMeetings {
+		if isMember(recurring.ChannelID) {
+			events = append(events, p.recurringMeetingEvent(recurring))
+		}
+	}
+
+	return events, nil
+}
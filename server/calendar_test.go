@@ -0,0 +1,92 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalendarToken(t *testing.T) {
+	p := Plugin{}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	mockKVStore(&apiMock, calendarTokenKeyPrefix, userCalendarTokenKeyPrefix)
+	p.SetAPI(&apiMock)
+
+	token, err := p.getCalendarToken("test-user")
+	require.Nil(t, err)
+	require.NotEmpty(t, token)
+
+	again, err := p.getCalendarToken("test-user")
+	require.Nil(t, err)
+	require.Equal(t, token, again)
+
+	userID, err := p.getCalendarTokenUser(token)
+	require.Nil(t, err)
+	require.Equal(t, "test-user", userID)
+
+	reset, err := p.resetCalendarToken("test-user")
+	require.Nil(t, err)
+	require.NotEqual(t, token, reset)
+
+	_, err = p.getCalendarTokenUser(token)
+	require.ErrorIs(t, err, errCalendarTokenNotFound)
+
+	userID, err = p.getCalendarTokenUser(reset)
+	require.Nil(t, err)
+	require.Equal(t, "test-user", userID)
+}
+
+func TestGetUserCalendarEvents(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL: "http://test/",
+		},
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("GetBundlePath").Return("..", nil)
+	config := model.Config{}
+	config.SetDefaults()
+	apiMock.On("GetConfig").Return(&config, nil)
+	mockMeetingStore(&apiMock)
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	startAt := time.Now().Add(24 * time.Hour).Truncate(time.Minute)
+	scheduled := &Meeting{Room: "scheduled-room", ChannelID: "member-channel", Topic: "Sprint review", State: MeetingStateScheduled, StartAt: startAt.UnixMilli(), Duration: (30 * time.Minute).Milliseconds()}
+	require.Nil(t, p.createMeeting(scheduled))
+	require.Nil(t, p.createMeeting(&Meeting{Room: "other-room", ChannelID: "other-channel", Topic: "Other", State: MeetingStateScheduled, StartAt: startAt.UnixMilli()}))
+	require.Nil(t, p.createMeeting(&Meeting{Room: "recurring-room", ChannelID: "member-channel", Topic: "Daily standup", State: MeetingStateScheduled, StartAt: startAt.UnixMilli(), RecurringID: "recurring"}))
+
+	recurring := &RecurringMeeting{ID: "recurring", Room: "recurring-room", ChannelID: "member-channel", Topic: "Daily standup", Rule: "FREQ=DAILY", Timezone: "Europe/Paris", StartAt: startAt.UnixMilli(), Duration: (15 * time.Minute).Milliseconds(), NextAt: startAt.UnixMilli()}
+	require.Nil(t, p.saveRecurringMeeting(recurring))
+	require.Nil(t, p.addToMeetingIndex(recurringMeetingsKey, recurring.ID, 0))
+
+	apiMock.On("GetChannelMember", "member-channel", "test-user").Return(&model.ChannelMember{}, nil)
+	apiMock.On("GetChannelMember", "other-channel", "test-user").Return(nil, &model.AppError{})
+
+	events, err := p.getUserCalendarEvents("test-user")
+	require.Nil(t, err)
+	require.Len(t, events, 2)
+
+	require.Equal(t, scheduled.ID+calendarEventUIDSuffix, events[0].UID)
+	require.Equal(t, "Sprint review", events[0].Summary)
+	require.Equal(t, "http://test/scheduled-room", events[0].URL)
+	require.Equal(t, "Join the Jitsi meeting: http://test/scheduled-room", events[0].Description)
+	require.Equal(t, 30*time.Minute, events[0].End.Sub(events[0].Start))
+	require.Empty(t, events[0].RRule)
+
+	require.Equal(t, "recurring"+calendarEventUIDSuffix, events[1].UID)
+	require.Equal(t, "http://test/recurring-room", events[1].URL)
+	require.Equal(t, "FREQ=DAILY", events[1].RRule)
+	require.Equal(t, "Europe/Paris", events[1].Start.Location().String())
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/shared/mlog"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+const (
+	callStatusKeyPrefix = "call_status_"
+	inCallStatusEmoji   = "video_camera"
+)
+
+// callStatus records the custom status a user had before joining a meeting, to restore it when
+// they leave. Status is the in-call status set by the plugin.
+type callStatus struct {
+	MeetingID string              `json:"meeting_id"`
+	Status    *model.CustomStatus `json:"status"`
+	Previous  *model.CustomStatus `json:"previous,omitempty"`
+}
+
+func (p *Plugin) getCallStatus(userID string) (*callStatus, error) {
+	data, appErr := p.API.KVGet(callStatusKeyPrefix + userID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if data == nil {
+		return nil, nil
+	}
+
+	var status callStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// setInCallStatus sets the custom status of a user joining a meeting, if they opted in. The
+// status expires at the planned end of the meeting, in case their leaving goes unnoticed.
+func (p *Plugin) setInCallStatus(user *model.User, meeting *Meeting) error {
+	userConfig, err := p.getStoredUserConfig(user.Id)
+	if err != nil {
+		return err
+	}
+	if userConfig == nil || !userConfig.InCallStatus {
+		return nil
+	}
+
+	record, err := p.getCallStatus(user.Id)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		record = &callStatus{Previous: user.GetCustomStatus()}
+	}
+	// Users moving from a meeting to another one get back the status they had before the first.
+	record.MeetingID = meeting.ID
+
+	l := p.b.GetUserLocalizer(user.Id)
+	record.Status = &model.CustomStatus{
+		Emoji: inCallStatusEmoji,
+		Text: p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.call_status.text",
+				Other: "In a Jitsi meeting",
+			},
+		}),
+		Duration:  "date_and_time",
+		ExpiresAt: time.Now().Add(meeting.PlannedDuration()).UTC(),
+	}
+
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if appErr := p.API.KVSet(callStatusKeyPrefix+user.Id, b); appErr != nil {
+		return appErr
+	}
+	if appErr := p.API.UpdateUserCustomStatus(user.Id, record.Status); appErr != nil {
+		return appErr
+	}
+
+	_, err = p.updateMeeting(meeting.ID, func(meeting *Meeting) error {
+		if !slices.Contains(meeting.StatusUserIDs, user.Id) {
+			meeting.StatusUserIDs = append(meeting.StatusUserIDs, user.Id)
+		}
+		return nil
+	})
+	return err
+}
+
+// clearInCallStatus restores the custom status a user had before joining a meeting. The status
+// is left alone when the user joined another meeting since, or changed their status themselves.
+func (p *Plugin) clearInCallStatus(userID string, meetingID string) error {
+	record, err := p.getCallStatus(userID)
+	if err != nil {
+		return err
+	}
+	if record == nil || record.MeetingID != meetingID {
+		return nil
+	}
+	if appErr := p.API.KVDelete(callStatusKeyPrefix + userID); appErr != nil {
+		return appErr
+	}
+
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		return appErr
+	}
+	current := user.GetCustomStatus()
+	if current == nil || record.Status == nil || current.Emoji != record.Status.Emoji || current.Text != record.Status.Text {
+		return nil
+	}
+
+	previous := record.Previous
+	if previous != nil && (previous.ExpiresAt.IsZero() || previous.ExpiresAt.After(time.Now())) {
+		if appErr = p.API.UpdateUserCustomStatus(userID, previous); appErr != nil {
+			return appErr
+		}
+		return nil
+	}
+	if appErr = p.API.RemoveUserCustomStatus(userID); appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// clearMeetingCallStatuses restores the custom statuses of the users still in an ended meeting.
+func (p *Plugin) clearMeetingCallStatuses(meeting *Meeting) {
+	for _, userID := range meeting.StatusUserIDs {
+		if err := p.clearInCallStatus(userID, meeting.ID); err != nil {
+			mlog.Warn("Unable to restore the custom status", mlog.String("user_id", userID), mlog.String("meeting_id", meeting.ID), mlog.Err(err))
+		}
+	}
+}
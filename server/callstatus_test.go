@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInCallStatus(t *testing.T) {
+	p := Plugin{configuration: &configuration{JitsiURL: "http://test"}}
+	apiMock := plugintest.API{}
+	apiMock.On("GetBundlePath").Return("..", nil)
+	mockMeetingStore(&apiMock)
+	store := mockKVStore(&apiMock, "config_", callStatusKeyPrefix)
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	user := &model.User{Id: "test-user", Locale: "en"}
+	apiMock.On("GetUser", "test-user").Return(func(string) *model.User { return user.DeepCopy() }, nil)
+	apiMock.On("UpdateUserCustomStatus", "test-user", mock.Anything).Return(func(_ string, status *model.CustomStatus) *model.AppError {
+		require.Nil(t, user.SetCustomStatus(status))
+		return nil
+	})
+	apiMock.On("RemoveUserCustomStatus", "test-user").Return(func(string) *model.AppError {
+		user.ClearCustomStatus()
+		return nil
+	})
+
+	meeting := &Meeting{Room: "test-room", ChannelID: "test-channel", Duration: time.Hour.Milliseconds()}
+	require.Nil(t, p.createMeeting(meeting))
+	other := &Meeting{Room: "other-room", ChannelID: "test-channel"}
+	require.Nil(t, p.createMeeting(other))
+
+	previous := &model.CustomStatus{Emoji: "palm_tree", Text: "On vacation"}
+	reset := func(t *testing.T, inCallStatus bool) {
+		user.ClearCustomStatus()
+		require.Nil(t, user.SetCustomStatus(previous))
+		store["config_test-user"] = []byte(fmt.Sprintf(`{"in_call_status": %v}`, inCallStatus))
+	}
+
+	t.Run("opted out", func(t *testing.T) {
+		reset(t, false)
+		require.Nil(t, p.setInCallStatus(user.DeepCopy(), meeting))
+		require.Equal(t, previous.Text, user.GetCustomStatus().Text)
+		require.NotContains(t, store, callStatusKeyPrefix+"test-user")
+	})
+
+	t.Run("set and restored", func(t *testing.T) {
+		reset(t, true)
+		before := time.Now()
+		require.Nil(t, p.setInCallStatus(user.DeepCopy(), meeting))
+		status := user.GetCustomStatus()
+		require.Equal(t, inCallStatusEmoji, status.Emoji)
+		require.Equal(t, "In a Jitsi meeting", status.Text)
+		require.WithinRange(t, status.ExpiresAt, before.Add(time.Hour).Truncate(time.Second), time.Now().Add(time.Hour))
+
+		stored, err := p.getMeeting(meeting.ID)
+		require.Nil(t, err)
+		require.Equal(t, []string{"test-user"}, stored.StatusUserIDs)
+
+		// Leaving another meeting keeps the status.
+		require.Nil(t, p.clearInCallStatus("test-user", other.ID))
+		require.Equal(t, "In a Jitsi meeting", user.GetCustomStatus().Text)
+
+		require.Nil(t, p.clearInCallStatus("test-user", meeting.ID))
+		require.Equal(t, previous.Text, user.GetCustomStatus().Text)
+		require.NotContains(t, store, callStatusKeyPrefix+"test-user")
+	})
+
+	t.Run("moving to another meeting", func(t *testing.T) {
+		reset(t, true)
+		require.Nil(t, p.setInCallStatus(user.DeepCopy(), meeting))
+		require.Nil(t, p.setInCallStatus(user.DeepCopy(), other))
+
+		require.Nil(t, p.clearInCallStatus("test-user", meeting.ID))
+		require.Equal(t, "In a Jitsi meeting", user.GetCustomStatus().Text)
+		require.Nil(t, p.clearInCallStatus("test-user", other.ID))
+		require.Equal(t, previous.Text, user.GetCustomStatus().Text)
+	})
+
+	t.Run("status changed during the meeting", func(t *testing.T) {
+		reset(t, true)
+		require.Nil(t, p.setInCallStatus(user.DeepCopy(), meeting))
+		require.Nil(t, user.SetCustomStatus(&model.CustomStatus{Emoji: "coffee", Text: "Break"}))
+
+		require.Nil(t, p.clearInCallStatus("test-user", meeting.ID))
+		require.Equal(t, "Break", user.GetCustomStatus().Text)
+	})
+
+	t.Run("previous status expired", func(t *testing.T) {
+		reset(t, true)
+		require.Nil(t, user.SetCustomStatus(&model.CustomStatus{Text: "Lunch", Duration: "date_and_time", ExpiresAt: time.Now().Add(-time.Minute)}))
+		require.Nil(t, p.setInCallStatus(user.DeepCopy(), meeting))
+
+		require.Nil(t, p.clearInCallStatus("test-user", meeting.ID))
+		require.Nil(t, user.GetCustomStatus())
+	})
+
+	t.Run("restored when the meeting ends", func(t *testing.T) {
+		reset(t, true)
+		require.Nil(t, p.setInCallStatus(user.DeepCopy(), meeting))
+
+		_, err := p.endMeeting(meeting.ID)
+		require.Nil(t, err)
+		require.Equal(t, previous.Text, user.GetCustomStatus().Text)
+	})
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const channelConfigKeyPrefix = "channel_config_"
+
+const (
+	whoCanStartEveryone = "everyone"
+	whoCanStartAdmins   = "admins"
+)
+
+// ChannelConfig holds the meeting defaults of a channel, set by its admins with
+// /jitsi channel-settings. Unset fields follow the system configuration.
+type ChannelConfig struct {
+	NamingScheme string `json:"naming_scheme,omitempty"`
+
+	// RoomName is the room of every meeting started in the channel without a topic.
+	RoomName string `json:"room_name,omitempty"`
+
+	// PermanentRoom makes every meeting of the channel use its permanent room, see getChannelRoom.
+	PermanentRoom *bool `json:"permanent_room,omitempty"`
+
+	Embedded            *bool `json:"embedded,omitempty"`
+	ShowPrejoinPage     *bool `json:"show_prejoin_page,omitempty"`
+	Lobby               *bool `json:"lobby,omitempty"`
+	StartWithAudioMuted *bool `json:"start_with_audio_muted,omitempty"`
+	StartWithVideoMuted *bool `json:"start_with_video_muted,omitempty"`
+
+	// WhoCanStart restricts who starts meetings in the channel, everyone by default.
+	WhoCanStart string `json:"who_can_start,omitempty"`
+}
+
+func (p *Plugin) getChannelConfig(channelID string) (*ChannelConfig, error) {
+	data, appErr := p.API.KVGet(channelConfigKeyPrefix + channelID)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	var channelConfig ChannelConfig
+	if data == nil {
+		return &channelConfig, nil
+	}
+	if err := json.Unmarshal(data, &channelConfig); err != nil {
+		return nil, err
+	}
+	return &channelConfig, nil
+}
+
+func (p *Plugin) setChannelConfig(channelID string, channelConfig *ChannelConfig) error {
+	b, err := json.Marshal(channelConfig)
+	if err != nil {
+		return err
+	}
+
+	if appErr := p.API.KVSet(channelConfigKeyPrefix+channelID, b); appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// resolveUserConfig returns the settings a user starts and joins meetings of a channel with: the
+// system configuration, overridden by the channel settings, then by the settings the user saved
+// with /jitsi settings. Command arguments override the result.
+func (p *Plugin) resolveUserConfig(userID string, channelID string) (*UserConfig, *ChannelConfig, error) {
+	channelConfig, err := p.getChannelConfig(channelID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userConfig, err := p.getUserConfig(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if userConfig.NamingScheme == nil && channelConfig.NamingScheme != "" {
+		userConfig.NamingScheme = model.NewPointer(channelConfig.NamingScheme)
+	}
+	if userConfig.Embedded == nil {
+		userConfig.Embedded = channelConfig.Embedded
+	}
+	if userConfig.ShowPrejoinPage == nil {
+		userConfig.ShowPrejoinPage = channelConfig.ShowPrejoinPage
+	}
+	if userConfig.StartWithAudioMuted == nil {
+		userConfig.StartWithAudioMuted = channelConfig.StartWithAudioMuted
+	}
+	if userConfig.StartWithVideoMuted == nil {
+		userConfig.StartWithVideoMuted = channelConfig.StartWithVideoMuted
+	}
+	p.applyDefaults(userConfig)
+
+	return userConfig, channelConfig, nil
+}
+
+// canStartMeeting tells whether the channel settings let a user start meetings in the channel.
+func (p *Plugin) canStartMeeting(userID string, channelID string, channelConfig *ChannelConfig) bool {
+	if channelConfig.WhoCanStart != whoCanStartAdmins {
+		return true
+	}
+	return p.API.HasPermissionToChannel(userID, channelID, model.PermissionManageChannelRoles)
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveUserConfig(t *testing.T) {
+	p := Plugin{configuration: &configuration{
+		JitsiNamingScheme: jitsiNameSchemeWords,
+		JitsiEmbedded:     true,
+		JitsiPrejoinPage:  true,
+	}}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	store := mockKVStore(&apiMock, "config_", channelConfigKeyPrefix)
+	p.SetAPI(&apiMock)
+
+	t.Run("system configuration", func(t *testing.T) {
+		userConfig, channelConfig, err := p.resolveUserConfig("test-user", "test-channel")
+		require.Nil(t, err)
+		require.Equal(t, &ChannelConfig{}, channelConfig)
+		require.Equal(t, &UserConfig{
+			NamingScheme:    model.NewPointer(jitsiNameSchemeWords),
+			Embedded:        model.NewPointer(true),
+			ShowPrejoinPage: model.NewPointer(true),
+		}, userConfig)
+	})
+
+	require.Nil(t, p.setChannelConfig("test-channel", &ChannelConfig{
+		NamingScheme:        jitsiNameSchemeUUID,
+		Embedded:            model.NewPointer(false),
+		StartWithAudioMuted: model.NewPointer(true),
+		StartWithVideoMuted: model.NewPointer(true),
+	}))
+
+	t.Run("channel settings override the system configuration", func(t *testing.T) {
+		userConfig, _, err := p.resolveUserConfig("test-user", "test-channel")
+		require.Nil(t, err)
+		require.Equal(t, &UserConfig{
+			NamingScheme:        model.NewPointer(jitsiNameSchemeUUID),
+			Embedded:            model.NewPointer(false),
+			ShowPrejoinPage:     model.NewPointer(true),
+			StartWithAudioMuted: model.NewPointer(true),
+			StartWithVideoMuted: model.NewPointer(true),
+		}, userConfig)
+	})
+
+	t.Run("user settings override the channel settings", func(t *testing.T) {
+		b, err := json.Marshal(&UserConfig{
+			NamingScheme:        model.NewPointer(jitsiNameSchemeMattermost),
+			Embedded:            model.NewPointer(true),
+			StartWithAudioMuted: model.NewPointer(false),
+		})
+		require.Nil(t, err)
+		store["config_other-user"] = b
+
+		userConfig, _, err := p.resolveUserConfig("other-user", "test-channel")
+		require.Nil(t, err)
+		require.Equal(t, &UserConfig{
+			NamingScheme:        model.NewPointer(jitsiNameSchemeMattermost),
+			Embedded:            model.NewPointer(true),
+			ShowPrejoinPage:     model.NewPointer(true),
+			StartWithAudioMuted: model.NewPointer(false),
+			StartWithVideoMuted: model.NewPointer(true),
+		}, userConfig)
+	})
+
+	t.Run("channel settings apply to the settings the user did not save", func(t *testing.T) {
+		b, err := json.Marshal(&UserConfig{InCallStatus: true})
+		require.Nil(t, err)
+		store["config_third-user"] = b
+
+		userConfig, _, err := p.resolveUserConfig("third-user", "test-channel")
+		require.Nil(t, err)
+		require.Equal(t, &UserConfig{
+			NamingScheme:        model.NewPointer(jitsiNameSchemeUUID),
+			Embedded:            model.NewPointer(false),
+			ShowPrejoinPage:     model.NewPointer(true),
+			StartWithAudioMuted: model.NewPointer(true),
+			StartWithVideoMuted: model.NewPointer(true),
+			InCallStatus:        true,
+		}, userConfig)
+	})
+}
+
+func TestStartMeetingWithChannelConfig(t *testing.T) {
+	p := Plugin{configuration: &configuration{JitsiURL: "http://test"}}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("GetBundlePath").Return("..", nil)
+	config := model.Config{}
+	config.SetDefaults()
+	apiMock.On("GetConfig").Return(&config, nil)
+	mockMeetingStore(&apiMock)
+	store := mockKVStore(&apiMock, "config_")
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	testUser := model.User{Id: "test-user", Username: "test-username"}
+	testChannel := model.Channel{Id: "support", Type: model.ChannelTypeOpen}
+	require.Nil(t, p.setChannelConfig("support", &ChannelConfig{
+		RoomName:            "SupportRoom",
+		Lobby:               model.NewPointer(true),
+		StartWithAudioMuted: model.NewPointer(true),
+		Embedded:            model.NewPointer(false),
+	}))
+
+	// The channel settings reach the clients through the post.
+	apiMock.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.Props["meeting_link"] == "http://test/SupportRoom" && post.Props["meeting_embedded"] == false &&
+			strings.Contains(post.Props["meeting_url_config"].(string), "config.startWithAudioMuted=true")
+	})).Return(&model.Post{Id: "support-post"}, nil).Once()
+	apiMock.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.Props["meeting_link"] == "http://test/Escalation-abc"
+	})).Return(&model.Post{Id: "topic-post"}, nil).Once()
+
+	t.Run("fixed room", func(t *testing.T) {
+		meeting, err := p.startMeetingWithOptions(&testUser, &testChannel, startMeetingOptions{})
+		require.Nil(t, err)
+		require.Equal(t, "SupportRoom", meeting.Room)
+		require.True(t, meeting.Lobby)
+	})
+
+	t.Run("a topic starts a separate meeting", func(t *testing.T) {
+		meeting, err := p.startMeetingWithOptions(&testUser, &testChannel, startMeetingOptions{MeetingID: "Escalation-abc", Topic: "Escalation"})
+		require.Nil(t, err)
+		require.Equal(t, "Escalation-abc", meeting.Room)
+	})
+
+	t.Run("channel settings apply to users who saved other settings", func(t *testing.T) {
+		b, err := json.Marshal(&UserConfig{Embedded: model.NewPointer(true), InCallStatus: true})
+		require.Nil(t, err)
+		store["config_test-user"] = b
+		require.Nil(t, p.setChannelConfig("forced", &ChannelConfig{
+			NamingScheme:    jitsiNameSchemeMattermost,
+			ShowPrejoinPage: model.NewPointer(false),
+		}))
+		forcedChannel := model.Channel{Id: "forced", TeamId: "test-team", Name: "forced", Type: model.ChannelTypeOpen}
+		apiMock.On("GetTeam", "test-team").Return(&model.Team{Id: "test-team", Name: "support"}, nil).Once()
+		apiMock.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "forced" && post.Props["meeting_show_prejoin_page"] == false
+		})).Return(&model.Post{Id: "forced-post"}, nil).Once()
+
+		meeting, err := p.startMeetingWithOptions(&testUser, &forcedChannel, startMeetingOptions{})
+		require.Nil(t, err)
+		require.True(t, strings.HasPrefix(meeting.Room, "support-forced-"))
+
+		userConfig, _, err := p.resolveUserConfig("test-user", "forced")
+		require.Nil(t, err)
+		require.True(t, *userConfig.Embedded)
+		require.False(t, *userConfig.ShowPrejoinPage)
+	})
+}
+
+func TestCommandChannelSettings(t *testing.T) {
+	p := Plugin{configuration: &configuration{JitsiURL: "http://test"}, botID: "test-bot-id"}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("GetBundlePath").Return("..", nil)
+	mockKVStore(&apiMock, channelConfigKeyPrefix)
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	for _, userID := range []string{"admin", "member"} {
+		apiMock.On("GetUser", userID).Return(&model.User{Id: userID, Locale: "en"}, nil)
+	}
+	apiMock.On("HasPermissionToChannel", "admin", "test-channel", model.PermissionManageChannelRoles).Return(true)
+	apiMock.On("HasPermissionToChannel", "member", "test-channel", model.PermissionManageChannelRoles).Return(false)
+
+	run := func(t *testing.T, userID string, command string, output string) {
+		apiMock.On("SendEphemeralPost", userID, &model.Post{
+			UserId:    "test-bot-id",
+			ChannelId: "test-channel",
+			Message:   output,
+		}).Return(nil).Once()
+		response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{UserId: userID, ChannelId: "test-channel", Command: command})
+		require.Equal(t, &model.CommandResponse{}, response)
+		require.Nil(t, err)
+	}
+
+	t.Run("set room name", func(t *testing.T) {
+		run(t, "admin", "/jitsi channel-settings room_name Support Room", "Invalid settings parameters")
+		run(t, "admin", "/jitsi channel-settings room_name Support.Room", "Jitsi channel settings updated:\n\n* room_name: `SupportRoom`")
+	})
+
+	t.Run("set invalid values", func(t *testing.T) {
+		run(t, "admin", "/jitsi channel-settings room_name ...", "Invalid `room_name` value, use letters, digits, `-` and `_`, or `default`.")
+		run(t, "admin", "/jitsi channel-settings lobby yes", "Invalid `lobby` value, use `true`, `false` or `default`.")
+		run(t, "admin", "/jitsi channel-settings who_can_start nobody", "Invalid `who_can_start` value, use `everyone` or `admins`.")
+	})
+
+	t.Run("only channel admins change the settings", func(t *testing.T) {
+		run(t, "member", "/jitsi channel-settings lobby true", "Only the channel admins can change the settings of the channel.")
+	})
+
+	t.Run("set who can start", func(t *testing.T) {
+		run(t, "admin", "/jitsi channel-settings who_can_start admins", "Jitsi channel settings updated:\n\n* who_can_start: `admins`")
+
+		channelConfig, err := p.getChannelConfig("test-channel")
+		require.Nil(t, err)
+		require.True(t, p.canStartMeeting("admin", "test-channel", channelConfig))
+		require.False(t, p.canStartMeeting("member", "test-channel", channelConfig))
+	})
+
+	t.Run("see current settings", func(t *testing.T) {
+		run(t, "member", "/jitsi channel-settings", "###### Jitsi Channel Settings:\n* Naming Scheme: `default`\n* Room Name: `SupportRoom`\n* Permanent Room: `default`\n* Embedded: `default`\n* Show Pre-join Page: `default`\n* Lobby: `default`\n* Start With Audio Muted: `default`\n* Start With Video Muted: `default`\n* Who Can Start Meetings: `admins`")
+	})
+}
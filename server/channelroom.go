@@ -0,0 +1,107 @@
+package main
+
+import (
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+const channelRoomKeyPrefix = "channel_room_"
+
+// usesPermanentRoom tells whether the meetings of a channel share the permanent room of the
+// channel instead of getting a new room each time.
+func (p *Plugin) usesPermanentRoom(channelConfig *ChannelConfig) bool {
+	if channelConfig.PermanentRoom != nil {
+		return *channelConfig.PermanentRoom
+	}
+	return p.getConfiguration().JitsiPermanentChannelRooms
+}
+
+// hasChannelRoom tells whether meetings started in a channel without a topic join a room of the
+// channel, so that there is no meeting name to ask for.
+func (p *Plugin) hasChannelRoom(channelConfig *ChannelConfig) bool {
+	return channelConfig.RoomName != "" || p.usesPermanentRoom(channelConfig)
+}
+
+// newChannelRoom generates a permanent room for a channel. Like other channel meeting names, it
+// ends with random letters so that it can't be guessed from the channel name.
+func (p *Plugin) newChannelRoom(channel *model.Channel) (string, error) {
+	if channel.Type == model.ChannelTypeDirect || channel.Type == model.ChannelTypeGroup {
+		return generateEnglishTitleName(), nil
+	}
+
+	team, appErr := p.API.GetTeam(channel.TeamId)
+	if appErr != nil {
+		return "", appErr
+	}
+	return generateTeamChannelName(team.Name, channel.Name), nil
+}
+
+// getChannelRoom returns the permanent room of a channel, created on first use. Concurrent first
+// uses agree on the same room.
+func (p *Plugin) getChannelRoom(channel *model.Channel) (string, error) {
+	data, appErr := p.API.KVGet(channelRoomKeyPrefix + channel.Id)
+	if appErr != nil {
+		return "", appErr
+	}
+	if data != nil {
+		return string(data), nil
+	}
+
+	room, err := p.newChannelRoom(channel)
+	if err != nil {
+		return "", err
+	}
+
+	err = p.atomicKVUpdate(channelRoomKeyPrefix+channel.Id, func(data []byte) ([]byte, error) {
+		if data != nil {
+			room = string(data)
+			return data, nil
+		}
+		return []byte(room), nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return room, nil
+}
+
+// resetChannelRoom replaces the permanent room of a channel, e.g. after its link leaked. The
+// meeting running in the previous room ends, and the tokens issued for it are revoked.
+func (p *Plugin) resetChannelRoom(channel *model.Channel) (string, error) {
+	room, err := p.newChannelRoom(channel)
+	if err != nil {
+		return "", err
+	}
+
+	var previous []byte
+	err = p.atomicKVUpdate(channelRoomKeyPrefix+channel.Id, func(data []byte) ([]byte, error) {
+		previous = data
+		return []byte(room), nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if previous == nil {
+		return room, nil
+	}
+
+	// The room key points to the latest meeting of the room, however old it is.
+	meeting, err := p.getMeetingByRoom(string(previous))
+	if errors.Is(err, errMeetingNotFound) {
+		return room, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if meeting.State == MeetingStateScheduled {
+		return room, nil
+	}
+	if _, err = p.endMeeting(meeting.ID); err != nil && !errors.Is(err, errMeetingAlreadyEnded) {
+		return "", err
+	}
+	if _, err = p.revokeMeetingTokens(meeting.ID); err != nil {
+		return "", err
+	}
+
+	return room, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelRoom(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL:                   "http://test",
+			JitsiJWT:                   true,
+			JitsiAppID:                 "test-app-id",
+			JitsiAppSecret:             "test-secret",
+			JitsiLinkValidTime:         30,
+			JitsiPermanentChannelRooms: true,
+		},
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("GetBundlePath").Return("..", nil)
+	config := model.Config{}
+	config.SetDefaults()
+	apiMock.On("GetConfig").Return(&config, nil)
+	mockMeetingStore(&apiMock)
+	mockKVStore(&apiMock, channelRoomKeyPrefix)
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	testUser := model.User{Id: "test-user", Username: "test-username"}
+	testChannel := model.Channel{Id: "test-channel", TeamId: "test-team", Name: "town-square", Type: model.ChannelTypeOpen}
+	apiMock.On("GetTeam", "test-team").Return(&model.Team{Id: "test-team", Name: "team"}, nil)
+	apiMock.On("CreatePost", mock.Anything).Return(&model.Post{}, nil)
+
+	var room string
+	t.Run("every meeting joins the permanent room", func(t *testing.T) {
+		first, err := p.startMeetingWithOptions(&testUser, &testChannel, startMeetingOptions{})
+		require.Nil(t, err)
+		require.True(t, strings.HasPrefix(first.Room, "team-town-square-"))
+
+		second, err := p.startMeetingWithOptions(&testUser, &testChannel, startMeetingOptions{Topic: "Standup"})
+		require.Nil(t, err)
+		require.Equal(t, first.Room, second.Room)
+		require.Equal(t, "Standup", second.Topic)
+
+		room, err = p.getChannelRoom(&testChannel)
+		require.Nil(t, err)
+		require.Equal(t, first.Room, room)
+	})
+
+	t.Run("the channel settings disable the permanent room", func(t *testing.T) {
+		require.Nil(t, p.setChannelConfig("test-channel", &ChannelConfig{PermanentRoom: model.NewPointer(false)}))
+		defer func() {
+			require.Nil(t, p.setChannelConfig("test-channel", &ChannelConfig{}))
+		}()
+
+		meeting, err := p.startMeetingWithOptions(&testUser, &testChannel, startMeetingOptions{Topic: "Standup"})
+		require.Nil(t, err)
+		require.NotEqual(t, room, meeting.Room)
+	})
+
+	t.Run("tokens are bound to the room", func(t *testing.T) {
+		meeting, err := p.getMeetingByRoom(room)
+		require.Nil(t, err)
+		token, _, err := p.issueMeetingToken(&testUser, meeting)
+		require.Nil(t, err)
+		claims, err := verifyJwt("test-secret", token)
+		require.Nil(t, err)
+		require.Equal(t, room, claims.Room)
+
+		// The meeting of the room is no longer among the recent meetings of the channel.
+		for i := 0; i < maxMeetingIndexSize; i++ {
+			require.Nil(t, p.createMeeting(&Meeting{Room: fmt.Sprintf("other-%d", i), ChannelID: "test-channel", CreatorID: "test-user"}))
+		}
+
+		newRoom, err := p.resetChannelRoom(&testChannel)
+		require.Nil(t, err)
+		require.NotEqual(t, room, newRoom)
+
+		// The meetings of the previous room ended and their tokens are revoked.
+		meeting, err = p.getMeeting(meeting.ID)
+		require.Nil(t, err)
+		require.Equal(t, MeetingStateEnded, meeting.State)
+		issued, err := p.getIssuedToken(claims.ID)
+		require.Nil(t, err)
+		require.Equal(t, tokenStatusRevoked, issued.Status(time.Now()))
+		_, _, err = p.issueMeetingToken(&testUser, meeting)
+		require.ErrorIs(t, err, errMeetingAlreadyEnded)
+
+		next, err := p.startMeetingWithOptions(&testUser, &testChannel, startMeetingOptions{})
+		require.Nil(t, err)
+		require.Equal(t, newRoom, next.Room)
+	})
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/plugin"
@@ -16,6 +18,23 @@ const jitsiCommand = "jitsi"
 
 const jitsiSettingsSeeCommand = "see"
 const jitsiStartCommand = "start"
+const jitsiEndCommand = "end"
+const jitsiInviteCommand = "invite"
+const jitsiHistoryCommand = "history"
+const jitsiScheduleCommand = "schedule"
+const jitsiRecurringCommand = "recurring"
+const jitsiRecurringListCommand = "list"
+const jitsiRecurringAddCommand = "add"
+const jitsiRecurringRemoveCommand = "remove"
+const jitsiCalendarCommand = "calendar"
+const jitsiCalendarResetCommand = "reset"
+const jitsiImportCommand = "import"
+const jitsiChannelSettingsCommand = "channel-settings"
+const jitsiRoomCommand = "room"
+const jitsiRoomResetCommand = "reset"
+
+const defaultHistoryLength = 10
+const maxHistoryLength = 50
 
 const valueTrue = "true"
 const valueFalse = "false"
@@ -23,6 +42,16 @@ const valueFalse = "false"
 const commandArgShowPrejoinPage = "show_prejoin_page"
 const commandArgEmbedded = "embedded"
 const commandArgNamingScheme = "naming_scheme"
+const commandArgStartWithAudioMuted = "start_with_audio_muted"
+const commandArgStartWithVideoMuted = "start_with_video_muted"
+const commandArgStartAudioOnly = "start_audio_only"
+const commandArgInCallStatus = "in_call_status"
+const commandArgRoomName = "room_name"
+const commandArgPermanentRoom = "permanent_room"
+const commandArgLobby = "lobby"
+const commandArgWhoCanStart = "who_can_start"
+
+const valueDefault = "default"
 
 func startMeetingError(channelID string, detailedError string) (*model.CommandResponse, *model.AppError) {
 	return &model.CommandResponse{
@@ -43,7 +72,7 @@ func (p *Plugin) createJitsiCommand() (*model.Command, error) {
 	return &model.Command{
 		Trigger:              jitsiCommand,
 		AutoComplete:         true,
-		AutoCompleteDesc:     "Start a Jitsi meeting in current channel. Other available commands: start, help, settings",
+		AutoCompleteDesc:     "Start a Jitsi meeting in current channel. Other available commands: start, schedule, recurring, import, calendar, end, invite, history, help, settings, channel-settings, room",
 		AutoCompleteHint:     "[command]",
 		AutocompleteData:     getAutocompleteData(),
 		AutocompleteIconData: iconData,
@@ -51,12 +80,59 @@ func (p *Plugin) createJitsiCommand() (*model.Command, error) {
 }
 
 func getAutocompleteData() *model.AutocompleteData {
-	jitsi := model.NewAutocompleteData("jitsi", "[command]", "Start a Jitsi meeting in current channel. Other available commands: start, help, settings")
+	jitsi := model.NewAutocompleteData("jitsi", "[command]", "Start a Jitsi meeting in current channel. Other available commands: start, schedule, recurring, import, calendar, end, invite, history, help, settings, channel-settings, room")
 
-	start := model.NewAutocompleteData(jitsiStartCommand, "[topic]", "Start a new meeting in the current channel")
-	start.AddTextArgument("(optional) The topic of the new meeting", "[topic]", "")
+	start := model.NewAutocompleteData(jitsiStartCommand, "[--lobby] [--password <password>] [--e2ee] [topic]", "Start a new meeting in the current channel")
+	start.AddTextArgument("(optional) --lobby to make participants knock on the lobby, --password to share a password with the channel members, --e2ee for end-to-end encryption, then the topic of the new meeting", "[--lobby] [--password <password>] [--e2ee] [topic]", "")
 	jitsi.AddCommand(start)
 
+	end := model.NewAutocompleteData(jitsiEndCommand, "[meeting-id]", "End a meeting you started, or the latest meeting of the current channel")
+	end.AddTextArgument("(optional) The ID of the meeting to end", "[meeting-id]", "")
+	jitsi.AddCommand(end)
+
+	invite := model.NewAutocompleteData(jitsiInviteCommand, "@username... [meeting-id]", "Invite users to a meeting, by default the latest running meeting of the current channel")
+	invite.AddTextArgument("The users to invite, and optionally the ID of the meeting. Without users, see who accepted", "@username... [meeting-id]", "")
+	jitsi.AddCommand(invite)
+
+	history := model.NewAutocompleteData(jitsiHistoryCommand, "[n]", "List the recent meetings of the current channel")
+	history.AddTextArgument("(optional) The number of meetings to list", "[n]", "")
+	jitsi.AddCommand(history)
+
+	schedule := model.NewAutocompleteData(jitsiScheduleCommand, "\"[topic]\" [YYYY-MM-DD] [HH:MM] [duration]", "Schedule a meeting in the current channel")
+	schedule.AddTextArgument("The topic of the meeting, between quotes", "\"[topic]\"", "")
+	schedule.AddTextArgument("The day of the meeting", "[YYYY-MM-DD]", "")
+	schedule.AddTextArgument("The start time of the meeting, in your timezone", "[HH:MM]", "")
+	schedule.AddTextArgument("(optional) The duration of the meeting, in minutes or like 1h30m", "[duration]", "")
+	jitsi.AddCommand(schedule)
+
+	recurring := model.NewAutocompleteData(jitsiRecurringCommand, "[list|add|remove]", "Manage the recurring meetings of the current channel")
+	recurringList := model.NewAutocompleteData(jitsiRecurringListCommand, "", "List the recurring meetings of the current channel")
+	recurring.AddCommand(recurringList)
+	recurringAdd := model.NewAutocompleteData(jitsiRecurringAddCommand, "\"[topic]\" [rule] [HH:MM] [duration]", "Add a recurring meeting to the current channel")
+	recurringAdd.AddTextArgument("The topic of the meeting, between quotes", "\"[topic]\"", "")
+	recurringAdd.AddTextArgument("daily, weekdays, weekly, weekly:MO,TH or an RRULE such as FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", "[rule]", "")
+	recurringAdd.AddTextArgument("The start time of the meeting, in your timezone", "[HH:MM]", "")
+	recurringAdd.AddTextArgument("(optional) The duration of the meeting, in minutes or like 1h30m", "[duration]", "")
+	recurring.AddCommand(recurringAdd)
+	recurringRemove := model.NewAutocompleteData(jitsiRecurringRemoveCommand, "[id]", "Remove a recurring meeting")
+	recurringRemove.AddTextArgument("The ID of the recurring meeting, see /jitsi recurring list", "[id]", "")
+	recurring.AddCommand(recurringRemove)
+	jitsi.AddCommand(recurring)
+
+	importData := model.NewAutocompleteData(jitsiImportCommand, "[file-id]", "Schedule the meetings of the .ics file you last uploaded in the current channel")
+	importData.AddTextArgument("(optional) The ID of the .ics file to import", "[file-id]", "")
+	jitsi.AddCommand(importData)
+
+	calendar := model.NewAutocompleteData(jitsiCalendarCommand, "[reset]", "Get the URL of your calendar feed of scheduled meetings")
+	calendarReset := model.NewAutocompleteData(jitsiCalendarResetCommand, "", "Replace the URL of your calendar feed, the previous one stops working")
+	calendar.AddCommand(calendarReset)
+	jitsi.AddCommand(calendar)
+
+	room := model.NewAutocompleteData(jitsiRoomCommand, "[reset]", "Show the permanent room of the current channel")
+	roomReset := model.NewAutocompleteData(jitsiRoomResetCommand, "", "Replace the permanent room of the current channel, the meetings running in the previous room end")
+	room.AddCommand(roomReset)
+	jitsi.AddCommand(room)
+
 	help := model.NewAutocompleteData("help", "", "Get slash command help")
 	jitsi.AddCommand(help)
 
@@ -103,73 +179,1025 @@ func getAutocompleteData() *model.AutocompleteData {
 	}}
 	namingScheme.AddStaticListArgument("Choose where the Jitsi meeting should open", true, items)
 	settings.AddCommand(namingScheme)
+
+	for _, setting := range []struct{ name, helpText string }{
+		{commandArgStartWithAudioMuted, "Choose whether you join meetings with your microphone muted"},
+		{commandArgStartWithVideoMuted, "Choose whether you join meetings with your camera turned off"},
+		{commandArgStartAudioOnly, "Choose whether you join meetings in audio only mode"},
+	} {
+		data := model.NewAutocompleteData(setting.name, "[value]", setting.helpText)
+		data.AddStaticListArgument(setting.helpText, true, []model.AutocompleteListItem{{
+			HelpText: "Enabled",
+			Item:     valueTrue,
+		}, {
+			HelpText: "Disabled",
+			Item:     valueFalse,
+		}, {
+			HelpText: "Follow the system setting",
+			Item:     valueDefault,
+		}})
+		settings.AddCommand(data)
+	}
+
+	inCallStatus := model.NewAutocompleteData(commandArgInCallStatus, "[value]", "Choose whether your custom status shows when you are in a meeting")
+	inCallStatus.AddStaticListArgument("Choose whether your custom status shows when you are in a meeting", true, []model.AutocompleteListItem{{
+		HelpText: "Your custom status is set to \"In a Jitsi meeting\" while you are in a meeting",
+		Item:     valueTrue,
+	}, {
+		HelpText: "Your custom status is left unchanged",
+		Item:     valueFalse,
+	}})
+	settings.AddCommand(inCallStatus)
 	jitsi.AddCommand(settings)
 
-	return jitsi
+	channelSettings := model.NewAutocompleteData(jitsiChannelSettingsCommand, "[setting] [value]", "Update the meeting defaults of the current channel (see /jitsi help for available options)")
+	channelSettings.AddCommand(model.NewAutocompleteData(jitsiSettingsSeeCommand, "", "See the current settings of the channel"))
+	optionalBool := []model.AutocompleteListItem{{
+		HelpText: "Enabled",
+		Item:     valueTrue,
+	}, {
+		HelpText: "Disabled",
+		Item:     valueFalse,
+	}, {
+		HelpText: "Follow the system setting",
+		Item:     valueDefault,
+	}}
+	channelNamingScheme := model.NewAutocompleteData(commandArgNamingScheme, "[value]", "Select how meeting names are generated in the channel")
+	channelNamingScheme.AddStaticListArgument("Select how meeting names are generated in the channel", true, append(items, model.AutocompleteListItem{
+		HelpText: "Follow the system setting",
+		Item:     valueDefault,
+	}))
+	channelSettings.AddCommand(channelNamingScheme)
+	roomName := model.NewAutocompleteData(commandArgRoomName, "[room]", "Use the same room for every meeting started in the channel without a topic")
+	roomName.AddTextArgument("The name of the room, or default to generate a new room for every meeting", "[room]", "")
+	channelSettings.AddCommand(roomName)
+	permanentRoom := model.NewAutocompleteData(commandArgPermanentRoom, "[value]", "Choose whether every meeting of the channel uses the permanent room of the channel")
+	permanentRoom.AddStaticListArgument("Choose whether every meeting of the channel uses the permanent room of the channel", true, optionalBool)
+	channelSettings.AddCommand(permanentRoom)
+	for _, setting := range []struct{ name, helpText string }{
+		{commandArgEmbedded, "Choose where the meetings of the channel open"},
+		{commandArgShowPrejoinPage, "Choose whether the pre-join page is visible on embedded meetings of the channel"},
+		{commandArgLobby, "Choose whether the participants knock and wait to be let in the meetings of the channel"},
+		{commandArgStartWithAudioMuted, "Choose whether participants join the meetings of the channel with their microphone muted"},
+		{commandArgStartWithVideoMuted, "Choose whether participants join the meetings of the channel with their camera turned off"},
+	} {
+		data := model.NewAutocompleteData(setting.name, "[value]", setting.helpText)
+		data.AddStaticListArgument(setting.helpText, true, optionalBool)
+		channelSettings.AddCommand(data)
+	}
+	whoCanStart := model.NewAutocompleteData(commandArgWhoCanStart, "[value]", "Choose who can start meetings in the channel")
+	whoCanStart.AddStaticListArgument("Choose who can start meetings in the channel", true, []model.AutocompleteListItem{{
+		HelpText: "Every channel member",
+		Item:     whoCanStartEveryone,
+	}, {
+		HelpText: "Only the channel admins",
+		Item:     whoCanStartAdmins,
+	}})
+	channelSettings.AddCommand(whoCanStart)
+	jitsi.AddCommand(channelSettings)
+
+	return jitsi
+}
+
+func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	split := strings.Fields(args.Command)
+	command := split[0]
+	var parameters []string
+	action := ""
+	if len(split) > 1 {
+		action = split[1]
+	}
+	if len(split) > 2 {
+		parameters = split[2:]
+	}
+
+	if command != "/"+jitsiCommand {
+		return &model.CommandResponse{}, nil
+	}
+
+	switch action {
+	case "help":
+		return p.executeHelpCommand(c, args)
+
+	case "settings":
+		return p.executeSettingsCommand(c, args, parameters)
+
+	case jitsiChannelSettingsCommand:
+		return p.executeChannelSettingsCommand(c, args, parameters)
+
+	case jitsiRoomCommand:
+		return p.executeRoomCommand(c, args, parameters)
+
+	case jitsiEndCommand:
+		return p.executeEndMeetingCommand(c, args, parameters)
+
+	case jitsiInviteCommand:
+		return p.executeInviteCommand(c, args, parameters)
+
+	case jitsiHistoryCommand:
+		return p.executeHistoryCommand(c, args, parameters)
+
+	case jitsiScheduleCommand:
+		return p.executeScheduleCommand(c, args)
+
+	case jitsiRecurringCommand:
+		return p.executeRecurringCommand(c, args, parameters)
+
+	case jitsiCalendarCommand:
+		return p.executeCalendarCommand(c, args, parameters)
+
+	case jitsiImportCommand:
+		return p.executeImportCommand(c, args, parameters)
+
+	case jitsiStartCommand:
+		fallthrough
+	default:
+		return p.executeStartMeetingCommand(c, args)
+	}
+}
+
+func (p *Plugin) executeStartMeetingCommand(_ *plugin.Context, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	input := strings.TrimSpace(strings.TrimPrefix(args.Command, "/"+jitsiCommand))
+	input = strings.TrimSpace(strings.TrimPrefix(input, jitsiStartCommand))
+
+	user, appErr := p.API.GetUser(args.UserId)
+	if appErr != nil {
+		return startMeetingError(args.ChannelId, fmt.Sprintf("getUser() threw error: %s", appErr))
+	}
+
+	channel, appErr := p.API.GetChannel(args.ChannelId)
+	if appErr != nil {
+		return startMeetingError(args.ChannelId, fmt.Sprintf("getChannel() threw error: %s", appErr))
+	}
+
+	userConfig, channelConfig, err := p.resolveUserConfig(args.UserId, args.ChannelId)
+	if err != nil {
+		return startMeetingError(args.ChannelId, fmt.Sprintf("getChannel() threw error: %s", err))
+	}
+
+	if err = p.checkStartPolicy(user, channel, channelConfig); err != nil {
+		if !isStartPolicyError(err) {
+			return startMeetingError(args.ChannelId, fmt.Sprintf("checkStartPolicy() threw error: %s", err))
+		}
+		return p.postCommandResponse(args, p.startPolicyMessage(p.b.GetUserLocalizer(args.UserId), err))
+	}
+
+	options, err := parseStartArguments(input)
+	if err != nil {
+		l := p.b.GetUserLocalizer(args.UserId)
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.start.invalid_parameters",
+				Other: "Invalid start parameters, use `/jitsi start [--lobby] [--password <password>] [--e2ee] [topic]`.",
+			},
+		}))
+	}
+	options.RootID = args.RootId
+
+	// Users asking for a topic or access restrictions start a new meeting instead of joining the
+	// running one.
+	if !options.explicit() {
+		unlock, err := p.lockMeetingStart(args.ChannelId, args.RootId)
+		if err != nil {
+			return startMeetingError(args.ChannelId, fmt.Sprintf("lockMeetingStart() threw error: %s", err))
+		}
+		defer unlock()
+		options.Unlock = unlock
+
+		running, err := p.getJoinableMeeting(args.ChannelId, args.RootId)
+		if err == nil {
+			p.sendMeetingAlreadyRunning(args.UserId, running, args.ChannelId, args.RootId)
+			return &model.CommandResponse{}, nil
+		}
+		if !errors.Is(err, errMeetingNotFound) {
+			return startMeetingError(args.ChannelId, fmt.Sprintf("getJoinableMeeting() threw error: %s", err))
+		}
+	}
+
+	// The options restricting the access to the meeting are not carried through the question, and
+	// channels with a fixed room have nothing to ask.
+	hasAccessOptions := options.Lobby || options.Password != "" || options.E2EE
+	if *userConfig.NamingScheme == jitsiNameSchemeAsk && options.Topic == "" && !hasAccessOptions && !p.hasChannelRoom(channelConfig) {
+		if err := p.askMeetingType(user, channel, args.RootId); err != nil {
+			return startMeetingError(args.ChannelId, fmt.Sprintf("startMeeting() threw error: %s", appErr))
+		}
+	} else {
+		if err := p.checkStartRateLimit(args.UserId, args.ChannelId); err != nil {
+			var limitErr *rateLimitError
+			if !errors.As(err, &limitErr) {
+				return startMeetingError(args.ChannelId, fmt.Sprintf("checkStartRateLimit() threw error: %s", err))
+			}
+			return p.postCommandResponse(args, p.rateLimitMessage(p.b.GetUserLocalizer(args.UserId), limitErr))
+		}
+		if _, err := p.startMeetingWithOptions(user, channel, options); err != nil {
+			return startMeetingError(args.ChannelId, fmt.Sprintf("startMeeting() threw error: %s", appErr))
+		}
+	}
+
+	p.trackMeeting(args)
+
+	return &model.CommandResponse{}, nil
+}
+
+func (p *Plugin) executeEndMeetingCommand(_ *plugin.Context, args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
+	l := p.b.GetUserLocalizer(args.UserId)
+
+	var meeting *Meeting
+	var err error
+	if len(parameters) > 0 {
+		meeting, err = p.findMeeting(parameters[0])
+	} else {
+		meeting, err = p.getRunningChannelMeeting(args.ChannelId)
+	}
+	if errors.Is(err, errMeetingNotFound) {
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.end.not_found",
+				Other: "No running meeting found.",
+			},
+		}))
+	}
+	if err != nil {
+		mlog.Error("Unable to get the meeting to end", mlog.Err(err))
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.end.error",
+				Other: "We could not end the meeting at this time.",
+			},
+		}))
+	}
+
+	if !p.canManageMeeting(args.UserId, meeting) {
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.end.forbidden",
+				Other: "Only the meeting creator or a channel admin can end this meeting.",
+			},
+		}))
+	}
+
+	if _, err = p.endMeeting(meeting.ID); err != nil {
+		if errors.Is(err, errMeetingAlreadyEnded) {
+			return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "jitsi.command.end.already_ended",
+					Other: "This meeting has already ended.",
+				},
+			}))
+		}
+		mlog.Error("Unable to end the meeting", mlog.String("meeting_id", meeting.ID), mlog.Err(err))
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.end.error",
+				Other: "We could not end the meeting at this time.",
+			},
+		}))
+	}
+
+	return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "jitsi.command.end.success",
+			Other: "Meeting `{{.MeetingID}}` ended.",
+		},
+		TemplateData: map[string]string{"MeetingID": meeting.Room},
+	}))
+}
+
+func (p *Plugin) executeInviteCommand(_ *plugin.Context, args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
+	l := p.b.GetUserLocalizer(args.UserId)
+
+	var usernames []string
+	meetingRef := ""
+	for _, parameter := range parameters {
+		if strings.HasPrefix(parameter, "@") {
+			usernames = append(usernames, strings.TrimPrefix(parameter, "@"))
+		} else if meetingRef == "" {
+			meetingRef = parameter
+		}
+	}
+
+	var meeting *Meeting
+	var err error
+	if meetingRef != "" {
+		meeting, err = p.findMeeting(meetingRef)
+	} else {
+		meeting, err = p.getRunningChannelMeeting(args.ChannelId)
+	}
+	if errors.Is(err, errMeetingNotFound) {
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.invite.not_found",
+				Other: "No running meeting found.",
+			},
+		}))
+	}
+	if err != nil {
+		mlog.Error("Unable to get the meeting to invite users to", mlog.Err(err))
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.invite.error",
+				Other: "We could not invite the users at this time.",
+			},
+		}))
+	}
+
+	if !p.canManageMeeting(args.UserId, meeting) {
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.invite.forbidden",
+				Other: "Only the meeting creator or a channel admin can invite users to this meeting.",
+			},
+		}))
+	}
+
+	if len(usernames) == 0 {
+		return p.postCommandResponse(args, p.invitationsList(l, meeting))
+	}
+
+	var users []*model.User
+	var unknown []string
+	for _, username := range usernames {
+		user, appErr := p.API.GetUserByUsername(username)
+		if appErr != nil || user.IsBot || user.DeleteAt > 0 {
+			unknown = append(unknown, "@"+username)
+			continue
+		}
+		users = append(users, user)
+	}
+	if len(unknown) > 0 {
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.invite.unknown_users",
+				Other: "Unable to find {{.Usernames}}.",
+			},
+			TemplateData: map[string]string{"Usernames": strings.Join(unknown, ", ")},
+		}))
+	}
+
+	inviter, appErr := p.API.GetUser(args.UserId)
+	if appErr != nil {
+		mlog.Error("Unable to get the user inviting users", mlog.Err(appErr))
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.invite.error",
+				Other: "We could not invite the users at this time.",
+			},
+		}))
+	}
+
+	if _, err = p.inviteUsers(inviter, meeting, users); err != nil {
+		if errors.Is(err, errMeetingAlreadyEnded) {
+			return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "jitsi.command.invite.already_ended",
+					Other: "This meeting has already ended.",
+				},
+			}))
+		}
+		mlog.Error("Unable to invite the users", mlog.String("meeting_id", meeting.ID), mlog.Err(err))
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.invite.error",
+				Other: "We could not invite the users at this time.",
+			},
+		}))
+	}
+
+	invited := make([]string, 0, len(users))
+	for _, user := range users {
+		invited = append(invited, "@"+user.Username)
+	}
+	return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "jitsi.command.invite.success",
+			Other: "Invited {{.Usernames}} to meeting `{{.MeetingID}}`.",
+		},
+		TemplateData: map[string]string{"Usernames": strings.Join(invited, ", "), "MeetingID": meeting.Room},
+	}))
+}
+
+// invitationsList lists the users invited to a meeting along with their answers.
+func (p *Plugin) invitationsList(l *i18n.Localizer, meeting *Meeting) string {
+	if len(meeting.Invitations) == 0 {
+		return p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.invite.no_invitations",
+				Other: "Nobody was invited to meeting `{{.MeetingID}}`.",
+			},
+			TemplateData: map[string]string{"MeetingID": meeting.Room},
+		})
+	}
+
+	lines := []string{p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "jitsi.command.invite.list_title",
+			Other: "###### Invitations to meeting `{{.MeetingID}}`",
+		},
+		TemplateData: map[string]string{"MeetingID": meeting.Room},
+	})}
+	for _, invitation := range meeting.Invitations {
+		var state string
+		switch invitation.State {
+		case InvitationStateAccepted:
+			state = p.b.LocalizeDefaultMessage(l, &i18n.Message{ID: "jitsi.command.invite.state_accepted", Other: "accepted"})
+		case InvitationStateDeclined:
+			state = p.b.LocalizeDefaultMessage(l, &i18n.Message{ID: "jitsi.command.invite.state_declined", Other: "declined"})
+		default:
+			state = p.b.LocalizeDefaultMessage(l, &i18n.Message{ID: "jitsi.command.invite.state_invited", Other: "no answer yet"})
+		}
+		lines = append(lines, "* @"+p.username(invitation.UserID)+": "+state)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (p *Plugin) executeHistoryCommand(_ *plugin.Context, args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
+	l := p.b.GetUserLocalizer(args.UserId)
+
+	limit := defaultHistoryLength
+	if len(parameters) > 0 {
+		n, err := strconv.Atoi(parameters[0])
+		if err != nil || n < 1 {
+			return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "jitsi.command.history.invalid_length",
+					Other: "Invalid number of meetings, use a positive number.",
+				},
+			}))
+		}
+		limit = min(n, maxHistoryLength)
+	}
+
+	user, appErr := p.API.GetUser(args.UserId)
+	if appErr != nil {
+		mlog.Error("Unable to get the user", mlog.Err(appErr))
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.history.error",
+				Other: "We could not get the meeting history at this time.",
+			},
+		}))
+	}
+
+	meetings, err := p.getChannelMeetingHistory(args.ChannelId, args.UserId, limit)
+	if errors.Is(err, errNotChannelMember) {
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.history.forbidden",
+				Other: "You must be a member of this channel to see its meeting history.",
+			},
+		}))
+	}
+	if err != nil {
+		mlog.Error("Unable to get the meeting history", mlog.Err(err))
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.history.error",
+				Other: "We could not get the meeting history at this time.",
+			},
+		}))
+	}
+
+	if len(meetings) == 0 {
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.history.empty",
+				Other: "No meetings have been started in this channel yet.",
+			},
+		}))
+	}
+
+	text := p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "jitsi.command.history.title",
+			Other: "###### Recent meetings in this channel",
+		},
+	})
+	siteURL := *p.API.GetConfig().ServiceSettings.SiteURL
+	location := user.GetTimezoneLocation()
+	usernames := map[string]string{}
+	for _, meeting := range meetings {
+		username, ok := usernames[meeting.CreatorID]
+		if !ok {
+			if creator, appErr := p.API.GetUser(meeting.CreatorID); appErr == nil {
+				username = "@" + creator.Username
+			}
+			usernames[meeting.CreatorID] = username
+		}
+
+		text += "\n" + p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.history.meeting",
+				Other: "* **{{.Topic}}** - `{{.MeetingID}}` started by {{.Creator}} on {{.Datetime}} ([view post]({{.PostURL}}))",
+			},
+			TemplateData: map[string]string{
+				"Topic":     meeting.Topic,
+				"MeetingID": meeting.Room,
+				"Creator":   username,
+				"Datetime":  meeting.StartTime().In(location).Format("Mon Jan 2 15:04 MST 2006"),
+				"PostURL":   siteURL + "/_redirect/pl/" + meeting.PostID,
+			},
+		})
+	}
+
+	return p.postCommandResponse(args, text)
+}
+
+// parseStartArguments parses the arguments of the start command, a topic mixed with the --lobby,
+// --password and --e2ee options.
+func parseStartArguments(input string) (startMeetingOptions, error) {
+	var options startMeetingOptions
+	var topic []string
+	fields := strings.Fields(input)
+	for i := 0; i < len(fields); i++ {
+		switch field := fields[i]; {
+		case field == "--lobby":
+			options.Lobby = true
+		case field == "--e2ee":
+			options.E2EE = true
+		case field == "--password":
+			// The password is required, an option following --password is not taken for it.
+			if i+1 == len(fields) || strings.HasPrefix(fields[i+1], "--") {
+				return options, errors.New("missing password")
+			}
+			i++
+			options.Password = fields[i]
+		case strings.HasPrefix(field, "--password="):
+			options.Password = strings.TrimPrefix(field, "--password=")
+			if options.Password == "" || strings.HasPrefix(options.Password, "--") {
+				return options, errors.New("missing password")
+			}
+		case strings.HasPrefix(field, "--"):
+			return options, errors.Errorf("unknown option %s", field)
+		default:
+			topic = append(topic, field)
+		}
+	}
+
+	options.Topic = strings.Join(topic, " ")
+	return options, nil
+}
+
+// parseScheduleArguments parses the arguments of the schedule command, a quoted topic followed by
+// the start date and time in the given location and an optional duration.
+func parseScheduleArguments(input string, location *time.Location) (string, time.Time, time.Duration, error) {
+	topic, input, err := parseTopicArgument(input)
+	if err != nil {
+		return "", time.Time{}, 0, err
+	}
+
+	fields := strings.Fields(input)
+	if len(fields) < 2 || len(fields) > 3 {
+		return "", time.Time{}, 0, errors.New("invalid number of arguments")
+	}
+
+	startAt, err := time.ParseInLocation("2006-01-02 15:04", fields[0]+" "+fields[1], location)
+	if err != nil {
+		return "", time.Time{}, 0, err
+	}
+
+	duration := defaultScheduledMeetingDuration
+	if len(fields) == 3 {
+		duration, err = parseMeetingDuration(fields[2])
+		if err != nil {
+			return "", time.Time{}, 0, err
+		}
+	}
+
+	return topic, startAt, duration, nil
+}
+
+// parseRecurringArguments parses the arguments of the recurring add command, a quoted topic
+// followed by the recurrence rule, the start time in the given location and an optional duration.
+// The returned start time is on the current day.
+func parseRecurringArguments(input string, location *time.Location, now time.Time) (string, *RecurrenceRule, time.Time, time.Duration, error) {
+	topic, input, err := parseTopicArgument(input)
+	if err != nil {
+		return "", nil, time.Time{}, 0, err
+	}
+
+	fields := strings.Fields(input)
+	if len(fields) < 2 || len(fields) > 3 {
+		return "", nil, time.Time{}, 0, errors.New("invalid number of arguments")
+	}
+
+	clock, err := time.Parse("15:04", fields[1])
+	if err != nil {
+		return "", nil, time.Time{}, 0, err
+	}
+	now = now.In(location)
+	startAt := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, location)
+
+	rule, err := parseRecurrenceRule(fields[0], startAt)
+	if err != nil {
+		return "", nil, time.Time{}, 0, err
+	}
+
+	duration := defaultScheduledMeetingDuration
+	if len(fields) == 3 {
+		duration, err = parseMeetingDuration(fields[2])
+		if err != nil {
+			return "", nil, time.Time{}, 0, err
+		}
+	}
+
+	return topic, rule, startAt, duration, nil
+}
+
+// parseTopicArgument splits a topic, quoted when it contains spaces, from the rest of the input.
+func parseTopicArgument(input string) (string, string, error) {
+	var topic string
+	input = strings.TrimSpace(input)
+	if strings.HasPrefix(input, "\"") {
+		end := strings.Index(input[1:], "\"")
+		if end < 0 {
+			return "", "", errors.New("unterminated topic")
+		}
+		topic = strings.TrimSpace(input[1 : end+1])
+		input = input[end+2:]
+	} else if fields := strings.Fields(input); len(fields) > 0 {
+		topic = fields[0]
+		input = strings.TrimPrefix(input, topic)
+	}
+	if topic == "" {
+		return "", "", errors.New("missing topic")
+	}
+	return topic, input, nil
+}
+
+// parseMeetingDuration parses a duration given either as a number of minutes or as a Go duration
+// such as 1h30m.
+func parseMeetingDuration(value string) (time.Duration, error) {
+	var duration time.Duration
+	if minutes, err := strconv.Atoi(value); err == nil {
+		duration = time.Duration(minutes) * time.Minute
+	} else {
+		duration, err = time.ParseDuration(value)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if duration <= 0 {
+		return 0, errors.New("the duration must be positive")
+	}
+	return duration, nil
+}
+
+func (p *Plugin) executeScheduleCommand(_ *plugin.Context, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	l := p.b.GetUserLocalizer(args.UserId)
+	input := strings.TrimSpace(strings.TrimPrefix(args.Command, "/"+jitsiCommand))
+	input = strings.TrimSpace(strings.TrimPrefix(input, jitsiScheduleCommand))
+
+	scheduleError := func() (*model.CommandResponse, *model.AppError) {
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.schedule.error",
+				Other: "We could not schedule the meeting at this time.",
+			},
+		}))
+	}
+
+	user, appErr := p.API.GetUser(args.UserId)
+	if appErr != nil {
+		mlog.Error("Unable to get the user", mlog.Err(appErr))
+		return scheduleError()
+	}
+
+	topic, startAt, duration, err := parseScheduleArguments(input, user.GetTimezoneLocation())
+	if err != nil {
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.schedule.invalid_parameters",
+				Other: "Invalid schedule parameters, use `/jitsi schedule \"[topic]\" [YYYY-MM-DD] [HH:MM] [duration]`, for example `/jitsi schedule \"Sprint review\" 2026-10-21 14:00 1h`.",
+			},
+		}))
+	}
+
+	channel, appErr := p.API.GetChannel(args.ChannelId)
+	if appErr != nil {
+		mlog.Error("Unable to get the channel", mlog.Err(appErr))
+		return scheduleError()
+	}
+
+	if err = p.checkChannelStartPolicy(user, channel); err != nil {
+		if !isStartPolicyError(err) {
+			mlog.Error("Unable to check the meeting policy", mlog.Err(err))
+			return scheduleError()
+		}
+		return p.postCommandResponse(args, p.startPolicyMessage(l, err))
+	}
+
+	meeting, err := p.scheduleMeeting(user, channel, topic, startAt, duration, args.RootId)
+	if errors.Is(err, errStartInThePast) {
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.schedule.start_in_the_past",
+				Other: "The meeting start time must be in the future.",
+			},
+		}))
+	}
+	if err != nil {
+		mlog.Error("Unable to schedule the meeting", mlog.Err(err))
+		return scheduleError()
+	}
+
+	return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "jitsi.command.schedule.success",
+			Other: "Meeting `{{.MeetingID}}` scheduled. Use `/jitsi end {{.MeetingID}}` to cancel it.",
+		},
+		TemplateData: map[string]string{"MeetingID": meeting.Room},
+	}))
+}
+
+func (p *Plugin) executeRecurringCommand(c *plugin.Context, args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
+	subcommand := ""
+	if len(parameters) > 0 {
+		subcommand = parameters[0]
+	}
+
+	switch subcommand {
+	case jitsiRecurringAddCommand:
+		return p.executeRecurringAddCommand(c, args)
+	case jitsiRecurringRemoveCommand:
+		return p.executeRecurringRemoveCommand(c, args, parameters[1:])
+	case jitsiRecurringListCommand:
+		return p.executeRecurringListCommand(c, args)
+	default:
+		l := p.b.GetUserLocalizer(args.UserId)
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.recurring.invalid_command",
+				Other: "Invalid recurring command, use `/jitsi recurring list`, `/jitsi recurring add` or `/jitsi recurring remove`.",
+			},
+		}))
+	}
+}
+
+func (p *Plugin) executeRecurringListCommand(_ *plugin.Context, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	l := p.b.GetUserLocalizer(args.UserId)
+
+	user, appErr := p.API.GetUser(args.UserId)
+	if appErr != nil {
+		mlog.Error("Unable to get the user", mlog.Err(appErr))
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.recurring.list_error",
+				Other: "We could not get the recurring meetings at this time.",
+			},
+		}))
+	}
+
+	recurringMeetings, err := p.getChannelRecurringMeetings(args.ChannelId)
+	if err != nil {
+		mlog.Error("Unable to get the recurring meetings", mlog.Err(err))
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.recurring.list_error",
+				Other: "We could not get the recurring meetings at this time.",
+			},
+		}))
+	}
+
+	if len(recurringMeetings) == 0 {
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.recurring.empty",
+				Other: "There is no recurring meeting in this channel.",
+			},
+		}))
+	}
+
+	text := p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "jitsi.command.recurring.title",
+			Other: "###### Recurring meetings in this channel",
+		},
+	})
+	location := user.GetTimezoneLocation()
+	for _, recurring := range recurringMeetings {
+		text += "\n" + p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.recurring.meeting",
+				Other: "* **{{.Topic}}** - `{{.MeetingID}}` repeating `{{.Rule}}`, next on {{.Datetime}} (ID: `{{.ID}}`)",
+			},
+			TemplateData: map[string]string{
+				"Topic":     recurring.Topic,
+				"MeetingID": recurring.Room,
+				"Rule":      recurring.Rule,
+				"Datetime":  time.UnixMilli(recurring.NextAt).In(location).Format("Mon Jan 2 15:04 MST 2006"),
+				"ID":        recurring.ID,
+			},
+		})
+	}
+
+	return p.postCommandResponse(args, text)
 }
 
-func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
-	split := strings.Fields(args.Command)
-	command := split[0]
-	var parameters []string
-	action := ""
-	if len(split) > 1 {
-		action = split[1]
+func (p *Plugin) executeRecurringAddCommand(_ *plugin.Context, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	l := p.b.GetUserLocalizer(args.UserId)
+	input := strings.TrimSpace(strings.TrimPrefix(args.Command, "/"+jitsiCommand))
+	input = strings.TrimSpace(strings.TrimPrefix(input, jitsiRecurringCommand))
+	input = strings.TrimSpace(strings.TrimPrefix(input, jitsiRecurringAddCommand))
+
+	addError := func() (*model.CommandResponse, *model.AppError) {
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.recurring.add_error",
+				Other: "We could not add the recurring meeting at this time.",
+			},
+		}))
 	}
-	if len(split) > 2 {
-		parameters = split[2:]
+
+	user, appErr := p.API.GetUser(args.UserId)
+	if appErr != nil {
+		mlog.Error("Unable to get the user", mlog.Err(appErr))
+		return addError()
 	}
 
-	if command != "/"+jitsiCommand {
-		return &model.CommandResponse{}, nil
+	topic, rule, startAt, duration, err := parseRecurringArguments(input, user.GetTimezoneLocation(), time.Now())
+	if err != nil {
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.recurring.invalid_parameters",
+				Other: "Invalid recurring meeting parameters, use `/jitsi recurring add \"[topic]\" [rule] [HH:MM] [duration]`, for example `/jitsi recurring add \"Daily standup\" weekdays 09:30 15`.",
+			},
+		}))
 	}
 
-	switch action {
-	case "help":
-		return p.executeHelpCommand(c, args)
+	channel, appErr := p.API.GetChannel(args.ChannelId)
+	if appErr != nil {
+		mlog.Error("Unable to get the channel", mlog.Err(appErr))
+		return addError()
+	}
 
-	case "settings":
-		return p.executeSettingsCommand(c, args, parameters)
+	if err = p.checkChannelStartPolicy(user, channel); err != nil {
+		if !isStartPolicyError(err) {
+			mlog.Error("Unable to check the meeting policy", mlog.Err(err))
+			return addError()
+		}
+		return p.postCommandResponse(args, p.startPolicyMessage(l, err))
+	}
 
-	case jitsiStartCommand:
-		fallthrough
-	default:
-		return p.executeStartMeetingCommand(c, args)
+	recurring, err := p.addRecurringMeeting(user, channel, topic, rule, startAt, duration, nil)
+	if errors.Is(err, errNoOccurrence) {
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.recurring.no_occurrence",
+				Other: "The recurrence rule has no upcoming occurrence.",
+			},
+		}))
+	}
+	if err != nil {
+		mlog.Error("Unable to add the recurring meeting", mlog.Err(err))
+		return addError()
 	}
+
+	return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "jitsi.command.recurring.add_success",
+			Other: "Recurring meeting **{{.Topic}}** added in room `{{.MeetingID}}`, the first occurrence is on {{.Datetime}}. Use `/jitsi recurring remove {{.ID}}` to remove it.",
+		},
+		TemplateData: map[string]string{
+			"Topic":     recurring.Topic,
+			"MeetingID": recurring.Room,
+			"Datetime":  time.UnixMilli(recurring.NextAt).In(user.GetTimezoneLocation()).Format("Mon Jan 2 15:04 MST 2006"),
+			"ID":        recurring.ID,
+		},
+	}))
 }
 
-func (p *Plugin) executeStartMeetingCommand(_ *plugin.Context, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
-	input := strings.TrimSpace(strings.TrimPrefix(args.Command, "/"+jitsiCommand))
-	input = strings.TrimSpace(strings.TrimPrefix(input, jitsiStartCommand))
+func (p *Plugin) executeRecurringRemoveCommand(_ *plugin.Context, args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
+	l := p.b.GetUserLocalizer(args.UserId)
+
+	removeError := func() (*model.CommandResponse, *model.AppError) {
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.recurring.remove_error",
+				Other: "We could not remove the recurring meeting at this time.",
+			},
+		}))
+	}
+
+	if len(parameters) == 0 {
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.recurring.missing_id",
+				Other: "Missing recurring meeting ID, use `/jitsi recurring list` to find it.",
+			},
+		}))
+	}
+
+	recurring, err := p.getRecurringMeeting(parameters[0])
+	if errors.Is(err, errRecurringMeetingNotFound) {
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.recurring.not_found",
+				Other: "Recurring meeting not found.",
+			},
+		}))
+	}
+	if err != nil {
+		mlog.Error("Unable to get the recurring meeting", mlog.Err(err))
+		return removeError()
+	}
+
+	if !p.isCreatorOrChannelAdmin(args.UserId, recurring.CreatorID, recurring.ChannelID) {
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.recurring.forbidden",
+				Other: "Only the meeting creator or a channel admin can remove this recurring meeting.",
+			},
+		}))
+	}
+
+	if err = p.removeRecurringMeeting(recurring); err != nil {
+		mlog.Error("Unable to remove the recurring meeting", mlog.String("recurring_id", recurring.ID), mlog.Err(err))
+		return removeError()
+	}
+
+	return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "jitsi.command.recurring.remove_success",
+			Other: "Recurring meeting **{{.Topic}}** removed.",
+		},
+		TemplateData: map[string]string{"Topic": recurring.Topic},
+	}))
+}
+
+func (p *Plugin) executeImportCommand(_ *plugin.Context, args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
+	l := p.b.GetUserLocalizer(args.UserId)
+
+	fileID := ""
+	if len(parameters) > 0 {
+		fileID = parameters[0]
+	}
 
 	user, appErr := p.API.GetUser(args.UserId)
 	if appErr != nil {
-		return startMeetingError(args.ChannelId, fmt.Sprintf("getUser() threw error: %s", appErr))
+		mlog.Error("Unable to get the user", mlog.Err(appErr))
+		return p.postCommandResponse(args, p.importErrorMessage(l, appErr))
 	}
 
 	channel, appErr := p.API.GetChannel(args.ChannelId)
 	if appErr != nil {
-		return startMeetingError(args.ChannelId, fmt.Sprintf("getChannel() threw error: %s", appErr))
+		mlog.Error("Unable to get the channel", mlog.Err(appErr))
+		return p.postCommandResponse(args, p.importErrorMessage(l, appErr))
 	}
 
-	userConfig, err := p.getUserConfig(args.UserId)
+	info, err := p.findCalendarFile(args.UserId, args.ChannelId, fileID)
 	if err != nil {
-		return startMeetingError(args.ChannelId, fmt.Sprintf("getChannel() threw error: %s", err))
+		return p.postCommandResponse(args, p.importErrorMessage(l, err))
 	}
 
-	if userConfig.NamingScheme == jitsiNameSchemeAsk && input == "" {
-		if err := p.askMeetingType(user, channel, args.RootId); err != nil {
-			return startMeetingError(args.ChannelId, fmt.Sprintf("startMeeting() threw error: %s", appErr))
-		}
+	result, err := p.importCalendarFile(user, channel, info, args.RootId)
+	if err != nil {
+		mlog.Warn("Unable to import the calendar file", mlog.String("file_id", info.Id), mlog.Err(err))
+		return p.postCommandResponse(args, p.importErrorMessage(l, err))
+	}
+
+	return p.postCommandResponse(args, p.formatImportResult(l, user, info.Name, result))
+}
+
+func (p *Plugin) executeCalendarCommand(_ *plugin.Context, args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
+	l := p.b.GetUserLocalizer(args.UserId)
+
+	reset := len(parameters) > 0 && parameters[0] == jitsiCalendarResetCommand
+
+	var token string
+	var err error
+	if reset {
+		token, err = p.resetCalendarToken(args.UserId)
 	} else {
-		if _, err := p.startMeeting(user, channel, "", input, false, args.RootId); err != nil {
-			return startMeetingError(args.ChannelId, fmt.Sprintf("startMeeting() threw error: %s", appErr))
-		}
+		token, err = p.getCalendarToken(args.UserId)
+	}
+	if err != nil {
+		mlog.Error("Unable to get the calendar token", mlog.Err(err))
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.calendar.error",
+				Other: "We could not get your calendar feed at this time.",
+			},
+		}))
 	}
 
-	p.trackMeeting(args)
+	if reset {
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.calendar.reset",
+				Other: "Your calendar feed URL was replaced, the previous one no longer works. The new URL is {{.URL}}",
+			},
+			TemplateData: map[string]string{"URL": p.getCalendarFeedURL(token)},
+		}))
+	}
 
-	return &model.CommandResponse{}, nil
+	return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "jitsi.command.calendar.url",
+			Other: "Subscribe to {{.URL}} in your calendar application to see the scheduled and recurring meetings of your channels. Keep this URL private, or use `/jitsi calendar reset` to replace it.",
+		},
+		TemplateData: map[string]string{"URL": p.getCalendarFeedURL(token)},
+	}))
 }
 
 func (p *Plugin) executeHelpCommand(_ *plugin.Context, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
@@ -186,9 +1214,24 @@ func (p *Plugin) executeHelpCommand(_ *plugin.Context, args *model.CommandArgs)
 			ID: "jitsi.command.help.text",
 			Other: `* |/jitsi| - Create a new meeting
 * |/jitsi start [topic]| - Create a new meeting with specified topic
+* |/jitsi start --lobby --password <password> --e2ee [topic]| - Create a new meeting restricting its access: |--lobby| makes participants knock once a moderator enabled the lobby of the meeting, |--password| sends a password to the channel members for a moderator to set in the meeting, and |--e2ee| enables end-to-end encryption. The Jitsi server does not enforce the lobby and the password until a moderator turns them on. Each option is optional
+* |/jitsi schedule "[topic]" [YYYY-MM-DD] [HH:MM] [duration]| - Schedule a meeting in the current channel, the meeting link is posted shortly before it starts
+* |/jitsi recurring add "[topic]" [rule] [HH:MM] [duration]| - Add a meeting taking place regularly in the same room, the rule is one of |daily|, |weekdays|, |weekly|, |weekly:MO,TH| or an RRULE such as |FREQ=WEEKLY;INTERVAL=2;BYDAY=MO|
+* |/jitsi recurring list| - List the recurring meetings of the current channel
+* |/jitsi recurring remove [id]| - Remove a recurring meeting
+* |/jitsi import [file-id]| - Schedule the meetings of the |.ics| file you last uploaded in the current channel. You can also mention @jitsi in the message of the uploaded file
+* |/jitsi calendar| - Get the URL of a calendar feed with the scheduled and recurring meetings of your channels, to subscribe to from your calendar application
+* |/jitsi calendar reset| - Replace the URL of your calendar feed, the previous one stops working
+* |/jitsi end [meeting-id]| - End a meeting, by default the latest running meeting of the current channel
+* |/jitsi invite @username... [meeting-id]| - Invite users to a meeting, by default the latest running meeting of the current channel, even if they are not members of the channel. Without users, see who accepted the invitations
+* |/jitsi history [n]| - List the last n meetings started in the current channel
+* |/jitsi room| - Show the permanent room of the current channel
+* |/jitsi room reset| - Replace the permanent room of the current channel, for channel admins. The meetings running in the previous room end
 * |/jitsi help| - Show this help text
 * |/jitsi settings see| - View your current user settings for the Jitsi plugin
 * |/jitsi settings [setting] [value]| - Update your user settings (see below for options)
+* |/jitsi channel-settings see| - View the meeting defaults of the current channel
+* |/jitsi channel-settings [setting] [value]| - Update the meeting defaults of the current channel, for channel admins (see below for options)
 
 ###### Jitsi Settings:
 * |/jitsi settings embedded [true/false]|: (Experimental) When true, Jitsi meeting is embedded as a floating window inside Mattermost. When false, Jitsi meeting opens in a new window.
@@ -197,7 +1240,19 @@ func (p *Plugin) executeHelpCommand(_ *plugin.Context, args *model.CommandArgs)
     * |words|: Random English words in title case (e.g. PlayfulDragonsObserveCuriously)
     * |uuid|: UUID (universally unique identifier)
     * |mattermost|: Mattermost specific names. Combination of team name, channel name and random text in public and private channels; personal meeting name in direct and group messages channels.
-    * |ask|: The plugin asks you to select the name every time you start a meeting`,
+    * |ask|: The plugin asks you to select the name every time you start a meeting
+* |/jitsi settings start_with_audio_muted [true/false/default]|: Join meetings with your microphone muted. |default| follows the system setting.
+* |/jitsi settings start_with_video_muted [true/false/default]|: Join meetings with your camera turned off. |default| follows the system setting.
+* |/jitsi settings start_audio_only [true/false/default]|: Join meetings in audio only mode. |default| follows the system setting.
+* |/jitsi settings in_call_status [true/false]|: When true, your custom status is set to "In a Jitsi meeting" while you are in a meeting, and restored when you leave.
+
+###### Jitsi Channel Settings:
+The settings of the channel apply unless you changed them in your own settings. |default| follows the system setting.
+* |/jitsi channel-settings naming_scheme [words/uuid/mattermost/ask/default]|: Select how meeting names are generated in the channel.
+* |/jitsi channel-settings room_name [room/default]|: Start every meeting without a topic in the same room.
+* |/jitsi channel-settings permanent_room [true/false/default]|: Start every meeting in the permanent room of the channel, created on first use.
+* |/jitsi channel-settings [embedded/show_prejoin_page/lobby/start_with_audio_muted/start_with_video_muted] [true/false/default]|: Set the defaults of the meetings of the channel.
+* |/jitsi channel-settings who_can_start [everyone/admins]|: Choose who can start meetings in the channel.`,
 		},
 	})
 
@@ -213,6 +1268,19 @@ func (p *Plugin) executeHelpCommand(_ *plugin.Context, args *model.CommandArgs)
 	return &model.CommandResponse{}, nil
 }
 
+// postCommandResponse sends text to the user who ran the command as an ephemeral bot post.
+func (p *Plugin) postCommandResponse(args *model.CommandArgs, text string) (*model.CommandResponse, *model.AppError) {
+	post := &model.Post{
+		UserId:    p.botID,
+		ChannelId: args.ChannelId,
+		Message:   text,
+		RootId:    args.RootId,
+	}
+	_ = p.API.SendEphemeralPost(args.UserId, post)
+
+	return &model.CommandResponse{}, nil
+}
+
 func (p *Plugin) settingsError(userID string, channelID string, errorText string, rootID string) (*model.CommandResponse, *model.AppError) {
 	post := &model.Post{
 		UserId:    p.botID,
@@ -225,6 +1293,27 @@ func (p *Plugin) settingsError(userID string, channelID string, errorText string
 	return &model.CommandResponse{}, nil
 }
 
+// parseOptionalBool parses the value of a user setting overriding a system setting, where
+// default removes the override.
+func parseOptionalBool(value string) (*bool, bool) {
+	switch value {
+	case valueTrue:
+		return model.NewPointer(true), true
+	case valueFalse:
+		return model.NewPointer(false), true
+	case valueDefault:
+		return nil, true
+	}
+	return nil, false
+}
+
+func formatOptionalBool(value *bool) string {
+	if value == nil {
+		return valueDefault
+	}
+	return strconv.FormatBool(*value)
+}
+
 func (p *Plugin) executeSettingsCommand(_ *plugin.Context, args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
 	l := p.b.GetUserLocalizer(args.UserId)
 	text := ""
@@ -241,18 +1330,29 @@ func (p *Plugin) executeSettingsCommand(_ *plugin.Context, args *model.CommandAr
 	}
 
 	if len(parameters) == 0 || parameters[0] == jitsiSettingsSeeCommand {
+		// Settings the user did not save show the system configuration.
+		current := *userConfig
+		p.applyDefaults(&current)
 		text = p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
 			DefaultMessage: &i18n.Message{
 				ID: "jitsi.command.settings.current_values",
 				Other: `###### Jitsi Settings:
 * Embedded: |{{.Embedded}}|
 * Show Pre-join Page: |{{.ShowPrejoinPage}}|
-* Naming Scheme: |{{.NamingScheme}}|`,
+* Naming Scheme: |{{.NamingScheme}}|
+* Start With Audio Muted: |{{.StartWithAudioMuted}}|
+* Start With Video Muted: |{{.StartWithVideoMuted}}|
+* Start Audio Only: |{{.StartAudioOnly}}|
+* In-call Status: |{{.InCallStatus}}|`,
 			},
 			TemplateData: map[string]string{
-				"Embedded":        fmt.Sprintf("%v", userConfig.Embedded),
-				"ShowPrejoinPage": fmt.Sprintf("%v", userConfig.ShowPrejoinPage),
-				"NamingScheme":    userConfig.NamingScheme,
+				"Embedded":            fmt.Sprintf("%v", *current.Embedded),
+				"ShowPrejoinPage":     fmt.Sprintf("%v", *current.ShowPrejoinPage),
+				"NamingScheme":        *current.NamingScheme,
+				"StartWithAudioMuted": formatOptionalBool(userConfig.StartWithAudioMuted),
+				"StartWithVideoMuted": formatOptionalBool(userConfig.StartWithVideoMuted),
+				"StartAudioOnly":      formatOptionalBool(userConfig.StartAudioOnly),
+				"InCallStatus":        fmt.Sprintf("%v", userConfig.InCallStatus),
 			},
 		})
 		post := &model.Post{
@@ -279,9 +1379,9 @@ func (p *Plugin) executeSettingsCommand(_ *plugin.Context, args *model.CommandAr
 	case commandArgEmbedded:
 		switch parameters[1] {
 		case valueTrue:
-			userConfig.Embedded = true
+			userConfig.Embedded = model.NewPointer(true)
 		case valueFalse:
-			userConfig.Embedded = false
+			userConfig.Embedded = model.NewPointer(false)
 		default:
 			text = p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
 				DefaultMessage: &i18n.Message{
@@ -294,13 +1394,13 @@ func (p *Plugin) executeSettingsCommand(_ *plugin.Context, args *model.CommandAr
 	case commandArgNamingScheme:
 		switch parameters[1] {
 		case jitsiNameSchemeAsk:
-			userConfig.NamingScheme = "ask"
+			userConfig.NamingScheme = model.NewPointer("ask")
 		case jitsiNameSchemeWords:
-			userConfig.NamingScheme = "words"
+			userConfig.NamingScheme = model.NewPointer("words")
 		case jitsiNameSchemeUUID:
-			userConfig.NamingScheme = "uuid"
+			userConfig.NamingScheme = model.NewPointer("uuid")
 		case jitsiNameSchemeMattermost:
-			userConfig.NamingScheme = "mattermost"
+			userConfig.NamingScheme = model.NewPointer("mattermost")
 		default:
 			text = p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
 				DefaultMessage: &i18n.Message{
@@ -313,9 +1413,9 @@ func (p *Plugin) executeSettingsCommand(_ *plugin.Context, args *model.CommandAr
 	case commandArgShowPrejoinPage:
 		switch parameters[1] {
 		case valueTrue:
-			userConfig.ShowPrejoinPage = true
+			userConfig.ShowPrejoinPage = model.NewPointer(true)
 		case valueFalse:
-			userConfig.ShowPrejoinPage = false
+			userConfig.ShowPrejoinPage = model.NewPointer(false)
 		default:
 			text = p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
 				DefaultMessage: &i18n.Message{
@@ -325,11 +1425,47 @@ func (p *Plugin) executeSettingsCommand(_ *plugin.Context, args *model.CommandAr
 			})
 			userConfig = nil
 		}
+	case commandArgStartWithAudioMuted, commandArgStartWithVideoMuted, commandArgStartAudioOnly:
+		value, ok := parseOptionalBool(parameters[1])
+		if !ok {
+			text = p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "jitsi.command.settings.wrong_optional_bool_value",
+					Other: "Invalid `{{.Setting}}` value, use `true`, `false` or `default`.",
+				},
+				TemplateData: map[string]string{"Setting": parameters[0]},
+			})
+			userConfig = nil
+			break
+		}
+		switch parameters[0] {
+		case commandArgStartWithAudioMuted:
+			userConfig.StartWithAudioMuted = value
+		case commandArgStartWithVideoMuted:
+			userConfig.StartWithVideoMuted = value
+		case commandArgStartAudioOnly:
+			userConfig.StartAudioOnly = value
+		}
+	case commandArgInCallStatus:
+		switch parameters[1] {
+		case valueTrue:
+			userConfig.InCallStatus = true
+		case valueFalse:
+			userConfig.InCallStatus = false
+		default:
+			text = p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "jitsi.command.settings.wrong_in_call_status_value",
+					Other: "Invalid `in_call_status` value, use `true` or `false`.",
+				},
+			})
+			userConfig = nil
+		}
 	default:
 		text = p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
 			DefaultMessage: &i18n.Message{
 				ID:    "jitsi.command.settings.wrong_field",
-				Other: "Invalid config field, use `embedded`, `show_prejoin_page` or `naming_scheme`.",
+				Other: "Invalid config field, use `embedded`, `show_prejoin_page`, `naming_scheme`, `start_with_audio_muted`, `start_with_video_muted`, `start_audio_only` or `in_call_status`.",
 			},
 		})
 		userConfig = nil
@@ -365,3 +1501,247 @@ func (p *Plugin) executeSettingsCommand(_ *plugin.Context, args *model.CommandAr
 
 	return &model.CommandResponse{}, nil
 }
+
+func (p *Plugin) executeChannelSettingsCommand(_ *plugin.Context, args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
+	l := p.b.GetUserLocalizer(args.UserId)
+
+	channelConfig, err := p.getChannelConfig(args.ChannelId)
+	if err != nil {
+		mlog.Debug("Unable to get channel config", mlog.Err(err))
+		return p.settingsError(args.UserId, args.ChannelId, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.channel_settings.unable_to_get",
+				Other: "Unable to get channel settings",
+			},
+		}), args.RootId)
+	}
+
+	if len(parameters) == 0 || parameters[0] == jitsiSettingsSeeCommand {
+		formatString := func(value string) string {
+			if value == "" {
+				return valueDefault
+			}
+			return value
+		}
+		text := p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID: "jitsi.command.channel_settings.current_values",
+				Other: `###### Jitsi Channel Settings:
+* Naming Scheme: |{{.NamingScheme}}|
+* Room Name: |{{.RoomName}}|
+* Permanent Room: |{{.PermanentRoom}}|
+* Embedded: |{{.Embedded}}|
+* Show Pre-join Page: |{{.ShowPrejoinPage}}|
+* Lobby: |{{.Lobby}}|
+* Start With Audio Muted: |{{.StartWithAudioMuted}}|
+* Start With Video Muted: |{{.StartWithVideoMuted}}|
+* Who Can Start Meetings: |{{.WhoCanStart}}|`,
+			},
+			TemplateData: map[string]string{
+				"NamingScheme":        formatString(channelConfig.NamingScheme),
+				"RoomName":            formatString(channelConfig.RoomName),
+				"PermanentRoom":       formatOptionalBool(channelConfig.PermanentRoom),
+				"Embedded":            formatOptionalBool(channelConfig.Embedded),
+				"ShowPrejoinPage":     formatOptionalBool(channelConfig.ShowPrejoinPage),
+				"Lobby":               formatOptionalBool(channelConfig.Lobby),
+				"StartWithAudioMuted": formatOptionalBool(channelConfig.StartWithAudioMuted),
+				"StartWithVideoMuted": formatOptionalBool(channelConfig.StartWithVideoMuted),
+				"WhoCanStart":         formatString(channelConfig.WhoCanStart),
+			},
+		})
+		return p.postCommandResponse(args, strings.ReplaceAll(text, "|", "`"))
+	}
+
+	if len(parameters) != 2 {
+		return p.settingsError(args.UserId, args.ChannelId, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.settings.invalid_parameters",
+				Other: "Invalid settings parameters",
+			},
+		}), args.RootId)
+	}
+
+	if !p.API.HasPermissionToChannel(args.UserId, args.ChannelId, model.PermissionManageChannelRoles) {
+		return p.settingsError(args.UserId, args.ChannelId, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.channel_settings.forbidden",
+				Other: "Only the channel admins can change the settings of the channel.",
+			},
+		}), args.RootId)
+	}
+
+	text := ""
+	value := parameters[1]
+	switch parameters[0] {
+	case commandArgNamingScheme:
+		switch value {
+		case jitsiNameSchemeAsk, jitsiNameSchemeWords, jitsiNameSchemeUUID, jitsiNameSchemeMattermost:
+			channelConfig.NamingScheme = value
+		case valueDefault:
+			channelConfig.NamingScheme = ""
+		default:
+			text = p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "jitsi.command.channel_settings.wrong_naming_scheme_value",
+					Other: "Invalid `naming_scheme` value, use `ask`, `words`, `uuid`, `mattermost` or `default`.",
+				},
+			})
+			channelConfig = nil
+		}
+	case commandArgRoomName:
+		if value == valueDefault {
+			channelConfig.RoomName = ""
+			break
+		}
+		if value = encodeJitsiMeetingID(value); value == "" {
+			text = p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "jitsi.command.channel_settings.wrong_room_name_value",
+					Other: "Invalid `room_name` value, use letters, digits, `-` and `_`, or `default`.",
+				},
+			})
+			channelConfig = nil
+			break
+		}
+		channelConfig.RoomName = value
+	case commandArgPermanentRoom, commandArgEmbedded, commandArgShowPrejoinPage, commandArgLobby, commandArgStartWithAudioMuted, commandArgStartWithVideoMuted:
+		optionalValue, ok := parseOptionalBool(value)
+		if !ok {
+			text = p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "jitsi.command.settings.wrong_optional_bool_value",
+					Other: "Invalid `{{.Setting}}` value, use `true`, `false` or `default`.",
+				},
+				TemplateData: map[string]string{"Setting": parameters[0]},
+			})
+			channelConfig = nil
+			break
+		}
+		switch parameters[0] {
+		case commandArgPermanentRoom:
+			channelConfig.PermanentRoom = optionalValue
+		case commandArgEmbedded:
+			channelConfig.Embedded = optionalValue
+		case commandArgShowPrejoinPage:
+			channelConfig.ShowPrejoinPage = optionalValue
+		case commandArgLobby:
+			channelConfig.Lobby = optionalValue
+		case commandArgStartWithAudioMuted:
+			channelConfig.StartWithAudioMuted = optionalValue
+		case commandArgStartWithVideoMuted:
+			channelConfig.StartWithVideoMuted = optionalValue
+		}
+	case commandArgWhoCanStart:
+		switch value {
+		case whoCanStartEveryone:
+			channelConfig.WhoCanStart = ""
+		case whoCanStartAdmins:
+			channelConfig.WhoCanStart = value
+		default:
+			text = p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "jitsi.command.channel_settings.wrong_who_can_start_value",
+					Other: "Invalid `who_can_start` value, use `everyone` or `admins`.",
+				},
+			})
+			channelConfig = nil
+		}
+	default:
+		text = p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.channel_settings.wrong_field",
+				Other: "Invalid channel config field, use `naming_scheme`, `room_name`, `permanent_room`, `embedded`, `show_prejoin_page`, `lobby`, `start_with_audio_muted`, `start_with_video_muted` or `who_can_start`.",
+			},
+		})
+		channelConfig = nil
+	}
+
+	if channelConfig == nil {
+		return p.settingsError(args.UserId, args.ChannelId, text, args.RootId)
+	}
+
+	if err = p.setChannelConfig(args.ChannelId, channelConfig); err != nil {
+		mlog.Debug("Unable to set channel settings", mlog.Err(err))
+		return p.settingsError(args.UserId, args.ChannelId, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.channel_settings.unable_to_set",
+				Other: "Unable to set channel settings",
+			},
+		}), args.RootId)
+	}
+
+	return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "jitsi.command.channel_settings.updated",
+			Other: "Jitsi channel settings updated:\n\n* {{.Setting}}: `{{.Value}}`",
+		},
+		TemplateData: map[string]string{"Setting": parameters[0], "Value": value},
+	}))
+}
+
+func (p *Plugin) executeRoomCommand(_ *plugin.Context, args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
+	l := p.b.GetUserLocalizer(args.UserId)
+	errorText := p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "jitsi.command.room.error",
+			Other: "We could not get the room of this channel at this time.",
+		},
+	})
+
+	channelConfig, err := p.getChannelConfig(args.ChannelId)
+	if err != nil {
+		mlog.Error("Unable to get the channel config", mlog.Err(err))
+		return p.postCommandResponse(args, errorText)
+	}
+	if !p.usesPermanentRoom(channelConfig) {
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.room.disabled",
+				Other: "This channel has no permanent room. Channel admins can enable it with `/jitsi channel-settings permanent_room true`.",
+			},
+		}))
+	}
+
+	channel, appErr := p.API.GetChannel(args.ChannelId)
+	if appErr != nil {
+		mlog.Error("Unable to get the channel", mlog.Err(appErr))
+		return p.postCommandResponse(args, errorText)
+	}
+
+	if len(parameters) > 0 && parameters[0] == jitsiRoomResetCommand {
+		if !p.API.HasPermissionToChannel(args.UserId, args.ChannelId, model.PermissionManageChannelRoles) {
+			return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "jitsi.command.room.reset_forbidden",
+					Other: "Only the channel admins can reset the room of the channel.",
+				},
+			}))
+		}
+
+		room, err := p.resetChannelRoom(channel)
+		if err != nil {
+			mlog.Error("Unable to reset the channel room", mlog.String("channel_id", args.ChannelId), mlog.Err(err))
+			return p.postCommandResponse(args, errorText)
+		}
+		return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.command.room.reset",
+				Other: "The permanent room of this channel is now [{{.Room}}]({{.MeetingURL}}). The meetings running in the previous room have ended.",
+			},
+			TemplateData: map[string]string{"Room": room, "MeetingURL": p.getMeetingLink(room)},
+		}))
+	}
+
+	room, err := p.getChannelRoom(channel)
+	if err != nil {
+		mlog.Error("Unable to get the channel room", mlog.String("channel_id", args.ChannelId), mlog.Err(err))
+		return p.postCommandResponse(args, errorText)
+	}
+	return p.postCommandResponse(args, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "jitsi.command.room.current",
+			Other: "The permanent room of this channel is [{{.Room}}]({{.MeetingURL}}).",
+		},
+		TemplateData: map[string]string{"Room": room, "MeetingURL": p.getMeetingLink(room)},
+	}))
+}
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/plugin"
@@ -36,9 +37,24 @@ func TestCommandHelp(t *testing.T) {
 	helpText := strings.ReplaceAll(`###### Mattermost Jitsi Plugin - Slash Command help
 * |/jitsi| - Create a new meeting
 * |/jitsi start [topic]| - Create a new meeting with specified topic
+* |/jitsi start --lobby --password <password> --e2ee [topic]| - Create a new meeting restricting its access: |--lobby| makes participants knock once a moderator enabled the lobby of the meeting, |--password| sends a password to the channel members for a moderator to set in the meeting, and |--e2ee| enables end-to-end encryption. The Jitsi server does not enforce the lobby and the password until a moderator turns them on. Each option is optional
+* |/jitsi schedule "[topic]" [YYYY-MM-DD] [HH:MM] [duration]| - Schedule a meeting in the current channel, the meeting link is posted shortly before it starts
+* |/jitsi recurring add "[topic]" [rule] [HH:MM] [duration]| - Add a meeting taking place regularly in the same room, the rule is one of |daily|, |weekdays|, |weekly|, |weekly:MO,TH| or an RRULE such as |FREQ=WEEKLY;INTERVAL=2;BYDAY=MO|
+* |/jitsi recurring list| - List the recurring meetings of the current channel
+* |/jitsi recurring remove [id]| - Remove a recurring meeting
+* |/jitsi import [file-id]| - Schedule the meetings of the |.ics| file you last uploaded in the current channel. You can also mention @jitsi in the message of the uploaded file
+* |/jitsi calendar| - Get the URL of a calendar feed with the scheduled and recurring meetings of your channels, to subscribe to from your calendar application
+* |/jitsi calendar reset| - Replace the URL of your calendar feed, the previous one stops working
+* |/jitsi end [meeting-id]| - End a meeting, by default the latest running meeting of the current channel
+* |/jitsi invite @username... [meeting-id]| - Invite users to a meeting, by default the latest running meeting of the current channel, even if they are not members of the channel. Without users, see who accepted the invitations
+* |/jitsi history [n]| - List the last n meetings started in the current channel
+* |/jitsi room| - Show the permanent room of the current channel
+* |/jitsi room reset| - Replace the permanent room of the current channel, for channel admins. The meetings running in the previous room end
 * |/jitsi help| - Show this help text
 * |/jitsi settings see| - View your current user settings for the Jitsi plugin
 * |/jitsi settings [setting] [value]| - Update your user settings (see below for options)
+* |/jitsi channel-settings see| - View the meeting defaults of the current channel
+* |/jitsi channel-settings [setting] [value]| - Update the meeting defaults of the current channel, for channel admins (see below for options)
 
 ###### Jitsi Settings:
 * |/jitsi settings embedded [true/false]|: (Experimental) When true, Jitsi meeting is embedded as a floating window inside Mattermost. When false, Jitsi meeting opens in a new window.
@@ -47,7 +63,19 @@ func TestCommandHelp(t *testing.T) {
     * |words|: Random English words in title case (e.g. PlayfulDragonsObserveCuriously)
     * |uuid|: UUID (universally unique identifier)
     * |mattermost|: Mattermost specific names. Combination of team name, channel name and random text in public and private channels; personal meeting name in direct and group messages channels.
-    * |ask|: The plugin asks you to select the name every time you start a meeting`, "|", "`")
+    * |ask|: The plugin asks you to select the name every time you start a meeting
+* |/jitsi settings start_with_audio_muted [true/false/default]|: Join meetings with your microphone muted. |default| follows the system setting.
+* |/jitsi settings start_with_video_muted [true/false/default]|: Join meetings with your camera turned off. |default| follows the system setting.
+* |/jitsi settings start_audio_only [true/false/default]|: Join meetings in audio only mode. |default| follows the system setting.
+* |/jitsi settings in_call_status [true/false]|: When true, your custom status is set to "In a Jitsi meeting" while you are in a meeting, and restored when you leave.
+
+###### Jitsi Channel Settings:
+The settings of the channel apply unless you changed them in your own settings. |default| follows the system setting.
+* |/jitsi channel-settings naming_scheme [words/uuid/mattermost/ask/default]|: Select how meeting names are generated in the channel.
+* |/jitsi channel-settings room_name [room/default]|: Start every meeting without a topic in the same room.
+* |/jitsi channel-settings permanent_room [true/false/default]|: Start every meeting in the permanent room of the channel, created on first use.
+* |/jitsi channel-settings [embedded/show_prejoin_page/lobby/start_with_audio_muted/start_with_video_muted] [true/false/default]|: Set the defaults of the meetings of the channel.
+* |/jitsi channel-settings who_can_start [everyone/admins]|: Choose who can start meetings in the channel.`, "|", "`")
 
 	apiMock.On("SendEphemeralPost", "test-user", &model.Post{
 		UserId:    "test-bot-id",
@@ -80,7 +108,7 @@ func TestCommandSettings(t *testing.T) {
 			name:      "set valid setting with valid value",
 			command:   "/jitsi settings embedded true",
 			output:    "Jitsi settings updated:\n\n* embedded: `true`",
-			newConfig: &UserConfig{Embedded: true, NamingScheme: "mattermost", ShowPrejoinPage: true},
+			newConfig: &UserConfig{Embedded: model.NewPointer(true)},
 		},
 		{
 			name:      "set valid setting with invalid value (embedded)",
@@ -94,10 +122,40 @@ func TestCommandSettings(t *testing.T) {
 			output:    "Invalid `naming_scheme` value, use `ask`, `words`, `uuid` or `mattermost`.",
 			newConfig: nil,
 		},
+		{
+			name:      "set optional setting",
+			command:   "/jitsi settings start_with_audio_muted true",
+			output:    "Jitsi settings updated:\n\n* start_with_audio_muted: `true`",
+			newConfig: &UserConfig{StartWithAudioMuted: model.NewPointer(true)},
+		},
+		{
+			name:      "reset optional setting to default",
+			command:   "/jitsi settings start_audio_only default",
+			output:    "Jitsi settings updated:\n\n* start_audio_only: `default`",
+			newConfig: &UserConfig{},
+		},
+		{
+			name:      "set in-call status",
+			command:   "/jitsi settings in_call_status true",
+			output:    "Jitsi settings updated:\n\n* in_call_status: `true`",
+			newConfig: &UserConfig{InCallStatus: true},
+		},
+		{
+			name:      "set in-call status with invalid value",
+			command:   "/jitsi settings in_call_status default",
+			output:    "Invalid `in_call_status` value, use `true` or `false`.",
+			newConfig: nil,
+		},
+		{
+			name:      "set optional setting with invalid value",
+			command:   "/jitsi settings start_with_video_muted yes",
+			output:    "Invalid `start_with_video_muted` value, use `true`, `false` or `default`.",
+			newConfig: nil,
+		},
 		{
 			name:      "set invalid setting",
 			command:   "/jitsi settings other true",
-			output:    "Invalid config field, use `embedded`, `show_prejoin_page` or `naming_scheme`.",
+			output:    "Invalid config field, use `embedded`, `show_prejoin_page`, `naming_scheme`, `start_with_audio_muted`, `start_with_video_muted`, `start_audio_only` or `in_call_status`.",
 			newConfig: nil,
 		},
 		{
@@ -109,7 +167,7 @@ func TestCommandSettings(t *testing.T) {
 		{
 			name:      "get current user settings",
 			command:   "/jitsi settings see",
-			output:    "###### Jitsi Settings:\n* Embedded: `false`\n* Show Pre-join Page: `true`\n* Naming Scheme: `mattermost`",
+			output:    "###### Jitsi Settings:\n* Embedded: `false`\n* Show Pre-join Page: `true`\n* Naming Scheme: `mattermost`\n* Start With Audio Muted: `default`\n* Start With Video Muted: `default`\n* Start Audio Only: `default`\n* In-call Status: `false`",
 			newConfig: nil,
 		},
 	}
@@ -173,8 +231,10 @@ func TestCommandStartMeeting(t *testing.T) {
 		apiMock.On("GetUser", "test-user").Return(&model.User{Id: "test-user"}, nil)
 		apiMock.On("GetChannel", "test-channel").Return(&model.Channel{Id: "test-channel"}, nil)
 		apiMock.On("GetUser", "test-user").Return(&model.User{Id: "test-user"}, nil)
-		b, _ := json.Marshal(UserConfig{Embedded: false, NamingScheme: "ask"})
+		b, _ := json.Marshal(UserConfig{Embedded: model.NewPointer(false), NamingScheme: model.NewPointer("ask")})
 		apiMock.On("KVGet", "config_test-user", mock.Anything).Return(b, nil)
+		apiMock.On("KVGet", "channel_config_test-channel").Return(nil, nil)
+		apiMock.On("HasPermissionToChannel", "test-user", "test-channel", model.PermissionCreatePost).Return(true)
 
 		response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{UserId: "test-user", ChannelId: "test-channel", Command: "/jitsi"})
 		require.Equal(t, &model.CommandResponse{}, response)
@@ -195,11 +255,13 @@ func TestCommandStartMeeting(t *testing.T) {
 		require.Nil(t, err)
 		p.b = i18nBundle
 
+		mockMeetingStore(&apiMock)
 		apiMock.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
 			return strings.HasPrefix(post.Props["meeting_link"].(string), "http://test/")
 		})).Return(&model.Post{}, nil)
 		apiMock.On("GetUser", "test-user").Return(&model.User{Id: "test-user"}, nil)
 		apiMock.On("GetChannel", "test-channel").Return(&model.Channel{Id: "test-channel"}, nil)
+		apiMock.On("HasPermissionToChannel", "test-user", "test-channel", model.PermissionCreatePost).Return(true)
 		apiMock.On("GetUser", "test-user").Return(&model.User{Id: "test-user"}, nil)
 		apiMock.On("KVGet", "config_test-user", mock.Anything).Return(nil, nil)
 
@@ -222,11 +284,13 @@ func TestCommandStartMeeting(t *testing.T) {
 		require.Nil(t, err)
 		p.b = i18nBundle
 
+		mockMeetingStore(&apiMock)
 		apiMock.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
 			return strings.HasPrefix(post.Props["meeting_link"].(string), "http://test/topic")
 		})).Return(&model.Post{}, nil)
 		apiMock.On("GetUser", "test-user").Return(&model.User{Id: "test-user"}, nil)
 		apiMock.On("GetChannel", "test-channel").Return(&model.Channel{Id: "test-channel"}, nil)
+		apiMock.On("HasPermissionToChannel", "test-user", "test-channel", model.PermissionCreatePost).Return(true)
 		apiMock.On("GetUser", "test-user").Return(&model.User{Id: "test-user"}, nil)
 		apiMock.On("KVGet", "config_test-user", mock.Anything).Return(nil, nil)
 
@@ -234,4 +298,383 @@ func TestCommandStartMeeting(t *testing.T) {
 		require.Equal(t, &model.CommandResponse{}, response)
 		require.Nil(t, err)
 	})
+
+	t.Run("meeting with access options", func(t *testing.T) {
+		apiMock := plugintest.API{}
+		defer apiMock.AssertExpectations(t)
+		p.SetAPI(&apiMock)
+
+		apiMock.On("GetBundlePath").Return("..", nil)
+		config := model.Config{}
+		config.SetDefaults()
+		apiMock.On("GetConfig").Return(&config, nil)
+
+		i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+		require.Nil(t, err)
+		p.b = i18nBundle
+
+		store := mockMeetingStore(&apiMock)
+		// The password is never part of the meeting post.
+		apiMock.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			attachment := post.Props["attachments"].([]*model.SlackAttachment)[0]
+			b, _ := json.Marshal(post)
+			return post.Props["meeting_topic"] == "Team sync" && post.Props["meeting_lobby"] == true &&
+				post.Props["meeting_e2ee"] == nil && post.Props["meeting_password_protected"] == true &&
+				strings.Contains(attachment.Text, "&config.lobby.autoKnock=true") &&
+				!strings.Contains(string(b), "s3cret")
+		})).Return(&model.Post{Id: "test-post"}, nil)
+		apiMock.On("GetUser", "test-user").Return(&model.User{Id: "test-user"}, nil)
+		apiMock.On("GetChannel", "test-channel").Return(&model.Channel{Id: "test-channel"}, nil)
+		apiMock.On("HasPermissionToChannel", "test-user", "test-channel", model.PermissionCreatePost).Return(true)
+		// The options skip the meeting type question.
+		b, _ := json.Marshal(UserConfig{NamingScheme: model.NewPointer("ask")})
+		apiMock.On("KVGet", "config_test-user", mock.Anything).Return(b, nil)
+		apiMock.On("GetUser", "other-user").Return(&model.User{Id: "other-user"}, nil)
+		apiMock.On("GetChannelMembers", "test-channel", 0, channelMembersPerPage).Return(model.ChannelMembers{{UserId: "test-user"}, {UserId: "other-user"}}, nil)
+		for _, userID := range []string{"test-user", "other-user"} {
+			apiMock.On("SendEphemeralPost", userID, mock.MatchedBy(func(post *model.Post) bool {
+				return strings.Contains(post.Message, "`s3cret`")
+			})).Return(nil).Once()
+		}
+
+		response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{UserId: "test-user", ChannelId: "test-channel", Command: "/jitsi start --lobby Team --password s3cret sync"})
+		require.Equal(t, &model.CommandResponse{}, response)
+		require.Nil(t, err)
+		// The password is sent in the background.
+		p.passwordSends.Wait()
+
+		var stored []string
+		require.Nil(t, json.Unmarshal(store[channelMeetingsKeyPrefix+"test-channel"], &stored))
+		meeting, err := p.getMeeting(stored[0])
+		require.Nil(t, err)
+		require.True(t, meeting.Lobby)
+		require.Equal(t, "s3cret", meeting.Password)
+		require.False(t, meeting.E2EE)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		apiMock := plugintest.API{}
+		defer apiMock.AssertExpectations(t)
+		p.SetAPI(&apiMock)
+
+		apiMock.On("GetBundlePath").Return("..", nil)
+		i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+		require.Nil(t, err)
+		p.b = i18nBundle
+
+		apiMock.On("GetUser", "test-user").Return(&model.User{Id: "test-user"}, nil)
+		apiMock.On("GetChannel", "test-channel").Return(&model.Channel{Id: "test-channel"}, nil)
+		apiMock.On("KVGet", "config_test-user", mock.Anything).Return(nil, nil)
+		apiMock.On("KVGet", "channel_config_test-channel").Return(nil, nil)
+		apiMock.On("HasPermissionToChannel", "test-user", "test-channel", model.PermissionCreatePost).Return(true)
+		apiMock.On("SendEphemeralPost", "test-user", mock.MatchedBy(func(post *model.Post) bool {
+			return strings.HasPrefix(post.Message, "Invalid start parameters")
+		})).Return(nil)
+
+		response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{UserId: "test-user", ChannelId: "test-channel", Command: "/jitsi start --waiting-room topic"})
+		require.Equal(t, &model.CommandResponse{}, response)
+		require.Nil(t, err)
+	})
+}
+
+func TestParseStartArguments(t *testing.T) {
+	options, err := parseStartArguments("Sprint   review")
+	require.Nil(t, err)
+	require.Equal(t, startMeetingOptions{Topic: "Sprint review"}, options)
+
+	options, err = parseStartArguments("--e2ee --password=s3cret Sprint review --lobby")
+	require.Nil(t, err)
+	require.Equal(t, startMeetingOptions{Topic: "Sprint review", Lobby: true, Password: "s3cret", E2EE: true}, options)
+
+	for name, input := range map[string]string{
+		"missing password":   "Sprint review --password",
+		"empty password":     "--password= Sprint review",
+		"option as password": "--password --lobby Sprint review",
+		"unknown option":     "--waiting-room Sprint review",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseStartArguments(input)
+			require.NotNil(t, err)
+		})
+	}
+}
+
+func TestCommandEndMeeting(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL: "http://test",
+		},
+		botID: "test-bot-id",
+	}
+
+	setup := func(t *testing.T) *plugintest.API {
+		apiMock := &plugintest.API{}
+		p.SetAPI(apiMock)
+
+		apiMock.On("GetBundlePath").Return("..", nil)
+		apiMock.On("GetUser", "test-user").Return(&model.User{Id: "test-user", Locale: "en"}, nil)
+		config := model.Config{}
+		config.SetDefaults()
+		apiMock.On("GetConfig").Return(&config, nil).Maybe()
+		mockMeetingStore(apiMock)
+
+		i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+		require.Nil(t, err)
+		p.b = i18nBundle
+
+		return apiMock
+	}
+
+	expectMessage := func(apiMock *plugintest.API, message string) {
+		apiMock.On("SendEphemeralPost", "test-user", &model.Post{
+			UserId:    "test-bot-id",
+			ChannelId: "test-channel",
+			Message:   message,
+		}).Return(nil).Once()
+	}
+
+	t.Run("no running meeting in the channel", func(t *testing.T) {
+		apiMock := setup(t)
+		defer apiMock.AssertExpectations(t)
+		expectMessage(apiMock, "No running meeting found.")
+
+		response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{UserId: "test-user", ChannelId: "test-channel", Command: "/jitsi end"})
+		require.Equal(t, &model.CommandResponse{}, response)
+		require.Nil(t, err)
+	})
+
+	t.Run("meeting started by somebody else", func(t *testing.T) {
+		apiMock := setup(t)
+		defer apiMock.AssertExpectations(t)
+		require.Nil(t, p.createMeeting(&Meeting{Room: "test-room", ChannelID: "test-channel", CreatorID: "other-user"}))
+		apiMock.On("HasPermissionToChannel", "test-user", "test-channel", model.PermissionManageChannelRoles).Return(false)
+		expectMessage(apiMock, "Only the meeting creator or a channel admin can end this meeting.")
+
+		response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{UserId: "test-user", ChannelId: "test-channel", Command: "/jitsi end test-room"})
+		require.Equal(t, &model.CommandResponse{}, response)
+		require.Nil(t, err)
+	})
+
+	t.Run("latest meeting of the channel", func(t *testing.T) {
+		apiMock := setup(t)
+		defer apiMock.AssertExpectations(t)
+		require.Nil(t, p.createMeeting(&Meeting{Room: "test-room", ChannelID: "test-channel", CreatorID: "test-user"}))
+		expectMessage(apiMock, "Meeting `test-room` ended.")
+
+		response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{UserId: "test-user", ChannelId: "test-channel", Command: "/jitsi end"})
+		require.Equal(t, &model.CommandResponse{}, response)
+		require.Nil(t, err)
+
+		meeting, err2 := p.getMeetingByRoom("test-room")
+		require.Nil(t, err2)
+		require.Equal(t, MeetingStateEnded, meeting.State)
+	})
+}
+
+func TestCommandHistory(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL: "http://test",
+		},
+		botID: "test-bot-id",
+	}
+
+	setup := func(t *testing.T) *plugintest.API {
+		apiMock := &plugintest.API{}
+		p.SetAPI(apiMock)
+
+		apiMock.On("GetBundlePath").Return("..", nil)
+		apiMock.On("GetUser", "test-user").Return(&model.User{Id: "test-user", Username: "test-username", Locale: "en", Timezone: model.StringMap{"useAutomaticTimezone": "false", "manualTimezone": "UTC"}}, nil)
+		config := model.Config{}
+		config.SetDefaults()
+		config.ServiceSettings.SiteURL = model.NewPointer("http://mattermost")
+		apiMock.On("GetConfig").Return(&config, nil).Maybe()
+		mockMeetingStore(apiMock)
+
+		i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+		require.Nil(t, err)
+		p.b = i18nBundle
+
+		return apiMock
+	}
+
+	expectMessage := func(apiMock *plugintest.API, message string) {
+		apiMock.On("SendEphemeralPost", "test-user", &model.Post{
+			UserId:    "test-bot-id",
+			ChannelId: "test-channel",
+			Message:   message,
+		}).Return(nil).Once()
+	}
+
+	t.Run("invalid length", func(t *testing.T) {
+		apiMock := setup(t)
+		defer apiMock.AssertExpectations(t)
+		expectMessage(apiMock, "Invalid number of meetings, use a positive number.")
+
+		response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{UserId: "test-user", ChannelId: "test-channel", Command: "/jitsi history abc"})
+		require.Equal(t, &model.CommandResponse{}, response)
+		require.Nil(t, err)
+	})
+
+	t.Run("not a channel member", func(t *testing.T) {
+		apiMock := setup(t)
+		defer apiMock.AssertExpectations(t)
+		apiMock.On("GetChannelMember", "test-channel", "test-user").Return(nil, &model.AppError{})
+		expectMessage(apiMock, "You must be a member of this channel to see its meeting history.")
+
+		response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{UserId: "test-user", ChannelId: "test-channel", Command: "/jitsi history"})
+		require.Equal(t, &model.CommandResponse{}, response)
+		require.Nil(t, err)
+	})
+
+	t.Run("empty history", func(t *testing.T) {
+		apiMock := setup(t)
+		defer apiMock.AssertExpectations(t)
+		apiMock.On("GetChannelMember", "test-channel", "test-user").Return(&model.ChannelMember{}, nil)
+		expectMessage(apiMock, "No meetings have been started in this channel yet.")
+
+		response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{UserId: "test-user", ChannelId: "test-channel", Command: "/jitsi history"})
+		require.Equal(t, &model.CommandResponse{}, response)
+		require.Nil(t, err)
+	})
+
+	t.Run("latest meetings", func(t *testing.T) {
+		apiMock := setup(t)
+		defer apiMock.AssertExpectations(t)
+		apiMock.On("GetChannelMember", "test-channel", "test-user").Return(&model.ChannelMember{}, nil)
+		createAt := time.Date(2026, 10, 20, 9, 30, 0, 0, time.UTC).UnixMilli()
+		require.Nil(t, p.createMeeting(&Meeting{Room: "old-room", ChannelID: "test-channel", CreatorID: "test-user", Topic: "Old", PostID: "old-post", CreateAt: createAt}))
+		require.Nil(t, p.createMeeting(&Meeting{Room: "new-room", ChannelID: "test-channel", CreatorID: "test-user", Topic: "Standup", PostID: "new-post", CreateAt: createAt}))
+		expectMessage(apiMock, "###### Recent meetings in this channel\n"+
+			"* **Standup** - `new-room` started by @test-username on Tue Oct 20 09:30 UTC 2026 ([view post](http://mattermost/_redirect/pl/new-post))")
+
+		response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{UserId: "test-user", ChannelId: "test-channel", Command: "/jitsi history 1"})
+		require.Equal(t, &model.CommandResponse{}, response)
+		require.Nil(t, err)
+	})
+}
+
+func TestParseScheduleArguments(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Paris")
+	require.Nil(t, err)
+
+	t.Run("quoted topic with duration", func(t *testing.T) {
+		topic, startAt, duration, err := parseScheduleArguments(`"Sprint review" 2026-10-21 14:00 1h30m`, location)
+		require.Nil(t, err)
+		require.Equal(t, "Sprint review", topic)
+		require.Equal(t, time.Date(2026, 10, 21, 14, 0, 0, 0, location), startAt)
+		require.Equal(t, 90*time.Minute, duration)
+	})
+
+	t.Run("single word topic with duration in minutes", func(t *testing.T) {
+		topic, _, duration, err := parseScheduleArguments(`Standup 2026-10-21 09:00 15`, location)
+		require.Nil(t, err)
+		require.Equal(t, "Standup", topic)
+		require.Equal(t, 15*time.Minute, duration)
+	})
+
+	t.Run("default duration", func(t *testing.T) {
+		_, _, duration, err := parseScheduleArguments(`"Sprint review" 2026-10-21 14:00`, location)
+		require.Nil(t, err)
+		require.Equal(t, defaultScheduledMeetingDuration, duration)
+	})
+
+	for name, input := range map[string]string{
+		"unterminated topic": `"Sprint review 2026-10-21 14:00`,
+		"empty topic":        `"" 2026-10-21 14:00`,
+		"missing time":       `"Sprint review" 2026-10-21`,
+		"invalid date":       `"Sprint review" 21/10/2026 14:00`,
+		"invalid duration":   `"Sprint review" 2026-10-21 14:00 soon`,
+		"negative duration":  `"Sprint review" 2026-10-21 14:00 -5`,
+		"too many arguments": `"Sprint review" 2026-10-21 14:00 1h extra`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, _, err := parseScheduleArguments(input, location)
+			require.NotNil(t, err)
+		})
+	}
+}
+
+func TestParseRecurringArguments(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Paris")
+	require.Nil(t, err)
+	now := time.Date(2026, 10, 21, 22, 30, 0, 0, time.UTC)
+
+	t.Run("quoted topic with duration", func(t *testing.T) {
+		topic, rule, startAt, duration, err := parseRecurringArguments(`"Daily standup" weekdays 09:30 15`, location, now)
+		require.Nil(t, err)
+		require.Equal(t, "Daily standup", topic)
+		require.Equal(t, "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR", rule.String())
+		// It is already the next day in Paris.
+		require.Equal(t, time.Date(2026, 10, 22, 9, 30, 0, 0, location), startAt)
+		require.Equal(t, 15*time.Minute, duration)
+	})
+
+	t.Run("weekly rule uses the current day", func(t *testing.T) {
+		_, rule, _, duration, err := parseRecurringArguments(`Sync weekly 14:00`, location, now)
+		require.Nil(t, err)
+		require.Equal(t, "FREQ=WEEKLY;BYDAY=TH", rule.String())
+		require.Equal(t, defaultScheduledMeetingDuration, duration)
+	})
+
+	for name, input := range map[string]string{
+		"missing time":       `"Daily standup" weekdays`,
+		"invalid time":       `"Daily standup" weekdays 9h30`,
+		"invalid rule":       `"Daily standup" monthly 09:30`,
+		"invalid duration":   `"Daily standup" weekdays 09:30 soon`,
+		"too many arguments": `"Daily standup" weekdays 09:30 15 extra`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, _, _, err := parseRecurringArguments(input, location, now)
+			require.NotNil(t, err)
+		})
+	}
+}
+
+func TestCommandImport(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL: "http://test",
+		},
+		botID: "test-bot-id",
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("GetBundlePath").Return("..", nil)
+	apiMock.On("GetUser", "test-user").Return(&model.User{Id: "test-user", Locale: "en"}, nil)
+	apiMock.On("GetChannel", "test-channel").Return(&model.Channel{Id: "test-channel"}, nil)
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	t.Run("no recent calendar file", func(t *testing.T) {
+		apiMock.On("GetFileInfos", 0, 20, mock.MatchedBy(func(opt *model.GetFileInfosOptions) bool {
+			return opt.UserIds[0] == "test-user" && opt.ChannelIds[0] == "test-channel"
+		})).Return([]*model.FileInfo{{Id: "image", Extension: "png"}}, nil).Once()
+		apiMock.On("SendEphemeralPost", "test-user", &model.Post{
+			UserId:    "test-bot-id",
+			ChannelId: "test-channel",
+			Message:   "No calendar file found. Upload an `.ics` file in this channel, then run `/jitsi import` again.",
+		}).Return(nil).Once()
+
+		response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{UserId: "test-user", ChannelId: "test-channel", Command: "/jitsi import"})
+		require.Equal(t, &model.CommandResponse{}, response)
+		require.Nil(t, err)
+	})
+
+	t.Run("file of another channel", func(t *testing.T) {
+		apiMock.On("GetFileInfo", "other-file").Return(&model.FileInfo{Id: "other-file", CreatorId: "other-user", ChannelId: "other-channel", Extension: "ics"}, nil).Once()
+		apiMock.On("GetChannelMember", "other-channel", "test-user").Return(nil, &model.AppError{}).Once()
+		apiMock.On("SendEphemeralPost", "test-user", &model.Post{
+			UserId:    "test-bot-id",
+			ChannelId: "test-channel",
+			Message:   "No calendar file found. Upload an `.ics` file in this channel, then run `/jitsi import` again.",
+		}).Return(nil).Once()
+
+		response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{UserId: "test-user", ChannelId: "test-channel", Command: "/jitsi import other-file"})
+		require.Equal(t, &model.CommandResponse{}, response)
+		require.Nil(t, err)
+	})
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/pluginapi/experimental/bot/logger"
 	"github.com/mattermost/mattermost/server/public/pluginapi/experimental/telemetry"
+	"github.com/mattermost/mattermost/server/public/shared/mlog"
 	"github.com/pkg/errors"
 )
 
@@ -35,6 +36,47 @@ type configuration struct {
 	JitsiEmbedded          bool
 	JitsiCompatibilityMode bool
 	JitsiPrejoinPage       bool
+
+	JitsiPermanentChannelRooms bool
+
+	JitsiMeetingChannelTypes   string
+	JitsiAllowedTeams          string
+	JitsiAllowedChannels       string
+	JitsiRequiredRole          string
+	JitsiGuestMeetings         string
+	JitsiAllowReadOnlyChannels bool
+
+	JitsiRateLimitWindow  int
+	JitsiUserRateLimit    int
+	JitsiChannelRateLimit int
+
+	JitsiJoinRunningMeeting       string
+	JitsiJoinRunningMeetingWindow int
+
+	JitsiEventsSecret   string
+	JitsiMeetingSummary bool
+
+	JitsiRingDirectMessages bool
+	JitsiRingTimeout        int
+
+	JitsiJWTAlgorithm          string
+	JitsiJWTPrivateKey         string
+	JitsiJWTKeyRotationOverlap int
+	JitsiJWTTimeClaims         bool
+	JitsiJWTTenant             string
+	JitsiJWTRecording          string
+	JitsiJWTLivestreaming      string
+	JitsiJWTTranscription      string
+	JitsiJWTOutboundCall       string
+
+	JitsiScheduleReminderTime int
+
+	JitsiStartWithAudioMuted bool
+	JitsiStartWithVideoMuted bool
+	JitsiStartAudioOnly      bool
+	JitsiDisableDeepLinking  bool
+	JitsiDefaultLanguage     string
+	JitsiToolbarButtons      string
 }
 
 const publicJitsiServerURL = "https://meet.jit.si"
@@ -52,6 +94,49 @@ func (c *configuration) GetDefaultJitsiURL() string {
 	return publicJitsiServerURL
 }
 
+// GetJWTAlgorithm returns the algorithm tokens are signed with, HS256 unless an asymmetric one
+// is configured.
+func (c *configuration) GetJWTAlgorithm() string {
+	if c.JitsiJWTAlgorithm == "" {
+		return jwtAlgorithmHS256
+	}
+	return c.JitsiJWTAlgorithm
+}
+
+// GetJWTKeyRotationOverlap returns the number of minutes a replaced signing key remains published.
+func (c *configuration) GetJWTKeyRotationOverlap() int {
+	if c.JitsiJWTKeyRotationOverlap < 1 {
+		return defaultJWTKeyRotationOverlap
+	}
+	return c.JitsiJWTKeyRotationOverlap
+}
+
+// GetRateLimitWindow returns the number of seconds meetings are counted in by the rate limits.
+func (c *configuration) GetRateLimitWindow() int {
+	if c.JitsiRateLimitWindow < 1 {
+		return defaultRateLimitWindow
+	}
+	return c.JitsiRateLimitWindow
+}
+
+// GetRingTimeout returns the number of seconds the members of direct and group message channels
+// are called for, see JitsiRingDirectMessages.
+func (c *configuration) GetRingTimeout() int {
+	if c.JitsiRingTimeout < 1 {
+		return defaultRingTimeout
+	}
+	return c.JitsiRingTimeout
+}
+
+// GetJoinRunningMeetingWindow returns the number of minutes after its start during which a
+// meeting is joined instead of starting a new one, see JitsiJoinRunningMeeting.
+func (c *configuration) GetJoinRunningMeetingWindow() int {
+	if c.JitsiJoinRunningMeetingWindow < 1 {
+		return defaultJoinRunningMeetingWindow
+	}
+	return c.JitsiJoinRunningMeetingWindow
+}
+
 // Clone shallow copies the configuration. Your implementation may require a deep copy if
 // your configuration has reference types.
 func (c *configuration) Clone() *configuration {
@@ -72,14 +157,55 @@ func (c *configuration) IsValid() error {
 		if len(c.JitsiAppID) == 0 {
 			return fmt.Errorf("error no Jitsi app ID was provided to use with JWT")
 		}
-		if len(c.JitsiAppSecret) == 0 {
-			return fmt.Errorf("error no Jitsi app secret provided to use with JWT")
+		switch c.GetJWTAlgorithm() {
+		case jwtAlgorithmHS256:
+			if len(c.JitsiAppSecret) == 0 {
+				return fmt.Errorf("error no Jitsi app secret provided to use with JWT")
+			}
+		case jwtAlgorithmRS256, jwtAlgorithmES256:
+			if _, err := parseJWTPrivateKey(c.GetJWTAlgorithm(), c.JitsiJWTPrivateKey); err != nil {
+				return fmt.Errorf("error invalid private key to use with JWT: %v", err)
+			}
+		default:
+			return fmt.Errorf("error unsupported JWT algorithm %q", c.JitsiJWTAlgorithm)
+		}
+		for _, setting := range []string{c.JitsiJWTRecording, c.JitsiJWTLivestreaming, c.JitsiJWTTranscription, c.JitsiJWTOutboundCall} {
+			switch setting {
+			case jwtFeatureDefault, jwtFeatureEveryone, jwtFeatureModerators, jwtFeatureNobody:
+			default:
+				return fmt.Errorf("error invalid JWT feature setting %q", setting)
+			}
 		}
 		if c.JitsiLinkValidTime < 1 {
 			c.JitsiLinkValidTime = 30
 		}
 	}
 
+	switch c.JitsiMeetingChannelTypes {
+	case "", policyChannelTypesAll, policyChannelTypesDirect, policyChannelTypesPublic:
+	default:
+		return fmt.Errorf("error invalid channel types setting %q", c.JitsiMeetingChannelTypes)
+	}
+	switch c.JitsiRequiredRole {
+	case "", policyRoleChannelAdmin, policyRoleTeamAdmin, policyRoleSystemAdmin:
+	default:
+		return fmt.Errorf("error invalid required role %q", c.JitsiRequiredRole)
+	}
+	switch c.JitsiGuestMeetings {
+	case "", policyGuestsAllow, policyGuestsDirect, policyGuestsBlock:
+	default:
+		return fmt.Errorf("error invalid guest meetings setting %q", c.JitsiGuestMeetings)
+	}
+	switch c.JitsiJoinRunningMeeting {
+	case "", joinRunningMeetingChannel, joinRunningMeetingThread:
+	default:
+		return fmt.Errorf("error invalid join running meeting setting %q", c.JitsiJoinRunningMeeting)
+	}
+
+	if c.JitsiScheduleReminderTime < 0 {
+		c.JitsiScheduleReminderTime = 0
+	}
+
 	return nil
 }
 
@@ -138,11 +264,23 @@ func (p *Plugin) OnConfigurationChange() error {
 
 	p.setConfiguration(configuration)
 
+	if err := p.rotateJWTKeys(); err != nil {
+		mlog.Error("Error publishing the JWT signing key", mlog.Err(err))
+	}
+
+	if err := p.updateRingJob(); err != nil {
+		mlog.Error("Error updating the ring timeouts job", mlog.Err(err))
+	}
+
 	return nil
 }
 
 // OnDeactivate is invoked once the user disables the plugin
 func (p *Plugin) OnDeactivate() error {
+	p.stopScheduler()
+	p.stopRingJob()
+	p.passwordSends.Wait()
+
 	if p.telemetryClient != nil {
 		err := p.telemetryClient.Close()
 		if err != nil {
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/shared/mlog"
+	"github.com/pkg/errors"
+)
+
+const (
+	eventRoomCreated    = "muc-room-created"
+	eventOccupantJoined = "muc-occupant-joined"
+	eventOccupantLeft   = "muc-occupant-left"
+	eventRoomDestroyed  = "muc-room-destroyed"
+
+	// eventSignatureHeader carries the hex encoded HMAC-SHA256 of the event timestamp, a dot and
+	// the event body, prefixed with "sha256=". Servers which can only send static headers use a JWT
+	// bearer token instead.
+	eventSignatureHeader = "X-Jitsi-Signature"
+	// eventTimestampHeader carries the time the event was signed at, in seconds since the epoch,
+	// so that captured events cannot be replayed later on.
+	eventTimestampHeader = "X-Jitsi-Timestamp"
+	// maxEventClockSkew is how far from the time of the Mattermost server an event may have been
+	// signed at, or its token issued at.
+	maxEventClockSkew = 5 * time.Minute
+
+	maxEventSize = 1024 * 1024
+
+	// maxParticipantAvatars is the number of participants shown in the meeting post.
+	maxParticipantAvatars = 8
+)
+
+var errInvalidEventSignature = errors.New("invalid event signature")
+
+// jitsiEvent is an event of the Jitsi server, as sent by the event_sync Prosody module when a
+// room is created or destroyed and when a participant joins or leaves it. Times are in seconds.
+type jitsiEvent struct {
+	EventName    string                `json:"event_name"`
+	RoomName     string                `json:"room_name"`
+	RoomJID      string                `json:"room_jid"`
+	IsBreakout   bool                  `json:"is_breakout"`
+	Occupant     *jitsiEventOccupant   `json:"occupant,omitempty"`
+	AllOccupants []*jitsiEventOccupant `json:"all_occupants,omitempty"`
+	CreatedAt    int64                 `json:"created_at"`
+	DestroyedAt  int64                 `json:"destroyed_at,omitempty"`
+}
+
+// jitsiEventOccupant is a participant of a room. ID is the context.user.id claim of the JWT the
+// participant joined with, which is their Mattermost user ID.
+type jitsiEventOccupant struct {
+	OccupantJID string `json:"occupant_jid"`
+	Name        string `json:"name"`
+	Email       string `json:"email,omitempty"`
+	ID          string `json:"id,omitempty"`
+	JoinedAt    int64  `json:"joined_at"`
+	LeftAt      int64  `json:"left_at,omitempty"`
+}
+
+// MeetingParticipant is a participant of a meeting, reported by the events of the Jitsi server.
+// UserID is empty for guests joining without a token of the plugin.
+type MeetingParticipant struct {
+	OccupantID string `json:"occupant_id"`
+	UserID     string `json:"user_id,omitempty"`
+	Name       string `json:"name,omitempty"`
+	JoinedAt   int64  `json:"joined_at"`
+	LeftAt     int64  `json:"left_at,omitempty"`
+}
+
+// PresentParticipants returns the participants currently in the meeting.
+func (m *Meeting) PresentParticipants() []*MeetingParticipant {
+	var present []*MeetingParticipant
+	for _, participant := range m.Participants {
+		if participant.LeftAt == 0 {
+			present = append(present, participant)
+		}
+	}
+	return present
+}
+
+// IsPresent reports whether a user is in the meeting, possibly from several devices.
+func (m *Meeting) IsPresent(userID string) bool {
+	for _, participant := range m.PresentParticipants() {
+		if participant.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyEventSignature checks that an event was recently sent by the Jitsi server, either through
+// the HMAC of its timestamp and body or through an expiring JWT signed with the events secret.
+func verifyEventSignature(secret string, body []byte, signature string, timestamp string, authorization string) error {
+	now := time.Now()
+	if signature != "" {
+		seconds, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return errInvalidEventSignature
+		}
+		if signedAt := time.Unix(seconds, 0); signedAt.Before(now.Add(-maxEventClockSkew)) || signedAt.After(now.Add(maxEventClockSkew)) {
+			return errInvalidEventSignature
+		}
+
+		expected := hmac.New(sha256.New, []byte(secret))
+		expected.Write([]byte(timestamp + "."))
+		expected.Write(body)
+		decoded, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+		if err != nil || !hmac.Equal(decoded, expected.Sum(nil)) {
+			return errInvalidEventSignature
+		}
+		return nil
+	}
+
+	token := strings.TrimPrefix(authorization, "Bearer ")
+	if token == "" || token == authorization {
+		return errInvalidEventSignature
+	}
+	claims, err := verifyJwt(secret, token)
+	if err != nil {
+		return errInvalidEventSignature
+	}
+	// Tokens without an expiry could be replayed forever.
+	if claims.ExpiresAt == nil || claims.IssuedAt == nil || claims.ExpiresAt.Before(now) || claims.IssuedAt.After(now.Add(maxEventClockSkew)) {
+		return errInvalidEventSignature
+	}
+	return nil
+}
+
+// setOccupantCallStatus sets the custom status of a Mattermost user who joined a meeting.
+func (p *Plugin) setOccupantCallStatus(userID string, meeting *Meeting) {
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		mlog.Debug("Unable to get the user who joined the meeting", mlog.String("user_id", userID), mlog.Err(appErr))
+		return
+	}
+	if err := p.setInCallStatus(user, meeting); err != nil {
+		mlog.Warn("Unable to set the in-call custom status", mlog.String("user_id", userID), mlog.Err(err))
+	}
+}
+
+func eventTime(seconds int64) int64 {
+	if seconds == 0 {
+		return model.GetMillis()
+	}
+	return seconds * 1000
+}
+
+// handleJitsiEvent records the participants of the meeting of an event and updates the meeting
+// post. Events of rooms which were not started from Mattermost are ignored.
+func (p *Plugin) handleJitsiEvent(event *jitsiEvent) error {
+	// Breakout rooms are part of the meeting of their main room.
+	if event.IsBreakout {
+		return nil
+	}
+
+	meeting, err := p.getMeetingByRoom(event.RoomName)
+	if errors.Is(err, errMeetingNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if meeting.State == MeetingStateEnded || meeting.State == MeetingStateScheduled {
+		return nil
+	}
+
+	switch event.EventName {
+	case eventOccupantJoined:
+		if event.Occupant == nil {
+			return nil
+		}
+		meeting, err = p.updateMeeting(meeting.ID, func(meeting *Meeting) error {
+			for _, participant := range meeting.PresentParticipants() {
+				if participant.OccupantID == event.Occupant.OccupantJID {
+					return nil
+				}
+			}
+			meeting.Participants = append(meeting.Participants, &MeetingParticipant{
+				OccupantID: event.Occupant.OccupantJID,
+				UserID:     event.Occupant.ID,
+				Name:       event.Occupant.Name,
+				JoinedAt:   eventTime(event.Occupant.JoinedAt),
+			})
+			return nil
+		})
+		if err == nil && event.Occupant.ID != "" {
+			p.setOccupantCallStatus(event.Occupant.ID, meeting)
+		}
+	case eventOccupantLeft:
+		if event.Occupant == nil {
+			return nil
+		}
+		meeting, err = p.updateMeeting(meeting.ID, func(meeting *Meeting) error {
+			for _, participant := range meeting.PresentParticipants() {
+				if participant.OccupantID == event.Occupant.OccupantJID {
+					participant.LeftAt = eventTime(event.Occupant.LeftAt)
+				}
+			}
+			return nil
+		})
+		// Users joining from several devices are in the meeting until they left from all of them.
+		if err == nil && event.Occupant.ID != "" && !meeting.IsPresent(event.Occupant.ID) {
+			if clearErr := p.clearInCallStatus(event.Occupant.ID, meeting.ID); clearErr != nil {
+				mlog.Warn("Unable to restore the custom status", mlog.String("user_id", event.Occupant.ID), mlog.Err(clearErr))
+			}
+		}
+	case eventRoomDestroyed:
+		_, err = p.updateMeeting(meeting.ID, func(meeting *Meeting) error {
+			destroyedAt := eventTime(event.DestroyedAt)
+			for _, participant := range meeting.PresentParticipants() {
+				participant.LeftAt = destroyedAt
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if _, err = p.endMeeting(meeting.ID); errors.Is(err, errMeetingAlreadyEnded) {
+			return nil
+		}
+		return err
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return p.refreshMeetingPost(meeting)
+}
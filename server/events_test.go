@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cristalhq/jwt/v2"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func signEvent(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func eventToken(t *testing.T, secret string) string {
+	now := time.Now()
+	token, err := signClaims(secret, &Claims{StandardClaims: jwt.StandardClaims{
+		Issuer:    "prosody",
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+	}})
+	require.Nil(t, err)
+	return token
+}
+
+func TestVerifyEventSignature(t *testing.T) {
+	body := []byte(`{"event_name": "muc-room-created"}`)
+
+	t.Run("HMAC", func(t *testing.T) {
+		now := strconv.FormatInt(time.Now().Unix(), 10)
+		require.Nil(t, verifyEventSignature("secret", body, signEvent("secret", now, body), now, ""))
+		require.ErrorIs(t, verifyEventSignature("secret", body, signEvent("other", now, body), now, ""), errInvalidEventSignature)
+		require.ErrorIs(t, verifyEventSignature("secret", body, "sha256=zz", now, ""), errInvalidEventSignature)
+		require.ErrorIs(t, verifyEventSignature("secret", body, signEvent("secret", "", body), "", ""), errInvalidEventSignature)
+
+		// The timestamp is signed along with the body.
+		later := strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10)
+		require.ErrorIs(t, verifyEventSignature("secret", body, signEvent("secret", now, body), later, ""), errInvalidEventSignature)
+
+		// Events signed long ago are replayed.
+		old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+		require.ErrorIs(t, verifyEventSignature("secret", body, signEvent("secret", old, body), old, ""), errInvalidEventSignature)
+	})
+
+	t.Run("JWT", func(t *testing.T) {
+		token := eventToken(t, "secret")
+		require.Nil(t, verifyEventSignature("secret", body, "", "", "Bearer "+token))
+		require.ErrorIs(t, verifyEventSignature("other", body, "", "", "Bearer "+token), errInvalidEventSignature)
+		require.ErrorIs(t, verifyEventSignature("secret", body, "", "", token), errInvalidEventSignature)
+
+		expired, err := signClaims("secret", &Claims{StandardClaims: jwt.StandardClaims{
+			IssuedAt:  jwt.NewNumericDate(time.Now().Add(-time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		}})
+		require.Nil(t, err)
+		require.ErrorIs(t, verifyEventSignature("secret", body, "", "", "Bearer "+expired), errInvalidEventSignature)
+
+		// Tokens must expire, and say when they were issued.
+		unbounded, err := signClaims("secret", &Claims{StandardClaims: jwt.StandardClaims{Issuer: "prosody", IssuedAt: jwt.NewNumericDate(time.Now())}})
+		require.Nil(t, err)
+		require.ErrorIs(t, verifyEventSignature("secret", body, "", "", "Bearer "+unbounded), errInvalidEventSignature)
+		undated, err := signClaims("secret", &Claims{StandardClaims: jwt.StandardClaims{Issuer: "prosody", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}})
+		require.Nil(t, err)
+		require.ErrorIs(t, verifyEventSignature("secret", body, "", "", "Bearer "+undated), errInvalidEventSignature)
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		require.ErrorIs(t, verifyEventSignature("secret", body, "", "", ""), errInvalidEventSignature)
+	})
+}
+
+func TestHandleEvents(t *testing.T) {
+	p := Plugin{configuration: &configuration{JitsiURL: "http://test", JitsiEventsSecret: "events-secret"}}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("GetBundlePath").Return("..", nil)
+	config := model.Config{}
+	config.SetDefaults()
+	apiMock.On("GetConfig").Return(&config, nil)
+	mockMeetingStore(&apiMock)
+	store := mockKVStore(&apiMock, "config_", callStatusKeyPrefix)
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	meeting := &Meeting{Room: "TeamSync-AbCdEf", ChannelID: "test-channel", CreatorID: "creator", Topic: "Team sync", PostID: "meeting-post"}
+	require.Nil(t, p.createMeeting(meeting))
+
+	post := &model.Post{Id: "meeting-post", Type: "custom_jitsi", Props: model.StringInterface{"meeting_id": meeting.Room}}
+	apiMock.On("GetPost", "meeting-post").Return(func(string) *model.Post { return post.Clone() }, nil)
+	apiMock.On("UpdatePost", mock.Anything).Return(func(updated *model.Post) *model.Post {
+		post = updated
+		return updated
+	}, nil)
+
+	// The member opted in for the in-call custom status.
+	store["config_member"] = []byte(`{"in_call_status": true}`)
+	previousStatus := &model.CustomStatus{Emoji: "palm_tree", Text: "On vacation"}
+	member := &model.User{Id: "member", Locale: "en"}
+	require.Nil(t, member.SetCustomStatus(previousStatus))
+	apiMock.On("GetUser", "member").Return(func(string) *model.User { return member.DeepCopy() }, nil)
+	apiMock.On("UpdateUserCustomStatus", "member", mock.Anything).Return(func(_ string, status *model.CustomStatus) *model.AppError {
+		require.Nil(t, member.SetCustomStatus(status))
+		return nil
+	})
+
+	serve := func(t *testing.T, file string, headers map[string]string) *httptest.ResponseRecorder {
+		body, err := os.ReadFile(filepath.Join("testdata", "events", file))
+		require.Nil(t, err)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/events", bytes.NewReader(body))
+		for name, value := range headers {
+			r.Header.Set(name, value)
+		}
+		if headers == nil {
+			timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+			r.Header.Set(eventTimestampHeader, timestamp)
+			r.Header.Set(eventSignatureHeader, signEvent("events-secret", timestamp, body))
+		}
+		p.ServeHTTP(&plugin.Context{}, w, r)
+		return w
+	}
+
+	t.Run("unsigned event", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, serve(t, "room_created.json", map[string]string{}).Code)
+		require.Equal(t, http.StatusUnauthorized, serve(t, "room_created.json", map[string]string{eventSignatureHeader: "sha256=00"}).Code)
+	})
+
+	t.Run("room created", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, serve(t, "room_created.json", nil).Code)
+		require.Nil(t, post.GetProp("meeting_participants_count"))
+	})
+
+	t.Run("participants join", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, serve(t, "occupant_joined_member.json", nil).Code)
+		require.Equal(t, 1, post.GetProp("meeting_participants_count"))
+		require.Equal(t, []string{"member"}, post.GetProp("meeting_participant_ids"))
+		require.Contains(t, post.Attachments()[0].Text, "1 person in the call")
+		require.Equal(t, "In a Jitsi meeting", member.GetCustomStatus().Text)
+
+		// Servers sending static headers authenticate with a JWT.
+		token := eventToken(t, "events-secret")
+		require.Equal(t, http.StatusOK, serve(t, "occupant_joined_guest.json", map[string]string{"Authorization": "Bearer " + token}).Code)
+		require.Equal(t, 2, post.GetProp("meeting_participants_count"))
+		require.Equal(t, []string{"member"}, post.GetProp("meeting_participant_ids"))
+		require.Contains(t, post.Attachments()[0].Text, "2 people in the call")
+
+		// Repeated events are ignored.
+		require.Equal(t, http.StatusOK, serve(t, "occupant_joined_member.json", nil).Code)
+		require.Equal(t, 2, post.GetProp("meeting_participants_count"))
+	})
+
+	t.Run("participant leaves", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, serve(t, "occupant_left_member.json", nil).Code)
+		require.Equal(t, 1, post.GetProp("meeting_participants_count"))
+		require.Empty(t, post.GetProp("meeting_participant_ids"))
+		require.Equal(t, previousStatus.Text, member.GetCustomStatus().Text)
+		require.NotContains(t, store, callStatusKeyPrefix+"member")
+
+		stored, err := p.getMeeting(meeting.ID)
+		require.Nil(t, err)
+		require.Len(t, stored.Participants, 2)
+		require.Equal(t, &MeetingParticipant{
+			OccupantID: "3a6f1c2e-8d4b-4c0a-9f7e-2b1d5e6a7c8f@meet.example.com/3Yq8xTzR",
+			UserID:     "member",
+			Name:       "Alice Doe",
+			JoinedAt:   1792252810000,
+			LeftAt:     1792254600000,
+		}, stored.Participants[0])
+	})
+
+	t.Run("room destroyed", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, serve(t, "room_destroyed.json", nil).Code)
+		require.NotNil(t, post.GetProp("meeting_ended_at"))
+		require.True(t, strings.Contains(post.Attachments()[0].Text, "Meeting ended at"))
+
+		stored, err := p.getMeeting(meeting.ID)
+		require.Nil(t, err)
+		require.Equal(t, MeetingStateEnded, stored.State)
+		require.Empty(t, stored.PresentParticipants())
+		require.Equal(t, int64(1792256400000), stored.Participants[1].LeftAt)
+
+		// Late events of the ended meeting are ignored.
+		require.Equal(t, http.StatusOK, serve(t, "occupant_joined_guest.json", nil).Code)
+		stored, err = p.getMeeting(meeting.ID)
+		require.Nil(t, err)
+		require.Empty(t, stored.PresentParticipants())
+	})
+
+	t.Run("events disabled", func(t *testing.T) {
+		p.configuration.JitsiEventsSecret = ""
+		require.Equal(t, http.StatusNotFound, serve(t, "room_created.json", nil).Code)
+	})
+}
@@ -0,0 +1,602 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	icsDateTimeLayout    = "20060102T150405"
+	icsUTCDateTimeLayout = "20060102T150405Z"
+	icsMaxLineLength     = 75
+	icsProductID         = "-//Mattermost//Mattermost Jitsi Plugin//EN"
+	icsStatusConfirmed   = "CONFIRMED"
+	icsStatusCancelled   = "CANCELLED"
+	icsContentType       = "text/calendar; charset=utf-8"
+)
+
+var (
+	errICSAllDayEvent     = errors.New("all-day events are not supported")
+	errICSMissingStart    = errors.New("missing start time")
+	errICSEndBeforeStart  = errors.New("the event ends before it starts")
+	errICSUnknownTimezone = errors.New("unknown time zone")
+)
+
+// icsEvent is a VEVENT of an iCalendar document.
+type icsEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	URL         string
+	Start       time.Time
+	End         time.Time
+	// RRule is the recurrence rule of a recurring event, without the RRULE: prefix.
+	RRule string
+	// ExDates are the occurrences of a recurring event which are cancelled.
+	ExDates []time.Time
+	Status  string
+	Created time.Time
+}
+
+// icsCalendar renders events as an iCalendar (RFC 5545) document.
+type icsCalendar struct {
+	Name   string
+	Events []icsEvent
+}
+
+func (c *icsCalendar) String() string {
+	var b strings.Builder
+	writeICSLine(&b, "BEGIN:VCALENDAR")
+	writeICSLine(&b, "VERSION:2.0")
+	writeICSLine(&b, "PRODID:"+icsProductID)
+	writeICSLine(&b, "CALSCALE:GREGORIAN")
+	writeICSLine(&b, "METHOD:PUBLISH")
+	if c.Name != "" {
+		writeICSLine(&b, "X-WR-CALNAME:"+escapeICSText(c.Name))
+	}
+
+	// Every TZID needs a VTIMEZONE, starting before the first event using it.
+	firstStarts := map[string]time.Time{}
+	for _, event := range c.Events {
+		if event.RRule == "" || !isICSLocalTime(event.Start) {
+			continue
+		}
+		name := event.Start.Location().String()
+		if first, ok := firstStarts[name]; !ok || event.Start.Before(first) {
+			firstStarts[name] = event.Start
+		}
+	}
+	names := make([]string, 0, len(firstStarts))
+	for name := range firstStarts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		writeICSTimezone(&b, firstStarts[name])
+	}
+
+	stamp := time.Now().UTC().Format(icsUTCDateTimeLayout)
+	for _, event := range c.Events {
+		writeICSLine(&b, "BEGIN:VEVENT")
+		writeICSLine(&b, "UID:"+event.UID)
+		writeICSLine(&b, "DTSTAMP:"+stamp)
+		if !event.Created.IsZero() {
+			writeICSLine(&b, "CREATED:"+event.Created.UTC().Format(icsUTCDateTimeLayout))
+		}
+		writeICSLine(&b, "DTSTART"+formatICSDateTime(event.Start, event.RRule != ""))
+		writeICSLine(&b, "DTEND"+formatICSDateTime(event.End, event.RRule != ""))
+		if event.RRule != "" {
+			writeICSLine(&b, "RRULE:"+event.RRule)
+			for _, exDate := range event.ExDates {
+				writeICSLine(&b, "EXDATE"+formatICSDateTime(exDate.In(event.Start.Location()), true))
+			}
+		}
+		writeICSLine(&b, "SUMMARY:"+escapeICSText(event.Summary))
+		if event.Description != "" {
+			writeICSLine(&b, "DESCRIPTION:"+escapeICSText(event.Description))
+		}
+		if event.URL != "" {
+			writeICSLine(&b, "LOCATION:"+escapeICSText(event.URL))
+			writeICSLine(&b, "URL:"+event.URL)
+		}
+		if event.Status != "" {
+			writeICSLine(&b, "STATUS:"+event.Status)
+		}
+		writeICSLine(&b, "END:VEVENT")
+	}
+
+	writeICSLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+// formatICSDateTime formats the value of a DTSTART or DTEND property, including the colon. Times
+// are in UTC, except for recurring events which keep their local time zone so that occurrences
+// are not shifted by daylight saving time changes.
+func formatICSDateTime(t time.Time, local bool) string {
+	if local && isICSLocalTime(t) {
+		return ";TZID=" + t.Location().String() + ":" + t.Format(icsDateTimeLayout)
+	}
+	return ":" + t.UTC().Format(icsUTCDateTimeLayout)
+}
+
+// isICSLocalTime tells whether a time is in a named location, which can be written with a TZID.
+func isICSLocalTime(t time.Time) bool {
+	return t.Location() != time.UTC && t.Location().String() != "Local"
+}
+
+var icsWeekdays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// icsObservance is a STANDARD or DAYLIGHT sub-component of a VTIMEZONE.
+type icsObservance struct {
+	Daylight   bool
+	Onset      time.Time
+	OffsetFrom int
+	OffsetTo   int
+	Name       string
+}
+
+func newICSObservance(location *time.Location, transition time.Time) icsObservance {
+	after := transition.In(location)
+	name, offsetTo := after.Zone()
+	_, offsetFrom := transition.Add(-time.Second).In(location).Zone()
+	return icsObservance{
+		Daylight:   after.IsDST(),
+		Onset:      transition.In(time.FixedZone("", offsetFrom)),
+		OffsetFrom: offsetFrom,
+		OffsetTo:   offsetTo,
+		Name:       name,
+	}
+}
+
+// yearlyRule returns the BYMONTH and BYDAY parts of a yearly rule repeating the onset on the
+// same n-th, or last, weekday of its month.
+func (o icsObservance) yearlyRule() string {
+	day := o.Onset.Day()
+	n := (day-1)/7 + 1
+	if day+7 > time.Date(o.Onset.Year(), o.Onset.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day() {
+		n = -1
+	}
+	return fmt.Sprintf("BYMONTH=%d;BYDAY=%d%s", o.Onset.Month(), n, icsWeekdays[o.Onset.Weekday()])
+}
+
+// icsTransitions returns the UTC offset changes of a location during a year.
+func icsTransitions(location *time.Location, year int) []time.Time {
+	var transitions []time.Time
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		_, offset := day.In(location).Zone()
+		next := day.AddDate(0, 0, 1)
+		if _, nextOffset := next.In(location).Zone(); nextOffset == offset {
+			continue
+		}
+		low, high := day.Unix(), next.Unix()
+		for high-low > 1 {
+			middle := (low + high) / 2
+			if _, middleOffset := time.Unix(middle, 0).In(location).Zone(); middleOffset == offset {
+				low = middle
+			} else {
+				high = middle
+			}
+		}
+		transitions = append(transitions, time.Unix(high, 0))
+	}
+	return transitions
+}
+
+// writeICSTimezone writes the VTIMEZONE of the location of from. The transitions of the year
+// before from repeat every year when they follow the same rule the next year, as daylight saving
+// time does in most zones. Other transitions are written as they are.
+func writeICSTimezone(b *strings.Builder, from time.Time) {
+	location := from.Location()
+	writeICSLine(b, "BEGIN:VTIMEZONE")
+	writeICSLine(b, "TZID:"+location.String())
+
+	previous := icsTransitions(location, from.Year()-1)
+	current := icsTransitions(location, from.Year())
+	if len(previous) == 0 && len(current) == 0 {
+		name, offset := from.Zone()
+		writeICSObservance(b, icsObservance{Onset: time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC), OffsetFrom: offset, OffsetTo: offset, Name: name}, "")
+	}
+
+	repeated := map[int]bool{}
+	for _, transition := range previous {
+		observance := newICSObservance(location, transition)
+		rule := ""
+		for i, next := range current {
+			nextObservance := newICSObservance(location, next)
+			if nextObservance.yearlyRule() == observance.yearlyRule() && nextObservance.OffsetTo == observance.OffsetTo &&
+				nextObservance.Onset.Format("150405") == observance.Onset.Format("150405") {
+				rule = "FREQ=YEARLY;" + observance.yearlyRule()
+				repeated[i] = true
+				break
+			}
+		}
+		writeICSObservance(b, observance, rule)
+	}
+	for i, transition := range current {
+		if !repeated[i] {
+			writeICSObservance(b, newICSObservance(location, transition), "")
+		}
+	}
+
+	writeICSLine(b, "END:VTIMEZONE")
+}
+
+func writeICSObservance(b *strings.Builder, observance icsObservance, rule string) {
+	component := "STANDARD"
+	if observance.Daylight {
+		component = "DAYLIGHT"
+	}
+	writeICSLine(b, "BEGIN:"+component)
+	writeICSLine(b, "DTSTART:"+observance.Onset.Format(icsDateTimeLayout))
+	writeICSLine(b, "TZOFFSETFROM:"+formatICSUTCOffset(observance.OffsetFrom))
+	writeICSLine(b, "TZOFFSETTO:"+formatICSUTCOffset(observance.OffsetTo))
+	if rule != "" {
+		writeICSLine(b, "RRULE:"+rule)
+	}
+	if observance.Name != "" {
+		writeICSLine(b, "TZNAME:"+escapeICSText(observance.Name))
+	}
+	writeICSLine(b, "END:"+component)
+}
+
+// formatICSUTCOffset formats a UTC offset in seconds as +HHMM, or +HHMMSS.
+func formatICSUTCOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	offset := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds/60%60)
+	if seconds%60 != 0 {
+		offset += fmt.Sprintf("%02d", seconds%60)
+	}
+	return offset
+}
+
+// escapeICSText escapes a TEXT property value.
+func escapeICSText(value string) string {
+	return strings.NewReplacer(
+		"\\", "\\\\",
+		";", "\\;",
+		",", "\\,",
+		"\r\n", "\\n",
+		"\n", "\\n",
+		"\r", "",
+	).Replace(value)
+}
+
+// writeICSLine writes a content line, folding it at 75 octets without splitting UTF-8 characters.
+func writeICSLine(b *strings.Builder, line string) {
+	limit := icsMaxLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isUTF8Start(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts in the line length.
+		limit = icsMaxLineLength - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func isUTF8Start(c byte) bool {
+	return c&0xC0 != 0x80
+}
+
+// icsProperty is a content line of an iCalendar document.
+type icsProperty struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// icsComponent is a BEGIN/END block of an iCalendar document, such as VEVENT or VTIMEZONE.
+type icsComponent struct {
+	Name       string
+	Properties []icsProperty
+	Components []*icsComponent
+}
+
+// Property returns the first property with the given name, or nil.
+func (c *icsComponent) Property(name string) *icsProperty {
+	for i := range c.Properties {
+		if c.Properties[i].Name == name {
+			return &c.Properties[i]
+		}
+	}
+	return nil
+}
+
+// Find returns the sub-components with the given name, searched recursively.
+func (c *icsComponent) Find(name string) []*icsComponent {
+	var found []*icsComponent
+	for _, component := range c.Components {
+		if component.Name == name {
+			found = append(found, component)
+		}
+		found = append(found, component.Find(name)...)
+	}
+	return found
+}
+
+// parseICS parses an iCalendar document into its components.
+func parseICS(data string) (*icsComponent, error) {
+	// Unfold the content lines.
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\n ", "")
+	data = strings.ReplaceAll(data, "\n\t", "")
+
+	root := &icsComponent{}
+	stack := []*icsComponent{root}
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+
+		property, err := parseICSProperty(line)
+		if err != nil {
+			return nil, err
+		}
+
+		current := stack[len(stack)-1]
+		switch property.Name {
+		case "BEGIN":
+			component := &icsComponent{Name: strings.ToUpper(property.Value)}
+			current.Components = append(current.Components, component)
+			stack = append(stack, component)
+		case "END":
+			if len(stack) == 1 || current.Name != strings.ToUpper(property.Value) {
+				return nil, errors.Errorf("unexpected END:%s", property.Value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			current.Properties = append(current.Properties, property)
+		}
+	}
+
+	if len(stack) != 1 {
+		return nil, errors.New("unterminated calendar component")
+	}
+	calendars := root.Find("VCALENDAR")
+	if len(calendars) == 0 {
+		return nil, errors.New("missing VCALENDAR")
+	}
+	return calendars[0], nil
+}
+
+func parseICSProperty(line string) (icsProperty, error) {
+	// The value starts at the first colon which is not inside a quoted parameter value.
+	quoted := false
+	separator := -1
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		}
+		if c == ':' && !quoted {
+			separator = i
+			break
+		}
+	}
+	if separator < 0 {
+		return icsProperty{}, errors.Errorf("invalid content line %q", line)
+	}
+
+	parts := strings.Split(line[:separator], ";")
+	property := icsProperty{
+		Name:   strings.ToUpper(parts[0]),
+		Params: map[string]string{},
+		Value:  line[separator+1:],
+	}
+	for _, param := range parts[1:] {
+		key, value, _ := strings.Cut(param, "=")
+		property.Params[strings.ToUpper(key)] = strings.Trim(value, "\"")
+	}
+	return property, nil
+}
+
+// unescapeICSText reverses escapeICSText.
+func unescapeICSText(value string) string {
+	return strings.NewReplacer(
+		"\\\\", "\\",
+		"\\;", ";",
+		"\\,", ",",
+		"\\n", "\n",
+		"\\N", "\n",
+	).Replace(value)
+}
+
+// windowsTimezones maps the Windows time zone names used by Outlook and Exchange invites to IANA
+// locations, for the most common zones.
+var windowsTimezones = map[string]string{
+	"Dateline Standard Time":          "Etc/GMT+12",
+	"Hawaiian Standard Time":          "Pacific/Honolulu",
+	"Alaskan Standard Time":           "America/Anchorage",
+	"Pacific Standard Time":           "America/Los_Angeles",
+	"Mountain Standard Time":          "America/Denver",
+	"US Mountain Standard Time":       "America/Phoenix",
+	"Central Standard Time":           "America/Chicago",
+	"Eastern Standard Time":           "America/New_York",
+	"Atlantic Standard Time":          "America/Halifax",
+	"E. South America Standard Time":  "America/Sao_Paulo",
+	"UTC":                             "UTC",
+	"GMT Standard Time":               "Europe/London",
+	"Greenwich Standard Time":         "Atlantic/Reykjavik",
+	"W. Europe Standard Time":         "Europe/Berlin",
+	"Romance Standard Time":           "Europe/Paris",
+	"Central Europe Standard Time":    "Europe/Budapest",
+	"Central European Standard Time":  "Europe/Warsaw",
+	"E. Europe Standard Time":         "Europe/Chisinau",
+	"FLE Standard Time":               "Europe/Kiev",
+	"GTB Standard Time":               "Europe/Bucharest",
+	"Russian Standard Time":           "Europe/Moscow",
+	"Israel Standard Time":            "Asia/Jerusalem",
+	"Arabian Standard Time":           "Asia/Dubai",
+	"India Standard Time":             "Asia/Kolkata",
+	"China Standard Time":             "Asia/Shanghai",
+	"Singapore Standard Time":         "Asia/Singapore",
+	"Tokyo Standard Time":             "Asia/Tokyo",
+	"Korea Standard Time":             "Asia/Seoul",
+	"AUS Eastern Standard Time":       "Australia/Sydney",
+	"New Zealand Standard Time":       "Pacific/Auckland",
+	"South Africa Standard Time":      "Africa/Johannesburg",
+	"Egypt Standard Time":             "Africa/Cairo",
+	"W. Central Africa Standard Time": "Africa/Lagos",
+}
+
+var icsUTCOffsetPattern = regexp.MustCompile(`^([+-])(\d{2})(\d{2})(\d{2})?$`)
+
+// icsLocation returns the location of a TZID. IANA names are used as is, known Windows names are
+// mapped to their IANA equivalent, and other names fall back to the standard offset of their
+// VTIMEZONE definition, ignoring daylight saving time.
+func icsLocation(calendar *icsComponent, tzid string) (*time.Location, error) {
+	if location, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+		return location, nil
+	}
+	if name, ok := windowsTimezones[tzid]; ok {
+		if location, err := time.LoadLocation(name); err == nil {
+			return location, nil
+		}
+	}
+
+	for _, timezone := range calendar.Find("VTIMEZONE") {
+		if id := timezone.Property("TZID"); id == nil || id.Value != tzid {
+			continue
+		}
+		for _, standard := range timezone.Find("STANDARD") {
+			offset := standard.Property("TZOFFSETTO")
+			if offset == nil {
+				continue
+			}
+			match := icsUTCOffsetPattern.FindStringSubmatch(offset.Value)
+			if match == nil {
+				continue
+			}
+			hours, _ := strconv.Atoi(match[2])
+			minutes, _ := strconv.Atoi(match[3])
+			seconds := hours*3600 + minutes*60
+			if match[1] == "-" {
+				seconds = -seconds
+			}
+			return time.FixedZone(tzid, seconds), nil
+		}
+	}
+
+	return nil, errors.Wrap(errICSUnknownTimezone, tzid)
+}
+
+// parseICSDateTime parses the value of a DTSTART or DTEND property. Floating times, without a
+// time zone, are interpreted in defaultLocation.
+func parseICSDateTime(calendar *icsComponent, property *icsProperty, defaultLocation *time.Location) (time.Time, error) {
+	if property.Params["VALUE"] == "DATE" || len(property.Value) == len("20060102") {
+		return time.Time{}, errICSAllDayEvent
+	}
+
+	if strings.HasSuffix(property.Value, "Z") {
+		return time.Parse(icsUTCDateTimeLayout, property.Value)
+	}
+
+	location := defaultLocation
+	if tzid, ok := property.Params["TZID"]; ok {
+		var err error
+		location, err = icsLocation(calendar, tzid)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.ParseInLocation(icsDateTimeLayout, property.Value, location)
+}
+
+var icsDurationPattern = regexp.MustCompile(`^\+?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseICSDuration parses the value of a DURATION property such as PT1H30M.
+func parseICSDuration(value string) (time.Duration, error) {
+	match := icsDurationPattern.FindStringSubmatch(value)
+	if match == nil || value == "P" || value == "PT" {
+		return 0, errors.Errorf("invalid duration %q", value)
+	}
+
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var duration time.Duration
+	for i, unit := range units {
+		if match[i+1] == "" {
+			continue
+		}
+		n, _ := strconv.Atoi(match[i+1])
+		duration += time.Duration(n) * unit
+	}
+	return duration, nil
+}
+
+// parseICSEvent reads a VEVENT component of calendar. The duration defaults to
+// defaultScheduledMeetingDuration when the event has neither DTEND nor DURATION.
+func parseICSEvent(calendar *icsComponent, component *icsComponent, defaultLocation *time.Location) (icsEvent, error) {
+	event := icsEvent{Status: icsStatusConfirmed}
+	if uid := component.Property("UID"); uid != nil {
+		event.UID = uid.Value
+	}
+	if summary := component.Property("SUMMARY"); summary != nil {
+		event.Summary = strings.TrimSpace(unescapeICSText(summary.Value))
+	}
+	if status := component.Property("STATUS"); status != nil {
+		event.Status = strings.ToUpper(status.Value)
+	}
+	if rrule := component.Property("RRULE"); rrule != nil {
+		event.RRule = rrule.Value
+	}
+
+	dtstart := component.Property("DTSTART")
+	if dtstart == nil {
+		return event, errICSMissingStart
+	}
+	var err error
+	event.Start, err = parseICSDateTime(calendar, dtstart, defaultLocation)
+	if err != nil {
+		return event, err
+	}
+
+	event.End = event.Start.Add(defaultScheduledMeetingDuration)
+	if dtend := component.Property("DTEND"); dtend != nil {
+		event.End, err = parseICSDateTime(calendar, dtend, event.Start.Location())
+		if err != nil {
+			return event, err
+		}
+	} else if duration := component.Property("DURATION"); duration != nil {
+		d, err := parseICSDuration(duration.Value)
+		if err != nil {
+			return event, err
+		}
+		event.End = event.Start.Add(d)
+	}
+	if !event.End.After(event.Start) {
+		return event, errICSEndBeforeStart
+	}
+
+	for _, property := range component.Properties {
+		if property.Name != "EXDATE" {
+			continue
+		}
+		for _, value := range strings.Split(property.Value, ",") {
+			property.Value = value
+			exDate, err := parseICSDateTime(calendar, &property, event.Start.Location())
+			if err != nil {
+				return event, err
+			}
+			event.ExDates = append(event.ExDates, exDate)
+		}
+	}
+
+	return event, nil
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscapeICSText(t *testing.T) {
+	require.Equal(t, `Sprint review\, planning\; retro \\o/\nsecond line`, escapeICSText("Sprint review, planning; retro \\o/\r\nsecond line"))
+}
+
+func TestWriteICSLine(t *testing.T) {
+	t.Run("short line", func(t *testing.T) {
+		var b strings.Builder
+		writeICSLine(&b, "SUMMARY:Standup")
+		require.Equal(t, "SUMMARY:Standup\r\n", b.String())
+	})
+
+	t.Run("long line is folded", func(t *testing.T) {
+		var b strings.Builder
+		line := "DESCRIPTION:" + strings.Repeat("a", 200)
+		writeICSLine(&b, line)
+
+		lines := strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n")
+		require.Len(t, lines, 3)
+		require.Len(t, lines[0], 75)
+		for _, l := range lines[1:] {
+			require.True(t, strings.HasPrefix(l, " "))
+			require.LessOrEqual(t, len(l), 75)
+		}
+		require.Equal(t, line, strings.ReplaceAll(b.String()[:len(b.String())-2], "\r\n ", ""))
+	})
+
+	t.Run("multi-byte characters are not split", func(t *testing.T) {
+		var b strings.Builder
+		writeICSLine(&b, "SUMMARY:"+strings.Repeat("é", 60))
+		for _, l := range strings.Split(b.String(), "\r\n ") {
+			require.True(t, strings.HasPrefix(l, "SUMMARY:") || strings.HasPrefix(l, "é"))
+		}
+	})
+}
+
+func TestICSCalendar(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Paris")
+	require.Nil(t, err)
+
+	calendar := icsCalendar{
+		Name: "Jitsi",
+		Events: []icsEvent{{
+			UID:     "single@test",
+			Summary: "Sprint review",
+			URL:     "http://test/room",
+			Start:   time.Date(2026, 10, 21, 14, 0, 0, 0, location),
+			End:     time.Date(2026, 10, 21, 15, 0, 0, 0, location),
+			Status:  icsStatusConfirmed,
+		}, {
+			UID:     "recurring@test",
+			Summary: "Daily standup",
+			Start:   time.Date(2026, 10, 21, 9, 30, 0, 0, location),
+			End:     time.Date(2026, 10, 21, 9, 45, 0, 0, location),
+			RRule:   "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+			ExDates: []time.Time{time.Date(2026, 10, 22, 7, 30, 0, 0, time.UTC)},
+		}},
+	}
+	output := calendar.String()
+
+	require.True(t, strings.HasPrefix(output, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	require.True(t, strings.HasSuffix(output, "END:VCALENDAR\r\n"))
+	require.Contains(t, output, "X-WR-CALNAME:Jitsi\r\n")
+	require.Equal(t, 2, strings.Count(output, "BEGIN:VEVENT\r\n"))
+
+	// Single events are in UTC.
+	require.Contains(t, output, "UID:single@test\r\n")
+	require.Contains(t, output, "DTSTART:20261021T120000Z\r\n")
+	require.Contains(t, output, "DTEND:20261021T130000Z\r\n")
+	require.Contains(t, output, "LOCATION:http://test/room\r\n")
+	require.Contains(t, output, "URL:http://test/room\r\n")
+	require.Contains(t, output, "STATUS:CONFIRMED\r\n")
+
+	// Recurring events keep their time zone.
+	require.Contains(t, output, "DTSTART;TZID=Europe/Paris:20261021T093000\r\n")
+	require.Contains(t, output, "DTEND;TZID=Europe/Paris:20261021T094500\r\n")
+	require.Contains(t, output, "RRULE:FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR\r\n")
+	require.Contains(t, output, "EXDATE;TZID=Europe/Paris:20261022T093000\r\n")
+
+	// Their time zone is defined in the calendar.
+	parsed, err := parseICS(output)
+	require.Nil(t, err)
+	timezones := parsed.Find("VTIMEZONE")
+	require.Len(t, timezones, 1)
+	require.Equal(t, "Europe/Paris", timezones[0].Property("TZID").Value)
+
+	daylight := timezones[0].Find("DAYLIGHT")
+	require.Len(t, daylight, 1)
+	require.Equal(t, "20250330T020000", daylight[0].Property("DTSTART").Value)
+	require.Equal(t, "+0100", daylight[0].Property("TZOFFSETFROM").Value)
+	require.Equal(t, "+0200", daylight[0].Property("TZOFFSETTO").Value)
+	require.Equal(t, "FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU", daylight[0].Property("RRULE").Value)
+	require.Equal(t, "CEST", daylight[0].Property("TZNAME").Value)
+
+	standard := timezones[0].Find("STANDARD")
+	require.Len(t, standard, 1)
+	require.Equal(t, "20251026T030000", standard[0].Property("DTSTART").Value)
+	require.Equal(t, "+0200", standard[0].Property("TZOFFSETFROM").Value)
+	require.Equal(t, "+0100", standard[0].Property("TZOFFSETTO").Value)
+	require.Equal(t, "FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU", standard[0].Property("RRULE").Value)
+
+	event, err := parseICSEvent(parsed, parsed.Find("VEVENT")[1], time.UTC)
+	require.Nil(t, err)
+	require.True(t, event.Start.Equal(calendar.Events[1].Start))
+	require.Len(t, event.ExDates, 1)
+	require.True(t, event.ExDates[0].Equal(calendar.Events[1].ExDates[0]))
+
+	t.Run("time zone without daylight saving time", func(t *testing.T) {
+		location, err := time.LoadLocation("Asia/Tokyo")
+		require.Nil(t, err)
+		calendar := icsCalendar{Events: []icsEvent{{
+			UID:   "recurring@test",
+			Start: time.Date(2026, 10, 21, 9, 30, 0, 0, location),
+			End:   time.Date(2026, 10, 21, 9, 45, 0, 0, location),
+			RRule: "FREQ=DAILY",
+		}}}
+		parsed, err := parseICS(calendar.String())
+		require.Nil(t, err)
+		timezones := parsed.Find("VTIMEZONE")
+		require.Len(t, timezones, 1)
+		require.Empty(t, timezones[0].Find("DAYLIGHT"))
+		standard := timezones[0].Find("STANDARD")
+		require.Len(t, standard, 1)
+		require.Equal(t, "+0900", standard[0].Property("TZOFFSETTO").Value)
+		require.Nil(t, standard[0].Property("RRULE"))
+	})
+}
+
+func TestParseICS(t *testing.T) {
+	calendar, err := parseICS("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:event@test\r\nSUMMARY:A very long summary that is folded\r\n  on two lines\\, with a comma\r\nDTSTART;TZID=\"Europe/Paris\":20261021T140000\r\nBEGIN:VALARM\r\nTRIGGER:-PT15M\r\nEND:VALARM\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n")
+	require.Nil(t, err)
+
+	events := calendar.Find("VEVENT")
+	require.Len(t, events, 1)
+	require.Len(t, calendar.Find("VALARM"), 1)
+	require.Equal(t, "event@test", events[0].Property("UID").Value)
+	require.Equal(t, "A very long summary that is folded on two lines, with a comma", unescapeICSText(events[0].Property("SUMMARY").Value))
+	require.Equal(t, "Europe/Paris", events[0].Property("DTSTART").Params["TZID"])
+	require.Nil(t, events[0].Property("DTEND"))
+
+	for name, input := range map[string]string{
+		"not a calendar":    "hello",
+		"missing VCALENDAR": "BEGIN:VEVENT\nEND:VEVENT\n",
+		"unterminated":      "BEGIN:VCALENDAR\nBEGIN:VEVENT\nEND:VCALENDAR\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseICS(input)
+			require.NotNil(t, err)
+		})
+	}
+}
+
+func TestParseICSEvent(t *testing.T) {
+	parse := func(t *testing.T, properties string) (icsEvent, error) {
+		calendar, err := parseICS("BEGIN:VCALENDAR\n" +
+			"BEGIN:VTIMEZONE\nTZID:Custom Zone\nBEGIN:STANDARD\nTZOFFSETFROM:+0300\nTZOFFSETTO:+0200\nEND:STANDARD\nBEGIN:DAYLIGHT\nTZOFFSETFROM:+0200\nTZOFFSETTO:+0300\nEND:DAYLIGHT\nEND:VTIMEZONE\n" +
+			"BEGIN:VEVENT\n" + properties + "END:VEVENT\nEND:VCALENDAR\n")
+		require.Nil(t, err)
+		return parseICSEvent(calendar, calendar.Find("VEVENT")[0], time.UTC)
+	}
+
+	t.Run("UTC times", func(t *testing.T) {
+		event, err := parse(t, "SUMMARY:Review\nDTSTART:20261021T120000Z\nDTEND:20261021T130000Z\n")
+		require.Nil(t, err)
+		require.Equal(t, "Review", event.Summary)
+		require.Equal(t, time.Date(2026, 10, 21, 12, 0, 0, 0, time.UTC), event.Start)
+		require.Equal(t, time.Hour, event.End.Sub(event.Start))
+	})
+
+	t.Run("Windows time zone and duration", func(t *testing.T) {
+		event, err := parse(t, "DTSTART;TZID=Romance Standard Time:20261021T140000\nDURATION:PT1H30M\n")
+		require.Nil(t, err)
+		require.Equal(t, "Europe/Paris", event.Start.Location().String())
+		require.Equal(t, 14, event.Start.Hour())
+		require.Equal(t, 90*time.Minute, event.End.Sub(event.Start))
+	})
+
+	t.Run("time zone from the VTIMEZONE definition", func(t *testing.T) {
+		event, err := parse(t, "DTSTART;TZID=Custom Zone:20261021T140000\n")
+		require.Nil(t, err)
+		_, offset := event.Start.Zone()
+		require.Equal(t, 2*3600, offset)
+		require.Equal(t, defaultScheduledMeetingDuration, event.End.Sub(event.Start))
+	})
+
+	t.Run("recurring and cancelled", func(t *testing.T) {
+		event, err := parse(t, "DTSTART:20261021T120000Z\nRRULE:FREQ=WEEKLY;BYDAY=WE\nSTATUS:CANCELLED\n")
+		require.Nil(t, err)
+		require.Equal(t, "FREQ=WEEKLY;BYDAY=WE", event.RRule)
+		require.Equal(t, icsStatusCancelled, event.Status)
+	})
+
+	t.Run("excluded occurrences", func(t *testing.T) {
+		event, err := parse(t, "DTSTART;TZID=Europe/Paris:20261021T140000\nRRULE:FREQ=DAILY\nEXDATE;TZID=Europe/Paris:20261022T140000,20261023T140000\nEXDATE:20261025T130000Z\n")
+		require.Nil(t, err)
+		require.Len(t, event.ExDates, 3)
+		require.Equal(t, time.Date(2026, 10, 22, 12, 0, 0, 0, time.UTC).UnixMilli(), event.ExDates[0].UnixMilli())
+		require.Equal(t, time.Date(2026, 10, 23, 12, 0, 0, 0, time.UTC).UnixMilli(), event.ExDates[1].UnixMilli())
+		require.Equal(t, time.Date(2026, 10, 25, 13, 0, 0, 0, time.UTC).UnixMilli(), event.ExDates[2].UnixMilli())
+	})
+
+	for name, properties := range map[string]string{
+		"missing start":     "SUMMARY:Review\n",
+		"all-day event":     "DTSTART;VALUE=DATE:20261021\n",
+		"unknown time zone": "DTSTART;TZID=Nowhere:20261021T140000\n",
+		"invalid duration":  "DTSTART:20261021T120000Z\nDURATION:soon\n",
+		"ends before start": "DTSTART:20261021T120000Z\nDTEND:20261021T110000Z\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := parse(t, properties)
+			require.NotNil(t, err)
+		})
+	}
+}
+
+func TestParseICSDuration(t *testing.T) {
+	for value, expected := range map[string]time.Duration{
+		"PT15M":   15 * time.Minute,
+		"PT1H30M": 90 * time.Minute,
+		"P1D":     24 * time.Hour,
+		"P1W":     7 * 24 * time.Hour,
+		"PT45S":   45 * time.Second,
+	} {
+		duration, err := parseICSDuration(value)
+		require.Nil(t, err)
+		require.Equal(t, expected, duration, value)
+	}
+
+	for _, value := range []string{"", "P", "PT", "1H", "PT1X"} {
+		_, err := parseICSDuration(value)
+		require.NotNil(t, err, value)
+	}
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/shared/mlog"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/pkg/errors"
+)
+
+const (
+	importedEventKeyPrefix = "ics_import_"
+
+	maxImportFileSize  = 1024 * 1024
+	maxImportedEvents  = 50
+	importFileLookback = 24 * time.Hour
+)
+
+var (
+	errNoCalendarFile        = errors.New("no calendar file found")
+	errCalendarFileTooBig    = errors.New("the calendar file is too big")
+	errEventCancelled        = errors.New("the event is cancelled")
+	errEventAlreadyDone      = errors.New("every occurrence of the event is in the past")
+	errEventAlreadyImported  = errors.New("the event was already imported in this channel")
+	errModifiedOccurrence    = errors.New("modified occurrences of recurring events are not supported")
+	errUnsupportedRecurrence = errors.New("unsupported recurrence")
+	errTooManyImportEvents   = errors.New("too many events in the calendar file")
+)
+
+var botMentionPattern = regexp.MustCompile(`(?i)(^|[^\w.-])@` + jitsiBotUsername + `\b`)
+
+// importResult lists what an iCalendar import created and skipped.
+type importResult struct {
+	Scheduled []*Meeting
+	Recurring []*RecurringMeeting
+	Skipped   []importSkippedEvent
+}
+
+type importSkippedEvent struct {
+	Summary string
+	Err     error
+}
+
+func isCalendarFile(info *model.FileInfo) bool {
+	return strings.EqualFold(info.Extension, "ics") || strings.HasPrefix(info.MimeType, "text/calendar")
+}
+
+func importedEventKey(channelID string, uid string) string {
+	hash := sha256.Sum256([]byte(channelID + "/" + uid))
+	return importedEventKeyPrefix + hex.EncodeToString(hash[:16])
+}
+
+// findCalendarFile returns the calendar file to import: the given file when fileID is set,
+// provided the user can access it, or else the latest calendar file the user uploaded in the
+// channel.
+func (p *Plugin) findCalendarFile(userID string, channelID string, fileID string) (*model.FileInfo, error) {
+	if fileID != "" {
+		info, appErr := p.API.GetFileInfo(fileID)
+		if appErr != nil || !isCalendarFile(info) {
+			return nil, errNoCalendarFile
+		}
+		if info.CreatorId != userID {
+			if _, appErr = p.API.GetChannelMember(info.ChannelId, userID); info.ChannelId == "" || appErr != nil {
+				return nil, errNoCalendarFile
+			}
+		}
+		return info, nil
+	}
+
+	infos, appErr := p.API.GetFileInfos(0, 20, &model.GetFileInfosOptions{
+		UserIds:        []string{userID},
+		ChannelIds:     []string{channelID},
+		Since:          time.Now().Add(-importFileLookback).UnixMilli(),
+		SortBy:         model.FileinfoSortByCreated,
+		SortDescending: true,
+	})
+	if appErr != nil {
+		return nil, appErr
+	}
+	for _, info := range infos {
+		if isCalendarFile(info) {
+			return info, nil
+		}
+	}
+	return nil, errNoCalendarFile
+}
+
+// importCalendarFile creates the meetings of a calendar file in the channel.
+func (p *Plugin) importCalendarFile(user *model.User, channel *model.Channel, info *model.FileInfo, rootID string) (*importResult, error) {
+	if err := p.checkChannelStartPolicy(user, channel); err != nil {
+		return nil, err
+	}
+
+	if info.Size > maxImportFileSize {
+		return nil, errCalendarFileTooBig
+	}
+
+	data, appErr := p.API.GetFile(info.Id)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if len(data) > maxImportFileSize {
+		return nil, errCalendarFileTooBig
+	}
+
+	return p.importCalendar(user, channel, string(data), rootID)
+}
+
+// importCalendar creates a scheduled meeting for every upcoming event of an iCalendar document,
+// and a recurring meeting for every recurring event. Floating times are read in the user time
+// zone. Events which cannot be imported are reported as skipped.
+func (p *Plugin) importCalendar(user *model.User, channel *model.Channel, data string, rootID string) (*importResult, error) {
+	calendar, err := parseICS(data)
+	if err != nil {
+		return nil, err
+	}
+
+	components := calendar.Find("VEVENT")
+	if len(components) > maxImportedEvents {
+		return nil, errTooManyImportEvents
+	}
+
+	defaultTopic := p.b.LocalizeDefaultMessage(p.b.GetServerLocalizer(), &i18n.Message{
+		ID:    "jitsi.start_meeting.default_meeting_topic",
+		Other: "Jitsi Meeting",
+	})
+
+	result := &importResult{}
+	for _, component := range components {
+		event, err := parseICSEvent(calendar, component, user.GetTimezoneLocation())
+		if event.Summary == "" {
+			event.Summary = defaultTopic
+		}
+		if err == nil {
+			err = p.importEvent(user, channel, component, event, rootID, result)
+		}
+		if err != nil {
+			mlog.Debug("Skipping a calendar event", mlog.String("uid", event.UID), mlog.Err(err))
+			result.Skipped = append(result.Skipped, importSkippedEvent{Summary: event.Summary, Err: err})
+		}
+	}
+
+	return result, nil
+}
+
+func (p *Plugin) importEvent(user *model.User, channel *model.Channel, component *icsComponent, event icsEvent, rootID string, result *importResult) error {
+	if event.Status == icsStatusCancelled {
+		return errEventCancelled
+	}
+	if component.Property("RECURRENCE-ID") != nil {
+		return errModifiedOccurrence
+	}
+
+	key := ""
+	if event.UID != "" {
+		key = importedEventKey(channel.Id, event.UID)
+		data, appErr := p.API.KVGet(key)
+		if appErr != nil {
+			return appErr
+		}
+		if data != nil {
+			return errEventAlreadyImported
+		}
+	}
+
+	start := event.Start
+	if _, err := time.LoadLocation(start.Location().String()); err != nil {
+		// Time zones only known from the VTIMEZONE definition cannot be stored.
+		start = start.UTC()
+	}
+	duration := event.End.Sub(event.Start)
+
+	var id string
+	if event.RRule != "" {
+		rule, err := parseRecurrenceRule(event.RRule, start)
+		if err != nil {
+			return errors.Wrap(errUnsupportedRecurrence, err.Error())
+		}
+		if rule.Count > 0 {
+			// The recurring meeting only counts the occurrences from its first upcoming one.
+			now := time.Now()
+			past := 0
+			for occurrence := rule.Next(start, start.Add(-time.Second)); !occurrence.IsZero() && occurrence.Before(now) && past < rule.Count; occurrence = rule.Next(start, occurrence) {
+				past++
+			}
+			if past >= rule.Count {
+				return errEventAlreadyDone
+			}
+			rule.Count -= past
+		}
+
+		recurring, err := p.addRecurringMeeting(user, channel, event.Summary, rule, start, duration, event.ExDates)
+		if errors.Is(err, errNoOccurrence) {
+			return errEventAlreadyDone
+		}
+		if err != nil {
+			return err
+		}
+		result.Recurring = append(result.Recurring, recurring)
+		id = recurring.ID
+	} else {
+		meeting, err := p.scheduleMeeting(user, channel, event.Summary, start, duration, rootID)
+		if errors.Is(err, errStartInThePast) {
+			return errEventAlreadyDone
+		}
+		if err != nil {
+			return err
+		}
+		result.Scheduled = append(result.Scheduled, meeting)
+		id = meeting.ID
+	}
+
+	if key != "" {
+		if appErr := p.API.KVSet(key, []byte(id)); appErr != nil {
+			mlog.Warn("Unable to store the imported event", mlog.String("uid", event.UID), mlog.Err(appErr))
+		}
+	}
+	return nil
+}
+
+// formatImportResult describes the result of an import to the user.
+func (p *Plugin) formatImportResult(l *i18n.Localizer, user *model.User, filename string, result *importResult) string {
+	location := user.GetTimezoneLocation()
+	text := p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "jitsi.import.title",
+			One:   "Imported {{.Count}} meeting from `{{.Filename}}`.",
+			Other: "Imported {{.Count}} meetings from `{{.Filename}}`.",
+		},
+		PluralCount: len(result.Scheduled) + len(result.Recurring),
+		TemplateData: map[string]interface{}{
+			"Count":    len(result.Scheduled) + len(result.Recurring),
+			"Filename": filename,
+		},
+	})
+
+	for _, meeting := range result.Scheduled {
+		text += "\n" + p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.import.scheduled",
+				Other: "* **{{.Topic}}** - `{{.MeetingID}}` on {{.Datetime}}",
+			},
+			TemplateData: map[string]string{
+				"Topic":     meeting.Topic,
+				"MeetingID": meeting.Room,
+				"Datetime":  meeting.StartTime().In(location).Format("Mon Jan 2 15:04 MST 2006"),
+			},
+		})
+	}
+	for _, recurring := range result.Recurring {
+		text += "\n" + p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.import.recurring",
+				Other: "* **{{.Topic}}** - `{{.MeetingID}}` repeating `{{.Rule}}`, next on {{.Datetime}}",
+			},
+			TemplateData: map[string]string{
+				"Topic":     recurring.Topic,
+				"MeetingID": recurring.Room,
+				"Rule":      recurring.Rule,
+				"Datetime":  time.UnixMilli(recurring.NextAt).In(location).Format("Mon Jan 2 15:04 MST 2006"),
+			},
+		})
+	}
+
+	if len(result.Skipped) > 0 {
+		text += "\n" + p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.import.skipped_title",
+				Other: "Skipped events:",
+			},
+		})
+		for _, skipped := range result.Skipped {
+			text += "\n" + p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "jitsi.import.skipped",
+					Other: "* **{{.Topic}}**: {{.Reason}}",
+				},
+				TemplateData: map[string]string{
+					"Topic":  skipped.Summary,
+					"Reason": p.importSkipReason(l, skipped.Err),
+				},
+			})
+		}
+	}
+
+	return text
+}
+
+// MessageHasBeenPosted imports the calendar files attached to a post mentioning the Jitsi bot.
+func (p *Plugin) MessageHasBeenPosted(_ *plugin.Context, post *model.Post) {
+	if post.UserId == p.botID || len(post.FileIds) == 0 || !botMentionPattern.MatchString(post.Message) {
+		return
+	}
+
+	user, appErr := p.API.GetUser(post.UserId)
+	if appErr != nil || user.IsBot {
+		return
+	}
+
+	channel, appErr := p.API.GetChannel(post.ChannelId)
+	if appErr != nil {
+		mlog.Error("Unable to get the channel", mlog.Err(appErr))
+		return
+	}
+
+	rootID := post.RootId
+	if rootID == "" {
+		rootID = post.Id
+	}
+
+	l := p.b.GetUserLocalizer(user.Id)
+	for _, fileID := range post.FileIds {
+		info, appErr := p.API.GetFileInfo(fileID)
+		if appErr != nil || !isCalendarFile(info) {
+			continue
+		}
+
+		var message string
+		result, err := p.importCalendarFile(user, channel, info, rootID)
+		if err != nil {
+			mlog.Warn("Unable to import the calendar file", mlog.String("file_id", fileID), mlog.Err(err))
+			message = p.importErrorMessage(l, err)
+		} else {
+			message = p.formatImportResult(l, user, info.Name, result)
+		}
+
+		_ = p.API.SendEphemeralPost(user.Id, &model.Post{
+			UserId:    p.botID,
+			ChannelId: channel.Id,
+			RootId:    rootID,
+			Message:   message,
+		})
+	}
+}
+
+func (p *Plugin) importErrorMessage(l *i18n.Localizer, err error) string {
+	switch {
+	case isStartPolicyError(err):
+		return p.startPolicyMessage(l, err)
+	case errors.Is(err, errNoCalendarFile):
+		return p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.import.no_file",
+				Other: "No calendar file found. Upload an `.ics` file in this channel, then run `/jitsi import` again.",
+			},
+		})
+	case errors.Is(err, errCalendarFileTooBig), errors.Is(err, errTooManyImportEvents):
+		return p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.import.too_big",
+				Other: "The calendar file is too big, it can contain at most {{.Count}} events.",
+			},
+			TemplateData: map[string]int{"Count": maxImportedEvents},
+		})
+	default:
+		return p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.import.error",
+				Other: "We could not import the calendar file. Make sure it is a valid `.ics` file.",
+			},
+		})
+	}
+}
+
+// importSkipReason explains to the user why an event was not imported.
+func (p *Plugin) importSkipReason(l *i18n.Localizer, err error) string {
+	var message *i18n.Message
+	switch {
+	case isStartPolicyError(err):
+		return p.startPolicyMessage(l, err)
+	case errors.Is(err, errEventCancelled):
+		message = &i18n.Message{ID: "jitsi.import.skip.cancelled", Other: "The event is cancelled."}
+	case errors.Is(err, errEventAlreadyDone):
+		message = &i18n.Message{ID: "jitsi.import.skip.done", Other: "The event has no upcoming occurrence."}
+	case errors.Is(err, errEventAlreadyImported):
+		message = &i18n.Message{ID: "jitsi.import.skip.imported", Other: "The event was already imported in this channel."}
+	case errors.Is(err, errModifiedOccurrence):
+		message = &i18n.Message{ID: "jitsi.import.skip.modified_occurrence", Other: "Modified occurrences of recurring events are not supported."}
+	case errors.Is(err, errUnsupportedRecurrence):
+		message = &i18n.Message{ID: "jitsi.import.skip.recurrence", Other: "The recurrence of the event is not supported."}
+	case errors.Is(err, errICSAllDayEvent):
+		message = &i18n.Message{ID: "jitsi.import.skip.all_day", Other: "All-day events are not supported."}
+	case errors.Is(err, errICSMissingStart):
+		message = &i18n.Message{ID: "jitsi.import.skip.no_start", Other: "The event has no start time."}
+	case errors.Is(err, errICSEndBeforeStart):
+		message = &i18n.Message{ID: "jitsi.import.skip.end_before_start", Other: "The event ends before it starts."}
+	case errors.Is(err, errICSUnknownTimezone):
+		message = &i18n.Message{ID: "jitsi.import.skip.timezone", Other: "The time zone of the event is unknown."}
+	default:
+		message = &i18n.Message{ID: "jitsi.import.skip.error", Other: "The event could not be imported."}
+	}
+	return p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{DefaultMessage: message})
+}
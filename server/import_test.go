@@ -0,0 +1,168 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportCalendar(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL:          "http://test",
+			JitsiNamingScheme: "uuid",
+		},
+		botID: "test-bot-id",
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("GetBundlePath").Return("..", nil)
+	config := model.Config{}
+	config.SetDefaults()
+	apiMock.On("GetConfig").Return(&config, nil)
+	apiMock.On("KVGet", "config_test-user").Return(nil, nil)
+	mockMeetingStore(&apiMock)
+	mockKVStore(&apiMock, importedEventKeyPrefix)
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	user := &model.User{Id: "test-user", Username: "test-username", Timezone: model.StringMap{"useAutomaticTimezone": "false", "manualTimezone": "Europe/Paris"}}
+	channel := &model.Channel{Id: "test-channel", Type: model.ChannelTypeOpen}
+
+	tomorrow := time.Now().Add(24 * time.Hour).UTC()
+	lastWeek := time.Now().Add(-7 * 24 * time.Hour).UTC()
+	event := func(uid string, properties string) string {
+		return "BEGIN:VEVENT\r\nUID:" + uid + "\r\n" + properties + "END:VEVENT\r\n"
+	}
+	data := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" +
+		event("single", "SUMMARY:Partner sync\r\nDTSTART:"+tomorrow.Format("20060102T150400Z")+"\r\nDURATION:PT30M\r\n") +
+		event("floating", "SUMMARY:Floating\r\nDTSTART:"+tomorrow.Format("20060102T150400")+"\r\n") +
+		event("daily", "SUMMARY:Standup\r\nDTSTART:"+lastWeek.Format("20060102T150400Z")+"\r\nDTEND:"+lastWeek.Add(15*time.Minute).Format("20060102T150400Z")+"\r\nRRULE:FREQ=DAILY;COUNT=30\r\n") +
+		event("weekly", "SUMMARY:Weekly review\r\nDTSTART:"+tomorrow.Format("20060102T150400Z")+"\r\nRRULE:FREQ=WEEKLY;COUNT=3\r\nEXDATE:"+tomorrow.Format("20060102T150400Z")+"\r\n") +
+		event("excluded", "SUMMARY:Excluded\r\nDTSTART:"+tomorrow.Format("20060102T150400Z")+"\r\nRRULE:FREQ=DAILY;COUNT=2\r\nEXDATE:"+tomorrow.Format("20060102T150400Z")+","+tomorrow.Add(24*time.Hour).Format("20060102T150400Z")+"\r\n") +
+		event("done", "SUMMARY:Done\r\nDTSTART:"+lastWeek.Format("20060102T150400Z")+"\r\nRRULE:FREQ=DAILY;COUNT=2\r\n") +
+		event("past", "SUMMARY:Past\r\nDTSTART:"+lastWeek.Format("20060102T150400Z")+"\r\n") +
+		event("cancelled", "SUMMARY:Cancelled\r\nSTATUS:CANCELLED\r\nDTSTART:"+tomorrow.Format("20060102T150400Z")+"\r\n") +
+		event("monthly", "SUMMARY:Monthly\r\nDTSTART:"+tomorrow.Format("20060102T150400Z")+"\r\nRRULE:FREQ=MONTHLY\r\n") +
+		event("all-day", "SUMMARY:Holiday\r\nDTSTART;VALUE=DATE:"+tomorrow.Format("20060102")+"\r\n") +
+		"END:VCALENDAR\r\n"
+
+	apiMock.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.UserId == "test-bot-id" && post.ChannelId == "test-channel" && post.RootId == "test-root"
+	})).Return(&model.Post{}, nil).Times(2)
+
+	result, err := p.importCalendar(user, channel, data, "test-root")
+	require.Nil(t, err)
+
+	require.Len(t, result.Scheduled, 2)
+	require.Equal(t, "Partner sync", result.Scheduled[0].Topic)
+	require.Equal(t, tomorrow.Truncate(time.Minute).UnixMilli(), result.Scheduled[0].StartAt)
+	require.Equal(t, 30*time.Minute, result.Scheduled[0].PlannedDuration())
+	// Floating times are in the user time zone.
+	floating, err := time.ParseInLocation("20060102T150400", tomorrow.Format("20060102T150400"), user.GetTimezoneLocation())
+	require.Nil(t, err)
+	require.Equal(t, floating.UnixMilli(), result.Scheduled[1].StartAt)
+
+	require.Len(t, result.Recurring, 2)
+	require.Equal(t, "Standup", result.Recurring[0].Topic)
+	require.Equal(t, int64(15*time.Minute/time.Millisecond), result.Recurring[0].Duration)
+	// The occurrences of the last week are deducted from the count.
+	require.Equal(t, "FREQ=DAILY;COUNT=22", result.Recurring[0].Rule)
+	// The excluded first occurrence is skipped but still counted.
+	require.Equal(t, "Weekly review", result.Recurring[1].Topic)
+	require.Equal(t, []int64{tomorrow.Truncate(time.Minute).UnixMilli()}, result.Recurring[1].ExDates)
+	require.Equal(t, tomorrow.Truncate(time.Minute).Add(7*24*time.Hour).UnixMilli(), result.Recurring[1].NextAt)
+	require.Equal(t, 1, result.Recurring[1].Occurrences)
+
+	skipped := map[string]error{}
+	for _, s := range result.Skipped {
+		skipped[s.Summary] = s.Err
+	}
+	require.Len(t, skipped, 6)
+	require.ErrorIs(t, skipped["Excluded"], errEventAlreadyDone)
+	require.ErrorIs(t, skipped["Done"], errEventAlreadyDone)
+	require.ErrorIs(t, skipped["Past"], errEventAlreadyDone)
+	require.ErrorIs(t, skipped["Cancelled"], errEventCancelled)
+	require.ErrorIs(t, skipped["Monthly"], errUnsupportedRecurrence)
+	require.ErrorIs(t, skipped["Holiday"], errICSAllDayEvent)
+
+	t.Run("importing the same events again", func(t *testing.T) {
+		result, err := p.importCalendar(user, channel, data, "test-root")
+		require.Nil(t, err)
+		require.Empty(t, result.Scheduled)
+		require.Empty(t, result.Recurring)
+		require.Len(t, result.Skipped, 10)
+		require.ErrorIs(t, result.Skipped[0].Err, errEventAlreadyImported)
+	})
+
+	t.Run("format the result", func(t *testing.T) {
+		text := p.formatImportResult(p.b.GetServerLocalizer(), user, "invite.ics", result)
+		require.True(t, strings.HasPrefix(text, "Imported 4 meetings from `invite.ics`.\n* **Partner sync** - `"))
+		require.Contains(t, text, "repeating `FREQ=DAILY;COUNT=22`")
+		require.Contains(t, text, "\nSkipped events:\n")
+		require.Contains(t, text, "\n* **Holiday**: All-day events are not supported.")
+		require.Contains(t, text, "\n* **Monthly**: The recurrence of the event is not supported.")
+		require.Contains(t, text, "\n* **Done**: The event has no upcoming occurrence.")
+	})
+}
+
+func TestMessageHasBeenPostedImportsCalendar(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL:          "http://test",
+			JitsiNamingScheme: "uuid",
+		},
+		botID: "test-bot-id",
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("GetBundlePath").Return("..", nil)
+	config := model.Config{}
+	config.SetDefaults()
+	apiMock.On("GetConfig").Return(&config, nil)
+	apiMock.On("KVGet", "config_test-user").Return(nil, nil)
+	mockMeetingStore(&apiMock)
+	mockKVStore(&apiMock, importedEventKeyPrefix)
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	post := &model.Post{Id: "test-post", UserId: "test-user", ChannelId: "test-channel", FileIds: []string{"image", "invite"}}
+
+	t.Run("without a bot mention", func(t *testing.T) {
+		post.Message = "See the invite"
+		p.MessageHasBeenPosted(&plugin.Context{}, post)
+	})
+
+	t.Run("with a bot mention", func(t *testing.T) {
+		post.Message = "@jitsi please schedule this"
+		tomorrow := time.Now().Add(24 * time.Hour).UTC().Format("20060102T150400Z")
+		apiMock.On("GetUser", "test-user").Return(&model.User{Id: "test-user", Username: "test-username", Locale: "en"}, nil)
+		apiMock.On("GetChannel", "test-channel").Return(&model.Channel{Id: "test-channel", Type: model.ChannelTypeOpen}, nil)
+		apiMock.On("HasPermissionToChannel", "test-user", "test-channel", model.PermissionCreatePost).Return(true)
+		apiMock.On("GetFileInfo", "image").Return(&model.FileInfo{Id: "image", Name: "image.png", Extension: "png"}, nil)
+		apiMock.On("GetFileInfo", "invite").Return(&model.FileInfo{Id: "invite", Name: "invite.ics", Extension: "ics", Size: 100}, nil)
+		apiMock.On("GetFile", "invite").Return([]byte("BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:x\nSUMMARY:Partner sync\nDTSTART:"+tomorrow+"\nEND:VEVENT\nEND:VCALENDAR\n"), nil)
+		apiMock.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.UserId == "test-bot-id" && post.RootId == "test-post"
+		})).Return(&model.Post{}, nil).Once()
+		apiMock.On("SendEphemeralPost", "test-user", mock.MatchedBy(func(post *model.Post) bool {
+			return post.RootId == "test-post" && strings.HasPrefix(post.Message, "Imported 1 meeting from `invite.ics`.")
+		})).Return(nil).Once()
+
+		p.MessageHasBeenPosted(&plugin.Context{}, post)
+	})
+}
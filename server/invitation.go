@@ -0,0 +1,279 @@
+package main
+
+import (
+	"net/url"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/shared/mlog"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/pkg/errors"
+)
+
+const (
+	InvitationStateInvited  = "invited"
+	InvitationStateAccepted = "accepted"
+	InvitationStateDeclined = "declined"
+)
+
+var errNotInvited = errors.New("the user was not invited to the meeting")
+
+// MeetingInvitation is a user invited to a meeting by a direct message of the Jitsi bot. Invited
+// users can join the meeting without being members of its channel, unless they declined.
+type MeetingInvitation struct {
+	UserID    string `json:"user_id"`
+	InviterID string `json:"inviter_id"`
+	PostID    string `json:"post_id,omitempty"`
+	State     string `json:"state"`
+	InvitedAt int64  `json:"invited_at"`
+}
+
+// getInvitation returns the invitation of a user to the meeting, or nil.
+func (m *Meeting) getInvitation(userID string) *MeetingInvitation {
+	for _, invitation := range m.Invitations {
+		if invitation.UserID == userID {
+			return invitation
+		}
+	}
+	return nil
+}
+
+// canJoinMeeting reports whether a user can get a token to join a meeting: the members of its
+// channel and the users invited to it.
+func (p *Plugin) canJoinMeeting(userID string, meeting *Meeting) bool {
+	if _, appErr := p.API.GetChannelMember(meeting.ChannelID, userID); appErr == nil {
+		return true
+	}
+	invitation := meeting.getInvitation(userID)
+	return invitation != nil && invitation.State != InvitationStateDeclined
+}
+
+// inviteUsers sends the join card of a meeting to users in direct messages of the Jitsi bot and
+// records their invitations. Users invited again get a new card. The users invited before a card
+// fails to be sent keep their invitation.
+func (p *Plugin) inviteUsers(inviter *model.User, meeting *Meeting, users []*model.User) (*Meeting, error) {
+	if meeting.State == MeetingStateEnded {
+		return nil, errMeetingAlreadyEnded
+	}
+
+	for _, user := range users {
+		var err error
+		if meeting, err = p.inviteUser(inviter, meeting, user); err != nil {
+			return nil, err
+		}
+	}
+	return meeting, nil
+}
+
+// inviteUser records the invitation of a user before sending their join card, so that the card
+// works as soon as it arrives. The previous invitation of the user is restored if the card cannot
+// be sent.
+func (p *Plugin) inviteUser(inviter *model.User, meeting *Meeting, user *model.User) (*Meeting, error) {
+	var previous *MeetingInvitation
+	meeting, err := p.updateMeeting(meeting.ID, func(meeting *Meeting) error {
+		if meeting.State == MeetingStateEnded {
+			return errMeetingAlreadyEnded
+		}
+		invitation := &MeetingInvitation{
+			UserID:    user.Id,
+			InviterID: inviter.Id,
+			State:     InvitationStateInvited,
+			InvitedAt: model.GetMillis(),
+		}
+		if existing := meeting.getInvitation(user.Id); existing != nil {
+			previous = &MeetingInvitation{}
+			*previous = *existing
+			*existing = *invitation
+			return nil
+		}
+		meeting.Invitations = append(meeting.Invitations, invitation)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	postID, sendErr := p.sendInvitation(inviter, meeting, user)
+	meeting, err = p.updateMeeting(meeting.ID, func(meeting *Meeting) error {
+		for i, invitation := range meeting.Invitations {
+			if invitation.UserID != user.Id {
+				continue
+			}
+			switch {
+			case sendErr == nil:
+				invitation.PostID = postID
+			case previous != nil:
+				*invitation = *previous
+			default:
+				meeting.Invitations = append(meeting.Invitations[:i], meeting.Invitations[i+1:]...)
+			}
+			return nil
+		}
+		return nil
+	})
+	if sendErr != nil {
+		return nil, sendErr
+	}
+	return meeting, err
+}
+
+// sendInvitation sends the join card of a meeting to a user and returns the ID of the post. With
+// JWT authentication the card carries no token, which would outlive its expiry in the post: the
+// user gets their own link when they accept the invitation or refresh the link.
+func (p *Plugin) sendInvitation(inviter *model.User, meeting *Meeting, user *model.User) (string, error) {
+	channel, appErr := p.API.GetDirectChannel(user.Id, p.botID)
+	if appErr != nil {
+		return "", appErr
+	}
+
+	channelConfig, err := p.getChannelConfig(meeting.ChannelID)
+	if err != nil {
+		return "", err
+	}
+
+	l := p.b.GetUserLocalizer(user.Id)
+	var message string
+	if p.getConfiguration().JitsiJWT {
+		message = p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.invitation.message_jwt",
+				Other: "@{{.Inviter}} invited you to a meeting. Accept the invitation to get your link to join it.",
+			},
+			TemplateData: map[string]string{"Inviter": inviter.Username},
+		})
+	} else {
+		meetingURL, err := p.meetingJoinURL(user.Id, meeting)
+		if err != nil {
+			return "", err
+		}
+		message = p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.invitation.message",
+				Other: "@{{.Inviter}} invited you to a meeting: [Join Meeting]({{.MeetingURL}})",
+			},
+			TemplateData: map[string]string{"Inviter": inviter.Username, "MeetingURL": meetingURL},
+		})
+	}
+	attachment := p.meetingAttachment(meeting, p.meetingURLConfig(meeting, channelConfig, nil).Hash())
+	if meeting.Password != "" {
+		// The "Show password" action answers in the channel of the meeting, which invited users
+		// may not be members of.
+		message += "\n\n" + p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.invitation.password",
+				Other: "The password of the meeting is `{{.Password}}`.",
+			},
+			TemplateData: map[string]string{"Password": meeting.Password},
+		})
+		var actions []*model.PostAction
+		for _, action := range attachment.Actions {
+			if action.Id != "showpassword" {
+				actions = append(actions, action)
+			}
+		}
+		attachment.Actions = actions
+	}
+	apiURL := *p.API.GetConfig().ServiceSettings.SiteURL + "/plugins/jitsi/api/v1/meetings/" + url.PathEscape(meeting.ID) + "/invitations"
+	attachment.Actions = append(attachment.Actions, &model.PostAction{
+		Id:    "accept",
+		Type:  model.PostActionTypeButton,
+		Style: "success",
+		Name: p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.invitation.accept",
+				Other: "Accept",
+			},
+		}),
+		Integration: &model.PostActionIntegration{URL: apiURL + "/accept"},
+	}, &model.PostAction{
+		Id:    "decline",
+		Type:  model.PostActionTypeButton,
+		Style: "danger",
+		Name: p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.invitation.decline",
+				Other: "Decline",
+			},
+		}),
+		Integration: &model.PostActionIntegration{URL: apiURL + "/decline"},
+	})
+
+	post := &model.Post{
+		UserId:    p.botID,
+		ChannelId: channel.Id,
+		Message:   message,
+	}
+	post.AddProp("attachments", []*model.SlackAttachment{attachment})
+	post.AddProp("meeting_invitation_id", meeting.ID)
+	createdPost, appErr := p.API.CreatePost(post)
+	if appErr != nil {
+		return "", appErr
+	}
+	return createdPost.Id, nil
+}
+
+// answerInvitation records the answer of an invited user, updates their join card and tells the
+// user who invited them.
+func (p *Plugin) answerInvitation(meetingID string, userID string, state string) (*Meeting, error) {
+	var invitation *MeetingInvitation
+	meeting, err := p.updateMeeting(meetingID, func(meeting *Meeting) error {
+		if meeting.State == MeetingStateEnded {
+			return errMeetingAlreadyEnded
+		}
+		invitation = meeting.getInvitation(userID)
+		if invitation == nil {
+			return errNotInvited
+		}
+		invitation.State = state
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	l := p.b.GetUserLocalizer(userID)
+	answer := p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "jitsi.invitation.accepted",
+			Other: "You accepted the invitation.",
+		},
+	})
+	notification := &i18n.Message{
+		ID:    "jitsi.invitation.accepted_by",
+		Other: "@{{.Username}} accepted your invitation to meeting `{{.MeetingID}}`.",
+	}
+	if state == InvitationStateDeclined {
+		answer = p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.invitation.declined",
+				Other: "You declined the invitation.",
+			},
+		})
+		notification = &i18n.Message{
+			ID:    "jitsi.invitation.declined_by",
+			Other: "@{{.Username}} declined your invitation to meeting `{{.MeetingID}}`.",
+		}
+	}
+
+	if invitation.PostID != "" {
+		if post, appErr := p.API.GetPost(invitation.PostID); appErr == nil {
+			post.AddProp("attachments", []*model.SlackAttachment{{
+				Title: meeting.Topic,
+				Text:  answer,
+			}})
+			if _, appErr = p.API.UpdatePost(post); appErr != nil {
+				mlog.Warn("Unable to update the invitation", mlog.String("post_id", invitation.PostID), mlog.Err(appErr))
+			}
+		}
+	}
+
+	p.API.SendEphemeralPost(invitation.InviterID, &model.Post{
+		UserId:    p.botID,
+		ChannelId: meeting.ChannelID,
+		RootId:    meeting.RootID,
+		Message: p.b.LocalizeWithConfig(p.b.GetUserLocalizer(invitation.InviterID), &i18n.LocalizeConfig{
+			DefaultMessage: notification,
+			TemplateData:   map[string]string{"Username": p.username(userID), "MeetingID": meeting.Room},
+		}),
+	})
+	return meeting, nil
+}
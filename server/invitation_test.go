@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInviteUsers(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL:           "http://test",
+			JitsiJWT:           true,
+			JitsiAppID:         "test-app-id",
+			JitsiAppSecret:     "test-secret",
+			JitsiLinkValidTime: 30,
+		},
+		botID: "test-bot-id",
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("GetBundlePath").Return("..", nil)
+	config := model.Config{}
+	config.SetDefaults()
+	config.ServiceSettings.SiteURL = model.NewPointer("http://mattermost")
+	apiMock.On("GetConfig").Return(&config, nil)
+	mockMeetingStore(&apiMock)
+	mockKVStore(&apiMock, "config_", callStatusKeyPrefix)
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	creator := &model.User{Id: "creator", Username: "carol", Locale: "en"}
+	alice := &model.User{Id: "alice", Username: "alice", Locale: "en"}
+	apiMock.On("GetUser", "creator").Return(creator, nil)
+	apiMock.On("GetUser", "alice").Return(alice, nil)
+	apiMock.On("GetUserByUsername", "alice").Return(alice, nil)
+	apiMock.On("GetUserByUsername", "nobody").Return(nil, &model.AppError{StatusCode: http.StatusNotFound})
+	apiMock.On("GetChannelMember", "test-channel", "alice").Return(nil, &model.AppError{StatusCode: http.StatusNotFound})
+	apiMock.On("HasPermissionToChannel", mock.AnythingOfType("string"), "test-channel", model.PermissionManageChannelRoles).Return(false)
+	apiMock.On("GetDirectChannel", "alice", "test-bot-id").Return(&model.Channel{Id: "alice-bot-dm"}, nil)
+
+	meeting := &Meeting{Room: "test-room", ChannelID: "test-channel", CreatorID: "creator", Topic: "Sync", PostID: "meeting-post", State: MeetingStateActive}
+	require.Nil(t, p.createMeeting(meeting))
+
+	invitationPost := &model.Post{Id: "invitation-post", ChannelId: "alice-bot-dm"}
+	apiMock.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.GetProp("meeting_invitation_id") == meeting.ID
+	})).Return(func(post *model.Post) *model.Post {
+		require.Equal(t, "alice-bot-dm", post.ChannelId)
+		require.Equal(t, "@carol invited you to a meeting. Accept the invitation to get your link to join it.", post.Message)
+		actions := post.Attachments()[0].Actions
+		require.Equal(t, "http://mattermost/plugins/jitsi/api/v1/meetings/"+meeting.ID+"/invitations/accept", actions[len(actions)-2].Integration.URL)
+		require.Equal(t, "http://mattermost/plugins/jitsi/api/v1/meetings/"+meeting.ID+"/invitations/decline", actions[len(actions)-1].Integration.URL)
+		invitationPost.Props = post.Props
+		return invitationPost
+	}, nil)
+	apiMock.On("GetPost", "invitation-post").Return(func(string) *model.Post { return invitationPost.Clone() }, nil)
+	apiMock.On("UpdatePost", mock.MatchedBy(func(post *model.Post) bool { return post.Id == "invitation-post" })).Return(func(post *model.Post) *model.Post {
+		invitationPost = post
+		return post
+	}, nil)
+
+	command := func(t *testing.T, text string, response string) {
+		apiMock.On("SendEphemeralPost", "creator", &model.Post{
+			UserId:    "test-bot-id",
+			ChannelId: "test-channel",
+			Message:   response,
+		}).Return(nil).Once()
+		_, appErr := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{UserId: "creator", ChannelId: "test-channel", Command: text})
+		require.Nil(t, appErr)
+	}
+
+	t.Run("slash command", func(t *testing.T) {
+		command(t, "/jitsi invite test-room", "Nobody was invited to meeting `test-room`.")
+		command(t, "/jitsi invite @alice @nobody test-room", "Unable to find @nobody.")
+		command(t, "/jitsi invite @alice test-room", "Invited @alice to meeting `test-room`.")
+		command(t, "/jitsi invite test-room", "###### Invitations to meeting `test-room`\n* @alice: no answer yet")
+	})
+
+	t.Run("invited users join without being channel members", func(t *testing.T) {
+		token, _, err := p.issueMeetingToken(alice, meeting)
+		require.Nil(t, err)
+		_, err = p.updateJwtUserInfo(token, alice)
+		require.Nil(t, err)
+	})
+
+	serve := func(userID string, method string, path string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, "/api/v1/meetings/"+meeting.ID+path, bytes.NewReader([]byte(body)))
+		r.Header.Set("Mattermost-User-Id", userID)
+		p.ServeHTTP(&plugin.Context{}, w, r)
+		return w
+	}
+
+	t.Run("API", func(t *testing.T) {
+		require.Equal(t, http.StatusForbidden, serve("member", http.MethodPost, "/invitations", `{"user_ids": ["alice"]}`).Code)
+		require.Equal(t, http.StatusBadRequest, serve("creator", http.MethodPost, "/invitations", `{"user_ids": []}`).Code)
+
+		w := serve("creator", http.MethodPost, "/invitations", `{"user_ids": ["alice"]}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		var invitations []*MeetingInvitation
+		require.Nil(t, json.Unmarshal(w.Body.Bytes(), &invitations))
+		require.Len(t, invitations, 1)
+		require.Equal(t, "alice", invitations[0].UserID)
+		require.Equal(t, "creator", invitations[0].InviterID)
+		require.Equal(t, InvitationStateInvited, invitations[0].State)
+	})
+
+	t.Run("accept", func(t *testing.T) {
+		require.Equal(t, http.StatusForbidden, serve("member", http.MethodPost, "/invitations/accept", "").Code)
+
+		apiMock.On("SendEphemeralPost", "creator", &model.Post{
+			UserId:    "test-bot-id",
+			ChannelId: "test-channel",
+			Message:   "@alice accepted your invitation to meeting `test-room`.",
+		}).Return(nil).Once()
+		w := serve("alice", http.MethodPost, "/invitations/accept", `{"post_id": "invitation-post"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		var response model.PostActionIntegrationResponse
+		require.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.True(t, strings.HasPrefix(response.EphemeralText, "[Join Meeting](http://test/test-room?jwt="))
+		require.Equal(t, "You accepted the invitation.", invitationPost.Attachments()[0].Text)
+
+		w = serve("creator", http.MethodGet, "/invitations", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var invitations []*MeetingInvitation
+		require.Nil(t, json.Unmarshal(w.Body.Bytes(), &invitations))
+		require.Equal(t, InvitationStateAccepted, invitations[0].State)
+		command(t, "/jitsi invite test-room", "###### Invitations to meeting `test-room`\n* @alice: accepted")
+	})
+
+	t.Run("decline", func(t *testing.T) {
+		apiMock.On("SendEphemeralPost", "creator", &model.Post{
+			UserId:    "test-bot-id",
+			ChannelId: "test-channel",
+			Message:   "@alice declined your invitation to meeting `test-room`.",
+		}).Return(nil).Once()
+		require.Equal(t, http.StatusOK, serve("alice", http.MethodPost, "/invitations/decline", "").Code)
+
+		stored, err := p.getMeeting(meeting.ID)
+		require.Nil(t, err)
+		require.False(t, p.canJoinMeeting("alice", stored))
+	})
+
+	t.Run("a failed card leaves the invitations sent before", func(t *testing.T) {
+		bob := &model.User{Id: "bob", Username: "bob", Locale: "en"}
+		apiMock.On("GetDirectChannel", "bob", "test-bot-id").Return(nil, &model.AppError{Message: "unavailable"}).Once()
+
+		_, err := p.inviteUsers(creator, meeting, []*model.User{alice, bob})
+		require.NotNil(t, err)
+
+		stored, err := p.getMeeting(meeting.ID)
+		require.Nil(t, err)
+		require.Len(t, stored.Invitations, 1)
+		require.Equal(t, "alice", stored.Invitations[0].UserID)
+		require.Equal(t, InvitationStateInvited, stored.Invitations[0].State)
+		require.Equal(t, "invitation-post", stored.Invitations[0].PostID)
+		require.Nil(t, stored.getInvitation("bob"))
+	})
+
+	t.Run("ended meeting", func(t *testing.T) {
+		apiMock.On("GetPost", "meeting-post").Return(&model.Post{Id: "meeting-post"}, nil).Once()
+		apiMock.On("UpdatePost", mock.MatchedBy(func(post *model.Post) bool { return post.Id == "meeting-post" })).Return(&model.Post{}, nil).Once()
+		_, err := p.endMeeting(meeting.ID)
+		require.Nil(t, err)
+
+		require.Equal(t, http.StatusConflict, serve("creator", http.MethodPost, "/invitations", `{"user_ids": ["alice"]}`).Code)
+	})
+}
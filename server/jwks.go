@@ -0,0 +1,456 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	"github.com/cristalhq/jwt/v2"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	jwtAlgorithmHS256 = "HS256"
+	jwtAlgorithmRS256 = "RS256"
+	jwtAlgorithmES256 = "ES256"
+
+	// publishedJWTKeysKey stores the public keys listed in the JWKS, including the replaced keys
+	// still in their overlap window.
+	publishedJWTKeysKey = "jwt_published_keys"
+
+	defaultJWTKeyRotationOverlap = 24 * 60
+)
+
+var errUnknownJWTKey = errors.New("unknown JWT key ID")
+
+// JWK is a public JSON Web Key as described by RFC 7517.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+
+	// RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC keys
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// JWKS is the JSON Web Key Set served to the Jitsi server.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// publishedJWTKey is a public key listed in the JWKS. RetiredAt is set once the key stopped being
+// used to sign tokens.
+type publishedJWTKey struct {
+	JWK       JWK   `json:"jwk"`
+	RetiredAt int64 `json:"retired_at,omitempty"`
+}
+
+// jwtSigningKey is the asymmetric key tokens are signed with.
+type jwtSigningKey struct {
+	Algorithm string
+	KeyID     string
+	Private   crypto.Signer
+}
+
+// parseJWTPrivateKey parses a PEM encoded private key, in the PKCS #1, PKCS #8 or SEC 1 format,
+// and checks that it can be used with the algorithm.
+func parseJWTPrivateKey(algorithm string, data string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("no PEM encoded private key found")
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, errors.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid private key")
+	}
+
+	switch algorithm {
+	case jwtAlgorithmRS256:
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("RS256 requires an RSA private key")
+		}
+		if rsaKey.N.BitLen() < 2048 {
+			return nil, errors.New("RS256 requires an RSA key of at least 2048 bits")
+		}
+		return rsaKey, nil
+	case jwtAlgorithmES256:
+		ecKey, ok := key.(*ecdsa.PrivateKey)
+		if !ok || ecKey.Curve != elliptic.P256() {
+			return nil, errors.New("ES256 requires an ECDSA P-256 private key")
+		}
+		return ecKey, nil
+	default:
+		return nil, errors.Errorf("unsupported JWT algorithm %q", algorithm)
+	}
+}
+
+// publicJWK returns the JWK of a public key. The key ID is the RFC 7638 thumbprint of the key, so
+// that replacing the private key in the configuration is enough to rotate it.
+func publicJWK(algorithm string, public crypto.PublicKey) (JWK, error) {
+	var key JWK
+	var thumbprintInput []byte
+	var err error
+	switch public := public.(type) {
+	case *rsa.PublicKey:
+		key = JWK{
+			KeyType: "RSA",
+			N:       base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			E:       base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}
+		thumbprintInput, err = json.Marshal(map[string]string{"e": key.E, "kty": key.KeyType, "n": key.N})
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		key = JWK{
+			KeyType: "EC",
+			Curve:   public.Curve.Params().Name,
+			X:       base64.RawURLEncoding.EncodeToString(public.X.FillBytes(make([]byte, size))),
+			Y:       base64.RawURLEncoding.EncodeToString(public.Y.FillBytes(make([]byte, size))),
+		}
+		thumbprintInput, err = json.Marshal(map[string]string{"crv": key.Curve, "kty": key.KeyType, "x": key.X, "y": key.Y})
+	default:
+		return JWK{}, errors.Errorf("unsupported public key type %T", public)
+	}
+	if err != nil {
+		return JWK{}, err
+	}
+
+	thumbprint := sha256.Sum256(thumbprintInput)
+	key.Use = "sig"
+	key.Algorithm = algorithm
+	key.KeyID = base64.RawURLEncoding.EncodeToString(thumbprint[:])
+	return key, nil
+}
+
+// PublicKey returns the public key described by the JWK.
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	decode := func(value string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	switch k.KeyType {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Curve != elliptic.P256().Params().Name {
+			return nil, errors.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	default:
+		return nil, errors.Errorf("unsupported key type %q", k.KeyType)
+	}
+}
+
+// asapKeyName returns the name of the public key file fetched by the Prosody token_verification
+// module when asap_key_server is set: the hex encoded SHA-256 digest of the key ID.
+func asapKeyName(keyID string) string {
+	hash := sha256.Sum256([]byte(keyID))
+	return hex.EncodeToString(hash[:])
+}
+
+// getSigningKey returns the asymmetric key configured to sign tokens.
+func (c *configuration) getSigningKey() (*jwtSigningKey, error) {
+	algorithm := c.GetJWTAlgorithm()
+	if algorithm == jwtAlgorithmHS256 {
+		return nil, errors.New("HS256 tokens are signed with the app secret")
+	}
+
+	private, err := parseJWTPrivateKey(algorithm, c.JitsiJWTPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	key, err := publicJWK(algorithm, private.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	return &jwtSigningKey{Algorithm: algorithm, KeyID: key.KeyID, Private: private}, nil
+}
+
+// jwtHeader is the JOSE header of the tokens signed with an asymmetric key. cristalhq/jwt does not
+// support the kid header, which the Jitsi server needs to pick the verification key.
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid,omitempty"`
+}
+
+// signClaimsWithKey signs the claims with an asymmetric key, adding its ID to the token header.
+func signClaimsWithKey(key *jwtSigningKey, claims *Claims) (string, error) {
+	var signer jwt.Signer
+	var err error
+	switch private := key.Private.(type) {
+	case *rsa.PrivateKey:
+		signer, err = jwt.NewSignerRS(jwt.RS256, private)
+	case *ecdsa.PrivateKey:
+		signer, err = jwt.NewSignerES(jwt.ES256, private)
+	default:
+		err = errors.Errorf("unsupported private key type %T", private)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	header, err := json.Marshal(jwtHeader{Algorithm: string(signer.Algorithm()), Type: "JWT", KeyID: key.KeyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature, err := signer.Sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// verifyJwtWithKeys verifies a token signed with one of the published asymmetric keys.
+func verifyJwtWithKeys(keys []JWK, jwtToken string) (*Claims, error) {
+	token, err := jwt.ParseString(jwtToken)
+	if err != nil {
+		return nil, err
+	}
+
+	rawHeader, err := base64.RawURLEncoding.DecodeString(string(token.RawHeader()))
+	if err != nil {
+		return nil, err
+	}
+	var header jwtHeader
+	if err = json.Unmarshal(rawHeader, &header); err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		if key.KeyID != header.KeyID || key.Algorithm != header.Algorithm {
+			continue
+		}
+
+		public, err := key.PublicKey()
+		if err != nil {
+			return nil, err
+		}
+
+		var verifier jwt.Verifier
+		switch public := public.(type) {
+		case *rsa.PublicKey:
+			verifier, err = jwt.NewVerifierRS(jwt.RS256, public)
+		case *ecdsa.PublicKey:
+			verifier, err = jwt.NewVerifierES(jwt.ES256, public)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if verifier == nil {
+			return nil, errors.Errorf("unsupported public key type %T", public)
+		}
+
+		if _, err = jwt.ParseAndVerifyString(jwtToken, verifier); err != nil {
+			return nil, err
+		}
+
+		var claims Claims
+		if err = json.Unmarshal(token.RawClaims(), &claims); err != nil {
+			return nil, err
+		}
+		return &claims, nil
+	}
+
+	return nil, errUnknownJWTKey
+}
+
+// signToken signs the claims with the configured algorithm.
+func (p *Plugin) signToken(claims *Claims) (string, error) {
+	config := p.getConfiguration()
+	if config.GetJWTAlgorithm() == jwtAlgorithmHS256 {
+		return signClaims(config.JitsiAppSecret, claims)
+	}
+
+	key, err := config.getSigningKey()
+	if err != nil {
+		return "", err
+	}
+	return signClaimsWithKey(key, claims)
+}
+
+// verifyToken verifies a token signed by the plugin with the app secret or with one of the
+// published keys.
+func (p *Plugin) verifyToken(jwtToken string) (*Claims, error) {
+	config := p.getConfiguration()
+	if config.GetJWTAlgorithm() == jwtAlgorithmHS256 {
+		return verifyJwt(config.JitsiAppSecret, jwtToken)
+	}
+
+	keys, err := p.getPublishedJWTKeys()
+	if err != nil {
+		return nil, err
+	}
+	return verifyJwtWithKeys(keys, jwtToken)
+}
+
+func (p *Plugin) loadPublishedJWTKeys() ([]publishedJWTKey, error) {
+	data, appErr := p.API.KVGet(publishedJWTKeysKey)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	var keys []publishedJWTKey
+	if data != nil {
+		if err := json.Unmarshal(data, &keys); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// getPublishedJWTKeys returns the public keys tokens may be verified with: the configured key and
+// the replaced keys still in their overlap window.
+func (p *Plugin) getPublishedJWTKeys() ([]JWK, error) {
+	config := p.getConfiguration()
+	keys := []JWK{}
+
+	var currentKeyID string
+	if config.JitsiJWT && config.GetJWTAlgorithm() != jwtAlgorithmHS256 {
+		signingKey, err := config.getSigningKey()
+		if err != nil {
+			return nil, err
+		}
+		current, err := publicJWK(signingKey.Algorithm, signingKey.Private.Public())
+		if err != nil {
+			return nil, err
+		}
+		currentKeyID = current.KeyID
+		keys = append(keys, current)
+	}
+
+	published, err := p.loadPublishedJWTKeys()
+	if err != nil {
+		return nil, err
+	}
+	overlap := time.Duration(config.GetJWTKeyRotationOverlap()) * time.Minute
+	now := time.Now()
+	for _, key := range published {
+		if key.JWK.KeyID == currentKeyID {
+			continue
+		}
+		if key.RetiredAt > 0 && now.After(time.UnixMilli(key.RetiredAt).Add(overlap)) {
+			continue
+		}
+		keys = append(keys, key.JWK)
+	}
+
+	return keys, nil
+}
+
+// rotateJWTKeys records the configured signing key as published, and retires the keys it
+// replaced. Retired keys stay in the JWKS during the overlap window, so that the tokens they
+// signed remain valid until they expire.
+func (p *Plugin) rotateJWTKeys() error {
+	config := p.getConfiguration()
+
+	var current *JWK
+	if config.JitsiJWT && config.GetJWTAlgorithm() != jwtAlgorithmHS256 {
+		signingKey, err := config.getSigningKey()
+		if err != nil {
+			return err
+		}
+		key, err := publicJWK(signingKey.Algorithm, signingKey.Private.Public())
+		if err != nil {
+			return err
+		}
+		current = &key
+	}
+
+	if current == nil {
+		// Nothing to retire when asymmetric keys were never used.
+		published, err := p.loadPublishedJWTKeys()
+		if err != nil || len(published) == 0 {
+			return err
+		}
+	}
+
+	overlap := time.Duration(config.GetJWTKeyRotationOverlap()) * time.Minute
+	return p.atomicKVUpdate(publishedJWTKeysKey, func(data []byte) ([]byte, error) {
+		var keys []publishedJWTKey
+		if data != nil {
+			if err := json.Unmarshal(data, &keys); err != nil {
+				return nil, err
+			}
+		}
+
+		now := time.Now()
+		updated := []publishedJWTKey{}
+		found := false
+		for _, key := range keys {
+			switch {
+			case current != nil && key.JWK.KeyID == current.KeyID:
+				// The key was configured again before the end of its overlap window.
+				key.RetiredAt = 0
+				found = true
+			case key.RetiredAt == 0:
+				key.RetiredAt = model.GetMillis()
+			case now.After(time.UnixMilli(key.RetiredAt).Add(overlap)):
+				continue
+			}
+			updated = append(updated, key)
+		}
+		if current != nil && !found {
+			updated = append(updated, publishedJWTKey{JWK: *current})
+		}
+
+		return json.Marshal(updated)
+	})
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cristalhq/jwt/v2"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/require"
+)
+
+func encodePrivateKey(t *testing.T, key any) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.Nil(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func TestParseJWTPrivateKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	otherECKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.Nil(t, err)
+
+	_, err = parseJWTPrivateKey(jwtAlgorithmRS256, encodePrivateKey(t, rsaKey))
+	require.Nil(t, err)
+	_, err = parseJWTPrivateKey(jwtAlgorithmRS256, string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})))
+	require.Nil(t, err)
+	_, err = parseJWTPrivateKey(jwtAlgorithmES256, encodePrivateKey(t, ecKey))
+	require.Nil(t, err)
+
+	for name, test := range map[string]struct {
+		algorithm string
+		key       string
+	}{
+		"not PEM":           {jwtAlgorithmRS256, "secret"},
+		"EC key for RS256":  {jwtAlgorithmRS256, encodePrivateKey(t, ecKey)},
+		"RSA key for ES256": {jwtAlgorithmES256, encodePrivateKey(t, rsaKey)},
+		"P-384 key":         {jwtAlgorithmES256, encodePrivateKey(t, otherECKey)},
+		"HS256":             {jwtAlgorithmHS256, encodePrivateKey(t, ecKey)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseJWTPrivateKey(test.algorithm, test.key)
+			require.NotNil(t, err)
+		})
+	}
+}
+
+func TestSignClaimsWithKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	for algorithm, privateKey := range map[string]any{jwtAlgorithmRS256: rsaKey, jwtAlgorithmES256: ecKey} {
+		t.Run(algorithm, func(t *testing.T) {
+			config := configuration{JitsiJWTAlgorithm: algorithm, JitsiJWTPrivateKey: encodePrivateKey(t, privateKey)}
+			key, err := config.getSigningKey()
+			require.Nil(t, err)
+
+			claims := Claims{Room: "test-room"}
+			claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Minute).Truncate(time.Second))
+			token, err := signClaimsWithKey(key, &claims)
+			require.Nil(t, err)
+
+			header, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+			require.Nil(t, err)
+			require.JSONEq(t, `{"alg":"`+algorithm+`","typ":"JWT","kid":"`+key.KeyID+`"}`, string(header))
+
+			public, err := publicJWK(algorithm, key.Private.Public())
+			require.Nil(t, err)
+			require.Equal(t, key.KeyID, public.KeyID)
+
+			verified, err := verifyJwtWithKeys([]JWK{public}, token)
+			require.Nil(t, err)
+			require.Equal(t, &claims, verified)
+
+			_, err = verifyJwtWithKeys([]JWK{}, token)
+			require.ErrorIs(t, err, errUnknownJWTKey)
+
+			tampered := token[:len(token)-4] + "AAAA"
+			_, err = verifyJwtWithKeys([]JWK{public}, tampered)
+			require.NotNil(t, err)
+		})
+	}
+}
+
+func TestJWTKeyRotation(t *testing.T) {
+	firstKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	secondKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL:           "http://test",
+			JitsiJWT:           true,
+			JitsiAppID:         "test-app-id",
+			JitsiJWTAlgorithm:  jwtAlgorithmES256,
+			JitsiJWTPrivateKey: encodePrivateKey(t, firstKey),
+		},
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	store := mockKVStore(&apiMock, publishedJWTKeysKey)
+	p.SetAPI(&apiMock)
+
+	require.Nil(t, p.rotateJWTKeys())
+
+	claims := Claims{Room: "test-room"}
+	firstToken, err := p.signToken(&claims)
+	require.Nil(t, err)
+
+	// Replace the key in the configuration.
+	p.configuration = p.configuration.Clone()
+	p.configuration.JitsiJWTPrivateKey = encodePrivateKey(t, secondKey)
+	require.Nil(t, p.rotateJWTKeys())
+
+	keys, err := p.getPublishedJWTKeys()
+	require.Nil(t, err)
+	require.Len(t, keys, 2)
+	secondKeyID := keys[0].KeyID
+
+	// Tokens signed with the replaced key remain valid during the overlap window.
+	_, err = p.verifyToken(firstToken)
+	require.Nil(t, err)
+
+	secondToken, err := p.signToken(&claims)
+	require.Nil(t, err)
+	_, err = p.verifyToken(secondToken)
+	require.Nil(t, err)
+
+	t.Run("JWKS and ASAP endpoints", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		p.ServeHTTP(&plugin.Context{}, w, httptest.NewRequest(http.MethodGet, "/api/v1/jwks.json", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var jwks JWKS
+		require.Nil(t, json.Unmarshal(w.Body.Bytes(), &jwks))
+		require.Equal(t, keys, jwks.Keys)
+		require.Equal(t, "EC", jwks.Keys[0].KeyType)
+		require.Equal(t, jwtAlgorithmES256, jwks.Keys[0].Algorithm)
+
+		w = httptest.NewRecorder()
+		p.ServeHTTP(&plugin.Context{}, w, httptest.NewRequest(http.MethodGet, "/api/v1/asap/"+asapKeyName(secondKeyID)+".pem", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		block, _ := pem.Decode(w.Body.Bytes())
+		require.NotNil(t, block)
+		public, err := x509.ParsePKIXPublicKey(block.Bytes)
+		require.Nil(t, err)
+		require.True(t, secondKey.PublicKey.Equal(public))
+
+		w = httptest.NewRecorder()
+		p.ServeHTTP(&plugin.Context{}, w, httptest.NewRequest(http.MethodGet, "/api/v1/asap/unknown.pem", nil))
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("retired key after the overlap window", func(t *testing.T) {
+		var published []publishedJWTKey
+		require.Nil(t, json.Unmarshal(store[publishedJWTKeysKey], &published))
+		require.Len(t, published, 2)
+		for i := range published {
+			if published[i].RetiredAt > 0 {
+				published[i].RetiredAt = time.Now().Add(-25 * time.Hour).UnixMilli()
+			}
+		}
+		store[publishedJWTKeysKey], err = json.Marshal(published)
+		require.Nil(t, err)
+
+		keys, err := p.getPublishedJWTKeys()
+		require.Nil(t, err)
+		require.Len(t, keys, 1)
+		require.Equal(t, secondKeyID, keys[0].KeyID)
+
+		_, err = p.verifyToken(firstToken)
+		require.ErrorIs(t, err, errUnknownJWTKey)
+
+		require.Nil(t, p.rotateJWTKeys())
+		require.Nil(t, json.Unmarshal(store[publishedJWTKeysKey], &published))
+		require.Len(t, published, 1)
+	})
+}
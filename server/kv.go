@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/pkg/errors"
+)
+
+// maxKVUpdateAttempts bounds how many times atomicKVUpdate retries when the value is modified
+// concurrently by another request or another node of the cluster.
+const maxKVUpdateAttempts = 10
+
+// atomicKVUpdate reads the value stored under key, passes it to update and stores the result
+// using compare-and-set, retrying when the value was modified in between. A nil value is passed
+// to update when the key does not exist.
+func (p *Plugin) atomicKVUpdate(key string, update func(data []byte) ([]byte, error)) error {
+	for i := 0; i < maxKVUpdateAttempts; i++ {
+		oldData, appErr := p.API.KVGet(key)
+		if appErr != nil {
+			return appErr
+		}
+
+		newData, err := update(oldData)
+		if err != nil {
+			return err
+		}
+
+		ok, appErr := p.API.KVCompareAndSet(key, oldData, newData)
+		if appErr != nil {
+			return appErr
+		}
+		if ok {
+			return nil
+		}
+	}
+
+	return errors.Errorf("failed to update %s: too many concurrent modifications", key)
+}
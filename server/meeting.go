@@ -0,0 +1,361 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	MeetingStateScheduled = "scheduled"
+	MeetingStateActive    = "active"
+	MeetingStateEnded     = "ended"
+	MeetingStateExpired   = "expired"
+)
+
+const (
+	meetingKeyPrefix         = "meeting_"
+	meetingRoomKeyPrefix     = "meeting_room_"
+	channelMeetingsKeyPrefix = "meetings_channel_"
+	userMeetingsKeyPrefix    = "meetings_user_"
+	scheduledMeetingsKey     = "meetings_scheduled"
+
+	// maxMeetingIndexSize is the number of most recent meetings kept in the per channel and per
+	// user indexes.
+	maxMeetingIndexSize = 250
+
+	// runningMeetingLookup is the number of recent channel meetings searched for one still running.
+	runningMeetingLookup = 20
+)
+
+var (
+	errMeetingNotFound     = errors.New("meeting not found")
+	errMeetingAlreadyEnded = errors.New("meeting already ended")
+	errNotChannelMember    = errors.New("user is not a member of the channel")
+)
+
+// Meeting is the persisted record of a Jitsi meeting started from Mattermost. Timestamps and
+// durations are expressed in milliseconds like the rest of the Mattermost data model.
+type Meeting struct {
+	ID           string `json:"id"`
+	Room         string `json:"room"`
+	ChannelID    string `json:"channel_id"`
+	RootID       string `json:"root_id,omitempty"`
+	CreatorID    string `json:"creator_id"`
+	Topic        string `json:"topic"`
+	PostID       string `json:"post_id"`
+	Personal     bool   `json:"personal"`
+	RecurringID  string `json:"recurring_id,omitempty"`
+	State        string `json:"state"`
+	CreateAt     int64  `json:"create_at"`
+	StartAt      int64  `json:"start_at,omitempty"`
+	Duration     int64  `json:"duration,omitempty"`
+	EndAt        int64  `json:"end_at,omitempty"`
+	JWTExpiresAt int64  `json:"jwt_expires_at,omitempty"`
+
+	// TokensRevokedAt revokes every token of the meeting issued until then, see revokeMeetingTokens.
+	TokensRevokedAt int64 `json:"tokens_revoked_at,omitempty"`
+
+	// Lobby makes participants knock before joining, and E2EE enables end-to-end encryption in
+	// the Jitsi clients. The password is only ever sent to channel members in ephemeral posts.
+	Lobby    bool   `json:"lobby,omitempty"`
+	Password string `json:"password,omitempty"`
+	E2EE     bool   `json:"e2ee,omitempty"`
+
+	// Participants are the people who joined the meeting, when the Jitsi server sends its events.
+	Participants []*MeetingParticipant `json:"participants,omitempty"`
+
+	// StatusUserIDs are the users whose custom status was set when they joined the meeting.
+	StatusUserIDs []string `json:"status_user_ids,omitempty"`
+
+	// Rings are the members called when the meeting started in a direct or group message channel,
+	// until RingUntil.
+	Rings     []*MeetingRing `json:"rings,omitempty"`
+	RingUntil int64          `json:"ring_until,omitempty"`
+
+	// Invitations are the users invited to the meeting with /jitsi invite.
+	Invitations []*MeetingInvitation `json:"invitations,omitempty"`
+}
+
+// Sanitize removes the meeting password before the meeting is sent to clients.
+func (m *Meeting) Sanitize() {
+	m.Password = ""
+}
+
+// StartTime returns the planned start of a scheduled meeting, or the creation time of a meeting
+// started on demand.
+func (m *Meeting) StartTime() time.Time {
+	if m.StartAt > 0 {
+		return time.UnixMilli(m.StartAt)
+	}
+	return time.UnixMilli(m.CreateAt)
+}
+
+// PlannedDuration returns the expected duration of the meeting.
+func (m *Meeting) PlannedDuration() time.Duration {
+	if m.Duration > 0 {
+		return time.Duration(m.Duration) * time.Millisecond
+	}
+	return defaultScheduledMeetingDuration
+}
+
+// IsExpired reports whether the JWT embedded in the meeting link is no longer valid.
+func (m *Meeting) IsExpired(now time.Time) bool {
+	return m.JWTExpiresAt > 0 && now.UnixMilli() >= m.JWTExpiresAt
+}
+
+// refreshState turns an active meeting whose link already expired into an expired one. The
+// expired state is derived on read, so that issuing a new link brings the meeting back to active.
+func (m *Meeting) refreshState(now time.Time) {
+	if m.State == MeetingStateActive && m.IsExpired(now) {
+		m.State = MeetingStateExpired
+	}
+	if m.State == MeetingStateExpired && !m.IsExpired(now) {
+		m.State = MeetingStateActive
+	}
+}
+
+func meetingRoomKey(room string) string {
+	// Room names are built from user provided topics and may exceed the maximum key length.
+	hash := sha256.Sum256([]byte(room))
+	return meetingRoomKeyPrefix + hex.EncodeToString(hash[:16])
+}
+
+// createMeeting stores a new meeting and adds it to the channel, user and room indexes.
+func (p *Plugin) createMeeting(meeting *Meeting) error {
+	if meeting.ID == "" {
+		meeting.ID = model.NewId()
+	}
+	if meeting.CreateAt == 0 {
+		meeting.CreateAt = model.GetMillis()
+	}
+	if meeting.State == "" {
+		meeting.State = MeetingStateActive
+	}
+
+	if err := p.saveMeeting(meeting); err != nil {
+		return err
+	}
+
+	if appErr := p.API.KVSet(meetingRoomKey(meeting.Room), []byte(meeting.ID)); appErr != nil {
+		return appErr
+	}
+	// The Jitsi server reports rooms in lower case in its events.
+	if room := strings.ToLower(meeting.Room); room != meeting.Room {
+		if appErr := p.API.KVSet(meetingRoomKey(room), []byte(meeting.ID)); appErr != nil {
+			return appErr
+		}
+	}
+
+	if err := p.addToMeetingIndex(channelMeetingsKeyPrefix+meeting.ChannelID, meeting.ID, maxMeetingIndexSize); err != nil {
+		return err
+	}
+
+	if meeting.State == MeetingStateScheduled {
+		if err := p.addToMeetingIndex(scheduledMeetingsKey, meeting.ID, 0); err != nil {
+			return err
+		}
+	}
+
+	return p.addToMeetingIndex(userMeetingsKeyPrefix+meeting.CreatorID, meeting.ID, maxMeetingIndexSize)
+}
+
+func (p *Plugin) saveMeeting(meeting *Meeting) error {
+	b, err := json.Marshal(meeting)
+	if err != nil {
+		return err
+	}
+
+	if appErr := p.API.KVSet(meetingKeyPrefix+meeting.ID, b); appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+func (p *Plugin) getMeeting(meetingID string) (*Meeting, error) {
+	data, appErr := p.API.KVGet(meetingKeyPrefix + meetingID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if data == nil {
+		return nil, errMeetingNotFound
+	}
+
+	var meeting Meeting
+	if err := json.Unmarshal(data, &meeting); err != nil {
+		return nil, err
+	}
+	meeting.refreshState(time.Now())
+
+	return &meeting, nil
+}
+
+// getMeetingByRoom returns the most recent meeting started in the given Jitsi room.
+func (p *Plugin) getMeetingByRoom(room string) (*Meeting, error) {
+	data, appErr := p.API.KVGet(meetingRoomKey(room))
+	if appErr != nil {
+		return nil, appErr
+	}
+	if data == nil {
+		return nil, errMeetingNotFound
+	}
+
+	return p.getMeeting(string(data))
+}
+
+// updateMeeting atomically applies update to the stored meeting and returns the updated record.
+func (p *Plugin) updateMeeting(meetingID string, update func(meeting *Meeting) error) (*Meeting, error) {
+	var meeting Meeting
+	err := p.atomicKVUpdate(meetingKeyPrefix+meetingID, func(data []byte) ([]byte, error) {
+		if data == nil {
+			return nil, errMeetingNotFound
+		}
+
+		meeting = Meeting{}
+		if err := json.Unmarshal(data, &meeting); err != nil {
+			return nil, err
+		}
+		meeting.refreshState(time.Now())
+
+		if err := update(&meeting); err != nil {
+			return nil, err
+		}
+		return json.Marshal(meeting)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &meeting, nil
+}
+
+// addToMeetingIndex prepends a meeting to an index, keeping at most maxSize meetings when maxSize
+// is positive.
+func (p *Plugin) addToMeetingIndex(key string, meetingID string, maxSize int) error {
+	return p.atomicKVUpdate(key, func(data []byte) ([]byte, error) {
+		var ids []string
+		if data != nil {
+			if err := json.Unmarshal(data, &ids); err != nil {
+				return nil, err
+			}
+		}
+
+		ids = append([]string{meetingID}, ids...)
+		if maxSize > 0 && len(ids) > maxSize {
+			ids = ids[:maxSize]
+		}
+		return json.Marshal(ids)
+	})
+}
+
+// removeFromMeetingIndex removes a meeting from an index.
+func (p *Plugin) removeFromMeetingIndex(key string, meetingID string) error {
+	return p.atomicKVUpdate(key, func(data []byte) ([]byte, error) {
+		var ids []string
+		if data != nil {
+			if err := json.Unmarshal(data, &ids); err != nil {
+				return nil, err
+			}
+		}
+
+		remaining := []string{}
+		for _, id := range ids {
+			if id != meetingID {
+				remaining = append(remaining, id)
+			}
+		}
+		return json.Marshal(remaining)
+	})
+}
+
+// getMeetingIndex returns the IDs of an index, newest first.
+func (p *Plugin) getMeetingIndex(key string) ([]string, error) {
+	data, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if data == nil {
+		return []string{}, nil
+	}
+
+	var ids []string
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// getIndexedMeetings loads the meetings of an index, newest first, keeping only the ones matching
+// filter when it is not nil. A limit of 0 returns every matching meeting.
+func (p *Plugin) getIndexedMeetings(key string, limit int, filter func(meeting *Meeting) bool) ([]*Meeting, error) {
+	ids, err := p.getMeetingIndex(key)
+	if err != nil {
+		return nil, err
+	}
+
+	meetings := []*Meeting{}
+	for _, id := range ids {
+		if limit > 0 && len(meetings) >= limit {
+			break
+		}
+
+		meeting, err := p.getMeeting(id)
+		if errors.Is(err, errMeetingNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if filter != nil && !filter(meeting) {
+			continue
+		}
+		meetings = append(meetings, meeting)
+	}
+
+	return meetings, nil
+}
+
+// getChannelMeetings returns the most recent meetings of a channel, newest first. A limit of 0
+// returns every indexed meeting.
+func (p *Plugin) getChannelMeetings(channelID string, limit int) ([]*Meeting, error) {
+	return p.getIndexedMeetings(channelMeetingsKeyPrefix+channelID, limit, nil)
+}
+
+// getUserMeetings returns the most recent meetings created by a user, newest first. A limit of 0
+// returns every indexed meeting.
+func (p *Plugin) getUserMeetings(userID string, limit int) ([]*Meeting, error) {
+	return p.getIndexedMeetings(userMeetingsKeyPrefix+userID, limit, nil)
+}
+
+// getRunningChannelMeeting returns the most recent meeting of a channel that has not ended yet.
+func (p *Plugin) getRunningChannelMeeting(channelID string) (*Meeting, error) {
+	meetings, err := p.getChannelMeetings(channelID, runningMeetingLookup)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, meeting := range meetings {
+		if meeting.State == MeetingStateActive || meeting.State == MeetingStateExpired {
+			return meeting, nil
+		}
+	}
+
+	return nil, errMeetingNotFound
+}
+
+// getChannelMeetingHistory returns the most recent meetings started in a channel, newest first,
+// provided the user is a member of the channel.
+func (p *Plugin) getChannelMeetingHistory(channelID string, userID string, limit int) ([]*Meeting, error) {
+	if _, appErr := p.API.GetChannelMember(channelID, userID); appErr != nil {
+		return nil, errNotChannelMember
+	}
+
+	return p.getIndexedMeetings(channelMeetingsKeyPrefix+channelID, limit, func(meeting *Meeting) bool {
+		return meeting.State != MeetingStateScheduled
+	})
+}
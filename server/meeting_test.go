@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockKVStore backs the KV methods of apiMock with an in-memory map for every key starting with
+// one of the given prefixes.
+func mockKVStore(apiMock *plugintest.API, prefixes ...string) map[string][]byte {
+	store := map[string][]byte{}
+	matchKey := mock.MatchedBy(func(key string) bool {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		}
+		return false
+	})
+
+	apiMock.On("KVGet", matchKey).Return(
+		func(key string) []byte { return store[key] },
+		func(string) *model.AppError { return nil },
+	).Maybe()
+	apiMock.On("KVSet", matchKey, mock.Anything).Return(func(key string, value []byte) *model.AppError {
+		store[key] = value
+		return nil
+	}).Maybe()
+	apiMock.On("KVSetWithExpiry", matchKey, mock.Anything, mock.Anything).Return(func(key string, value []byte, _ int64) *model.AppError {
+		store[key] = value
+		return nil
+	}).Maybe()
+	apiMock.On("KVDelete", matchKey).Return(func(key string) *model.AppError {
+		delete(store, key)
+		return nil
+	}).Maybe()
+	apiMock.On("KVCompareAndSet", matchKey, mock.Anything, mock.Anything).Return(
+		func(key string, oldValue, newValue []byte) (bool, *model.AppError) {
+			current, ok := store[key]
+			if (oldValue == nil && ok) || (oldValue != nil && !bytes.Equal(current, oldValue)) {
+				return false, nil
+			}
+			if newValue == nil {
+				delete(store, key)
+			} else {
+				store[key] = newValue
+			}
+			return true, nil
+		},
+	).Maybe()
+	apiMock.On("KVSetWithOptions", matchKey, mock.Anything, mock.Anything).Return(
+		func(key string, value []byte, options model.PluginKVSetOptions) (bool, *model.AppError) {
+			current, ok := store[key]
+			if options.Atomic && ((options.OldValue == nil && ok) || (options.OldValue != nil && !bytes.Equal(current, options.OldValue))) {
+				return false, nil
+			}
+			if value == nil {
+				delete(store, key)
+			} else {
+				store[key] = value
+			}
+			return true, nil
+		},
+	).Maybe()
+
+	return store
+}
+
+func mockMeetingStore(apiMock *plugintest.API) map[string][]byte {
+	return mockKVStore(apiMock, meetingKeyPrefix, channelMeetingsKeyPrefix, userMeetingsKeyPrefix, scheduledMeetingsKey,
+		recurringMeetingKeyPrefix, channelRecurringMeetingsKeyPrefix, recurringMeetingsKey,
+		issuedTokenKeyPrefix, meetingTokensKeyPrefix, channelConfigKeyPrefix)
+}
+
+func TestMeetingRefreshState(t *testing.T) {
+	now := time.Now()
+
+	t.Run("active meeting without JWT never expires", func(t *testing.T) {
+		meeting := Meeting{State: MeetingStateActive}
+		meeting.refreshState(now)
+		require.Equal(t, MeetingStateActive, meeting.State)
+	})
+
+	t.Run("active meeting with expired JWT is expired", func(t *testing.T) {
+		meeting := Meeting{State: MeetingStateActive, JWTExpiresAt: now.Add(-time.Minute).UnixMilli()}
+		meeting.refreshState(now)
+		require.Equal(t, MeetingStateExpired, meeting.State)
+	})
+
+	t.Run("expired meeting with a new JWT is active again", func(t *testing.T) {
+		meeting := Meeting{State: MeetingStateExpired, JWTExpiresAt: now.Add(time.Minute).UnixMilli()}
+		meeting.refreshState(now)
+		require.Equal(t, MeetingStateActive, meeting.State)
+	})
+
+	t.Run("ended meeting stays ended", func(t *testing.T) {
+		meeting := Meeting{State: MeetingStateEnded, JWTExpiresAt: now.Add(-time.Minute).UnixMilli()}
+		meeting.refreshState(now)
+		require.Equal(t, MeetingStateEnded, meeting.State)
+	})
+}
+
+func TestMeetingStore(t *testing.T) {
+	p := Plugin{}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	p.SetAPI(&apiMock)
+	mockMeetingStore(&apiMock)
+
+	first := &Meeting{Room: "first-room", ChannelID: "test-channel", CreatorID: "test-user", Topic: "First"}
+	require.Nil(t, p.createMeeting(first))
+	require.NotEmpty(t, first.ID)
+	require.NotZero(t, first.CreateAt)
+	require.Equal(t, MeetingStateActive, first.State)
+
+	second := &Meeting{Room: "second-room", ChannelID: "test-channel", CreatorID: "other-user", Topic: "Second"}
+	require.Nil(t, p.createMeeting(second))
+
+	t.Run("get meeting by id", func(t *testing.T) {
+		meeting, err := p.getMeeting(first.ID)
+		require.Nil(t, err)
+		require.Equal(t, first, meeting)
+	})
+
+	t.Run("get unknown meeting", func(t *testing.T) {
+		meeting, err := p.getMeeting("unknown")
+		require.ErrorIs(t, err, errMeetingNotFound)
+		require.Nil(t, meeting)
+	})
+
+	t.Run("get meeting by room", func(t *testing.T) {
+		meeting, err := p.getMeetingByRoom("second-room")
+		require.Nil(t, err)
+		require.Equal(t, second, meeting)
+	})
+
+	t.Run("channel index is sorted newest first", func(t *testing.T) {
+		meetings, err := p.getChannelMeetings("test-channel", 0)
+		require.Nil(t, err)
+		require.Equal(t, []*Meeting{second, first}, meetings)
+
+		meetings, err = p.getChannelMeetings("test-channel", 1)
+		require.Nil(t, err)
+		require.Equal(t, []*Meeting{second}, meetings)
+	})
+
+	t.Run("user index only contains the user meetings", func(t *testing.T) {
+		meetings, err := p.getUserMeetings("test-user", 0)
+		require.Nil(t, err)
+		require.Equal(t, []*Meeting{first}, meetings)
+
+		meetings, err = p.getUserMeetings("unknown-user", 0)
+		require.Nil(t, err)
+		require.Empty(t, meetings)
+	})
+
+	t.Run("update meeting", func(t *testing.T) {
+		updated, err := p.updateMeeting(first.ID, func(meeting *Meeting) error {
+			meeting.State = MeetingStateEnded
+			meeting.EndAt = 1234
+			return nil
+		})
+		require.Nil(t, err)
+		require.Equal(t, MeetingStateEnded, updated.State)
+
+		meeting, err := p.getMeeting(first.ID)
+		require.Nil(t, err)
+		require.Equal(t, updated, meeting)
+	})
+
+	t.Run("update unknown meeting", func(t *testing.T) {
+		_, err := p.updateMeeting("unknown", func(*Meeting) error { return nil })
+		require.ErrorIs(t, err, errMeetingNotFound)
+	})
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/plugin"
 	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
 	"github.com/mattermost/mattermost/server/public/pluginapi/experimental/telemetry"
 	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
 	"github.com/mattermost/mattermost/server/public/shared/mlog"
@@ -25,11 +26,31 @@ const jitsiNameSchemeWords = "words"
 const jitsiNameSchemeUUID = "uuid"
 const jitsiNameSchemeMattermost = "mattermost"
 const configChangeEvent = "config_update"
+const jitsiBotUsername = "jitsi"
+const channelMembersPerPage = 200
 
+// UserConfig holds the settings a user saved with /jitsi settings. Unset fields follow the
+// settings of the channel, then the system configuration, see resolveUserConfig.
 type UserConfig struct {
-	NamingScheme    string `json:"naming_scheme"`
-	Embedded        bool   `json:"embedded"`
-	ShowPrejoinPage bool   `json:"show_prejoin_page"`
+	NamingScheme    *string `json:"naming_scheme,omitempty"`
+	Embedded        *bool   `json:"embedded,omitempty"`
+	ShowPrejoinPage *bool   `json:"show_prejoin_page,omitempty"`
+
+	// StartWithAudioMuted, StartWithVideoMuted and StartAudioOnly override the system-wide
+	// defaults when set.
+	StartWithAudioMuted *bool `json:"start_with_audio_muted,omitempty"`
+	StartWithVideoMuted *bool `json:"start_with_video_muted,omitempty"`
+	StartAudioOnly      *bool `json:"start_audio_only,omitempty"`
+
+	// InCallStatus sets the custom status of the user while they are in a meeting.
+	InCallStatus bool `json:"in_call_status"`
+}
+
+// UserConfigResponse is the user config sent to the webapp. UserSettings lists the settings the
+// user saved, which take precedence over the settings of the channels.
+type UserConfigResponse struct {
+	*UserConfig
+	UserSettings []string `json:"user_settings"`
 }
 
 type Plugin struct {
@@ -50,6 +71,15 @@ type Plugin struct {
 	b *i18n.Bundle
 
 	botID string
+
+	schedulerJob *cluster.Job
+
+	// ringJobLock synchronizes starting and stopping the ring job as the configuration changes.
+	ringJobLock sync.Mutex
+	ringJob     *cluster.Job
+
+	// passwordSends tracks the meeting passwords being sent to channel members in the background.
+	passwordSends sync.WaitGroup
 }
 
 func (p *Plugin) OnActivate() error {
@@ -74,7 +104,7 @@ func (p *Plugin) OnActivate() error {
 	p.b = i18nBundle
 
 	jitsiBot := &model.Bot{
-		Username:    "jitsi",
+		Username:    jitsiBotUsername,
 		DisplayName: "Jitsi",
 		Description: "A bot account created by the jitsi plugin",
 	}
@@ -95,7 +125,10 @@ func (p *Plugin) OnActivate() error {
 		p.API.LogWarn("telemetry client not started", "error", err.Error())
 	}
 
-	return nil
+	if err = p.startScheduler(); err != nil {
+		return err
+	}
+	return p.updateRingJob()
 }
 
 type User struct {
@@ -103,11 +136,20 @@ type User struct {
 	Name   string `json:"name"`
 	Email  string `json:"email"`
 	ID     string `json:"id"`
+
+	// Moderator and Affiliation grant moderator rights in the Jitsi room, through the
+	// token_moderation and token_affiliation Prosody modules respectively.
+	Moderator   bool   `json:"moderator"`
+	Affiliation string `json:"affiliation,omitempty"`
+
+	// LobbyBypass lets the user in without knocking when the meeting has a lobby.
+	LobbyBypass bool `json:"lobby_bypass,omitempty"`
 }
 
 type Context struct {
-	User  User   `json:"user"`
-	Group string `json:"group"`
+	User     User              `json:"user"`
+	Group    string            `json:"group"`
+	Features map[string]string `json:"features,omitempty"`
 }
 
 type EnrichMeetingJwtRequest struct {
@@ -177,40 +219,114 @@ func (p *Plugin) deleteEphemeralPost(userID, postID string) {
 	p.API.DeleteEphemeralPost(userID, postID)
 }
 
+// updateJwtUserInfo re-issues a meeting token for the user. Revoked tokens are rejected, and the
+// tokens of a meeting still running are replaced by a fresh one, so that posts whose token expired
+// are not a dead end.
 func (p *Plugin) updateJwtUserInfo(jwtToken string, user *model.User) (string, error) {
-	secret := p.getConfiguration().JitsiAppSecret
-	sanitizedUser := user.DeepCopy()
+	claims, err := p.verifyToken(jwtToken)
+	if err != nil {
+		return "", err
+	}
+
+	if claims.ID != "" {
+		issued, err := p.getIssuedToken(claims.ID)
+		if err != nil && !errors.Is(err, errTokenNotFound) {
+			return "", err
+		}
+		if issued != nil && issued.RevokedAt > 0 {
+			return "", errTokenRevoked
+		}
+	}
 
-	claims, err := verifyJwt(secret, jwtToken)
+	meeting, err := p.getMeetingByRoom(claims.Room)
+	if errors.Is(err, errMeetingNotFound) {
+		// Tokens of meetings started before the meeting records existed have no known creator.
+		claims.Context.User = p.meetingJWTUser(user, nil)
+		return p.signMeetingClaims(claims, user.Id, "")
+	}
 	if err != nil {
 		return "", err
 	}
 
-	config := p.API.GetConfig()
-	if config.PrivacySettings.ShowFullName == nil || !*config.PrivacySettings.ShowFullName {
-		sanitizedUser.FirstName = ""
-		sanitizedUser.LastName = ""
+	if !p.canJoinMeeting(user.Id, meeting) {
+		return "", errNotChannelMember
 	}
-	if config.PrivacySettings.ShowEmailAddress == nil || !*config.PrivacySettings.ShowEmailAddress {
-		sanitizedUser.Email = ""
+
+	token, _, err := p.issueMeetingToken(user, meeting)
+	if err != nil {
+		return "", err
 	}
-	newContext := Context{
-		User: User{
-			Avatar: fmt.Sprintf("%s/api/v4/users/%s/image?_=%d", *config.ServiceSettings.SiteURL, sanitizedUser.Id, sanitizedUser.LastPictureUpdate),
-			Name:   sanitizedUser.GetDisplayName(model.ShowNicknameFullName),
-			Email:  sanitizedUser.Email,
-			ID:     sanitizedUser.Id,
-		},
-		Group: claims.Context.Group,
+
+	// The clients ask for the token of the user right before they join the meeting.
+	if err = p.setInCallStatus(user, meeting); err != nil {
+		mlog.Warn("Unable to set the in-call custom status", mlog.String("user_id", user.Id), mlog.Err(err))
 	}
+	return token, nil
+}
+
+// startMeetingOptions customizes the meeting created by startMeetingWithOptions.
+type startMeetingOptions struct {
+	MeetingID string
+	Topic     string
+	RootID    string
+
+	// ScheduledMeetingID is the ID of a scheduled meeting record to activate instead of creating
+	// a new record.
+	ScheduledMeetingID string
 
-	claims.Context = newContext
+	// AsBot makes the Jitsi bot the author of the meeting post instead of the user.
+	AsBot bool
+
+	// Lobby, Password and E2EE restrict the access to the meeting, see Meeting.
+	Lobby    bool
+	Password string
+	E2EE     bool
+
+	// Unlock releases the lock of lockMeetingStart once the meeting record exists, before the
+	// channel members are sent the password or called.
+	Unlock func()
+}
 
-	return signClaims(secret, claims)
+// explicit reports whether the user asked for a specific meeting, which is started even when
+// another meeting is running in the channel.
+func (o startMeetingOptions) explicit() bool {
+	return o.Topic != "" || o.Lobby || o.Password != "" || o.E2EE
+}
+
+// getMeetingLink returns the URL of a Jitsi room, without any JWT or configuration.
+func (p *Plugin) getMeetingLink(room string) string {
+	jitsiURL := strings.TrimSpace(p.getConfiguration().GetJitsiURL())
+	jitsiURL = strings.TrimRight(jitsiURL, "/")
+	if tenant := p.getConfiguration().JitsiJWTTenant; tenant != "" {
+		// Multi-tenant deployments serve the rooms of a tenant under its path.
+		jitsiURL += "/" + url.PathEscape(tenant)
+	}
+	return jitsiURL + "/" + room
 }
 
 func (p *Plugin) startMeeting(user *model.User, channel *model.Channel, meetingID string, meetingTopic string, _ bool, rootID string) (string, error) {
+	meeting, err := p.startMeetingWithOptions(user, channel, startMeetingOptions{
+		MeetingID: meetingID,
+		Topic:     meetingTopic,
+		RootID:    rootID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return meeting.Room, nil
+}
+
+func (p *Plugin) startMeetingWithOptions(user *model.User, channel *model.Channel, options startMeetingOptions) (*Meeting, error) {
+	channelConfig, err := p.getChannelConfig(channel.Id)
+	if err != nil {
+		return nil, err
+	}
+
 	l := p.b.GetServerLocalizer()
+	meetingID := options.MeetingID
+	meetingTopic := options.Topic
+	rootID := options.RootID
 	if meetingID == "" {
 		meetingID = encodeJitsiMeetingID(meetingTopic)
 		if meetingID != "" {
@@ -224,13 +340,21 @@ func (p *Plugin) startMeeting(user *model.User, channel *model.Channel, meetingI
 		Other: "Jitsi Meeting",
 	})
 
-	if len(meetingTopic) < 1 {
-		userConfig, err := p.getUserConfig(user.Id)
+	switch {
+	case len(meetingTopic) < 1 && channelConfig.RoomName != "":
+		meetingID = channelConfig.RoomName
+	case options.MeetingID == "" && p.usesPermanentRoom(channelConfig):
+		// Topics only change the subject of the meetings of the permanent room.
+		if meetingID, err = p.getChannelRoom(channel); err != nil {
+			return nil, err
+		}
+	case len(meetingTopic) < 1:
+		userConfig, _, err := p.resolveUserConfig(user.Id, channel.Id)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 
-		switch userConfig.NamingScheme {
+		switch *userConfig.NamingScheme {
 		case jitsiNameSchemeWords:
 			meetingID = generateEnglishTitleName()
 		case jitsiNameSchemeUUID:
@@ -249,7 +373,7 @@ func (p *Plugin) startMeeting(user *model.User, channel *model.Channel, meetingI
 			} else {
 				team, teamErr := p.API.GetTeam(channel.TeamId)
 				if teamErr != nil {
-					return "", teamErr
+					return nil, teamErr
 				}
 				meetingTopic = p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
 					DefaultMessage: &i18n.Message{
@@ -264,52 +388,180 @@ func (p *Plugin) startMeeting(user *model.User, channel *model.Channel, meetingI
 			meetingID = generateEnglishTitleName()
 		}
 	}
-	jitsiURL := strings.TrimSpace(p.getConfiguration().GetJitsiURL())
-	jitsiURL = strings.TrimRight(jitsiURL, "/")
-	meetingURL := jitsiURL + "/" + meetingID
-	meetingLink := meetingURL
+	meetingLink := p.getMeetingLink(meetingID)
 
-	var meetingLinkValidUntil = time.Time{}
+	// With JWT authentication the post only carries the room, every participant then requests
+	// their own short-lived token to join.
 	JWTMeeting := p.getConfiguration().JitsiJWT
-	var jwtToken string
 
+	slackMeetingTopic := meetingTopic
+	if slackMeetingTopic == "" {
+		slackMeetingTopic = defaultMeetingTopic
+	}
+
+	// The post identifies the meeting record: rooms are reused by permanent and recurring meetings.
+	recordID := options.ScheduledMeetingID
+	if recordID == "" {
+		recordID = model.NewId()
+	}
+	meeting := &Meeting{
+		ID:        recordID,
+		Room:      meetingID,
+		ChannelID: channel.Id,
+		RootID:    rootID,
+		CreatorID: user.Id,
+		Topic:     slackMeetingTopic,
+		Personal:  meetingPersonal,
+		State:     MeetingStateActive,
+		Lobby:     options.Lobby || (channelConfig.Lobby != nil && *channelConfig.Lobby),
+		Password:  options.Password,
+		E2EE:      options.E2EE,
+	}
 	if JWTMeeting {
-		// Error check is done in configuration.IsValid()
-		jURL, _ := url.Parse(p.getConfiguration().GetJitsiURL())
-
-		meetingLinkValidUntil = time.Now().Add(time.Duration(p.getConfiguration().JitsiLinkValidTime) * time.Minute)
-
-		claims := Claims{}
-		claims.Issuer = p.getConfiguration().JitsiAppID
-		claims.Audience = []string{p.getConfiguration().JitsiAppID}
-		claims.ExpiresAt = jwt.NewNumericDate(meetingLinkValidUntil)
-		claims.Subject = jURL.Hostname()
-		claims.Room = meetingID
-
-		var err2 error
-		jwtToken, err2 = signClaims(p.getConfiguration().JitsiAppSecret, &claims)
-		if err2 != nil {
-			return "", err2
-		}
+		// Participants extend this as they request their tokens.
+		meeting.JWTExpiresAt = time.Now().Add(time.Duration(p.getConfiguration().JitsiLinkValidTime) * time.Minute).UnixMilli()
+	}
+
+	postUserID := user.Id
+	if options.AsBot {
+		postUserID = p.botID
+	}
+
+	// The clients add the settings of the user to the meeting configuration.
+	urlConfigHash := p.meetingURLConfig(meeting, channelConfig, nil).Hash()
+
+	post := &model.Post{
+		UserId:    postUserID,
+		ChannelId: channel.Id,
+		Type:      "custom_jitsi",
+		Props: map[string]interface{}{
+			"attachments":           []*model.SlackAttachment{p.meetingAttachment(meeting, urlConfigHash)},
+			"meeting_id":            meetingID,
+			"meeting_record_id":     meeting.ID,
+			"meeting_link":          meetingLink,
+			"jwt_meeting":           JWTMeeting,
+			"meeting_personal":      meetingPersonal,
+			"meeting_topic":         meetingTopic,
+			"default_meeting_topic": defaultMeetingTopic,
+		},
+		RootId: rootID,
+	}
+	if tenant := p.getConfiguration().JitsiJWTTenant; tenant != "" {
+		// The embedded meeting joins the room of the tenant.
+		post.AddProp("meeting_tenant", tenant)
+	}
+	post.AddProp("meeting_url_config", urlConfigHash)
+	// Users who did not save their own settings follow the settings of the channel.
+	if channelConfig.Embedded != nil {
+		post.AddProp("meeting_embedded", *channelConfig.Embedded)
+	}
+	if channelConfig.ShowPrejoinPage != nil {
+		post.AddProp("meeting_show_prejoin_page", *channelConfig.ShowPrejoinPage)
+	}
+	if meeting.Lobby {
+		post.AddProp("meeting_lobby", true)
+	}
+	if meeting.E2EE {
+		post.AddProp("meeting_e2ee", true)
+	}
+	if meeting.Password != "" {
+		// The password itself is only sent to the channel members in ephemeral posts.
+		post.AddProp("meeting_password_protected", true)
+	}
 
-		meetingURL = meetingURL + "?jwt=" + jwtToken
+	createdPost, appErr := p.API.CreatePost(post)
+	if appErr != nil {
+		return nil, appErr
 	}
-	if meetingTopic == "" {
-		meetingURL = meetingURL + "#config.callDisplayName=" + url.PathEscape("\""+defaultMeetingTopic+"\"")
+	meeting.PostID = createdPost.Id
+
+	if options.ScheduledMeetingID != "" {
+		var scheduledMeeting *Meeting
+		scheduledMeeting, err = p.updateMeeting(options.ScheduledMeetingID, func(scheduled *Meeting) error {
+			scheduled.State = MeetingStateActive
+			scheduled.PostID = meeting.PostID
+			scheduled.JWTExpiresAt = meeting.JWTExpiresAt
+			return nil
+		})
+		if err == nil {
+			meeting = scheduledMeeting
+		}
 	} else {
-		meetingURL = meetingURL + "#config.callDisplayName=" + url.PathEscape("\""+meetingTopic+"\"")
+		err = p.createMeeting(meeting)
+	}
+	if err != nil {
+		// The meeting post is already visible, so the meeting is still usable without its record.
+		mlog.Error("Error storing the meeting record", mlog.String("meeting_id", meetingID), mlog.Err(err))
+	}
+	if options.Unlock != nil {
+		options.Unlock()
 	}
 
-	meetingUntil := ""
-	if JWTMeeting {
-		meetingUntil = p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+	if meeting.Password != "" {
+		// Large channels take a while, the request starting the meeting does not wait for them.
+		p.passwordSends.Add(1)
+		go func(meeting Meeting) {
+			defer p.passwordSends.Done()
+			p.sendMeetingPasswordToChannel(&meeting)
+		}(*meeting)
+	}
+
+	if err == nil && p.getConfiguration().JitsiRingDirectMessages && (channel.Type == model.ChannelTypeDirect || channel.Type == model.ChannelTypeGroup) {
+		if ringErr := p.ringChannelMembers(user, channel, meeting); ringErr != nil {
+			mlog.Warn("Unable to call the channel members", mlog.String("meeting_id", meeting.ID), mlog.Err(ringErr))
+		}
+	}
+
+	return meeting, nil
+}
+
+// sendMeetingPassword sends the password of a meeting to a user in an ephemeral post, so that it
+// never appears in the channel.
+func (p *Plugin) sendMeetingPassword(meeting *Meeting, userID string) {
+	l := p.b.GetUserLocalizer(userID)
+	p.API.SendEphemeralPost(userID, &model.Post{
+		UserId:    p.botID,
+		ChannelId: meeting.ChannelID,
+		RootId:    meeting.RootID,
+		Message: p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
 			DefaultMessage: &i18n.Message{
-				ID:    "jitsi.start_meeting.meeting_link_valid_until",
-				Other: "Meeting link valid until: {{.Datetime}}",
+				ID:    "jitsi.start_meeting.password",
+				Other: "The password of the meeting `{{.MeetingID}}` is `{{.Password}}`. Please do not share it outside of this channel.",
 			},
-			TemplateData: map[string]string{"Datetime": meetingLinkValidUntil.Format("Mon Jan 2 15:04:05 -0700 MST 2006")},
-		})
+			TemplateData: map[string]string{
+				"MeetingID": meeting.Room,
+				"Password":  meeting.Password,
+			},
+		}),
+	})
+}
+
+// sendMeetingPasswordToChannel sends the password of a meeting to every member of its channel.
+// Members who were not online can get it later with the "Show password" action.
+func (p *Plugin) sendMeetingPasswordToChannel(meeting *Meeting) {
+	for page := 0; ; page++ {
+		members, appErr := p.API.GetChannelMembers(meeting.ChannelID, page, channelMembersPerPage)
+		if appErr != nil {
+			mlog.Warn("Unable to get the channel members to send the meeting password", mlog.String("meeting_id", meeting.ID), mlog.Err(appErr))
+			return
+		}
+		for _, member := range members {
+			p.sendMeetingPassword(meeting, member.UserId)
+		}
+		if len(members) < channelMembersPerPage {
+			return
+		}
 	}
+}
+
+// meetingAttachment builds the attachment of a meeting post, shown by the clients not rendering
+// the custom post type. With JWT authentication, its link carries no token, so it gets a "Refresh
+// link" action sending the user a link with their own token. Password protected meetings get a
+// "Show password" action instead of showing the password.
+func (p *Plugin) meetingAttachment(meeting *Meeting, urlConfigHash string) *model.SlackAttachment {
+	l := p.b.GetServerLocalizer()
+	room := meeting.Room
+	meetingURL := p.getMeetingLink(room) + urlConfigHash
 
 	meetingTypeString := p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
 		DefaultMessage: &i18n.Message{
@@ -317,7 +569,7 @@ func (p *Plugin) startMeeting(user *model.User, channel *model.Channel, meetingI
 			Other: "Meeting ID",
 		},
 	})
-	if meetingPersonal {
+	if meeting.Personal {
 		meetingTypeString = p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
 			DefaultMessage: &i18n.Message{
 				ID:    "jitsi.start_meeting.personal_meeting_id",
@@ -326,12 +578,7 @@ func (p *Plugin) startMeeting(user *model.User, channel *model.Channel, meetingI
 		})
 	}
 
-	slackMeetingTopic := meetingTopic
-	if slackMeetingTopic == "" {
-		slackMeetingTopic = defaultMeetingTopic
-	}
-
-	slackAttachment := model.SlackAttachment{
+	attachment := &model.SlackAttachment{
 		Fallback: p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
 			DefaultMessage: &i18n.Message{
 				ID: "jitsi.start_meeting.fallback_text",
@@ -340,11 +587,11 @@ func (p *Plugin) startMeeting(user *model.User, channel *model.Channel, meetingI
 [Join Meeting]({{.MeetingURL}})`,
 			},
 			TemplateData: map[string]string{
-				"MeetingID":  meetingID,
+				"MeetingID":  room,
 				"MeetingURL": meetingURL,
 			},
-		}) + "\n\n" + meetingUntil,
-		Title: slackMeetingTopic,
+		}),
+		Title: meeting.Topic,
 		Text: p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
 			DefaultMessage: &i18n.Message{
 				ID: "jitsi.start_meeting.slack_attachment_text",
@@ -354,35 +601,225 @@ func (p *Plugin) startMeeting(user *model.User, channel *model.Channel, meetingI
 			},
 			TemplateData: map[string]string{
 				"MeetingType": meetingTypeString,
-				"MeetingID":   meetingID,
+				"MeetingID":   room,
 				"MeetingURL":  meetingURL,
 			},
-		}) + "\n\n" + meetingUntil,
+		}),
 	}
 
-	post := &model.Post{
-		UserId:    user.Id,
-		ChannelId: channel.Id,
-		Type:      "custom_jitsi",
-		Props: map[string]interface{}{
-			"attachments":             []*model.SlackAttachment{&slackAttachment},
-			"meeting_id":              meetingID,
-			"meeting_link":            meetingLink,
-			"jwt_meeting":             JWTMeeting,
-			"meeting_jwt":             jwtToken,
-			"jwt_meeting_valid_until": meetingLinkValidUntil.Unix(),
-			"meeting_personal":        meetingPersonal,
-			"meeting_topic":           meetingTopic,
-			"default_meeting_topic":   defaultMeetingTopic,
-		},
-		RootId: rootID,
+	if present := len(meeting.PresentParticipants()); present > 0 {
+		attachment.Text += "\n\n" + p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.start_meeting.participants",
+				One:   "{{.Count}} person in the call",
+				Other: "{{.Count}} people in the call",
+			},
+			TemplateData: map[string]int{"Count": present},
+			PluralCount:  present,
+		})
+	}
+
+	apiURL := *p.API.GetConfig().ServiceSettings.SiteURL + "/plugins/jitsi/api/v1/meetings/" + url.PathEscape(meeting.ID)
+	if p.getConfiguration().JitsiJWT {
+		attachment.Actions = append(attachment.Actions, &model.PostAction{
+			Id:   "refreshlink",
+			Type: model.PostActionTypeButton,
+			Name: p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "jitsi.start_meeting.refresh_link",
+					Other: "Refresh link",
+				},
+			}),
+			Integration: &model.PostActionIntegration{
+				URL: apiURL + "/refresh",
+			},
+		})
+	}
+	if meeting.Password != "" {
+		attachment.Actions = append(attachment.Actions, &model.PostAction{
+			Id:   "showpassword",
+			Type: model.PostActionTypeButton,
+			Name: p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "jitsi.start_meeting.show_password",
+					Other: "Show password",
+				},
+			}),
+			Integration: &model.PostActionIntegration{
+				URL: apiURL + "/password",
+			},
+		})
 	}
 
-	if _, err := p.API.CreatePost(post); err != nil {
+	return attachment
+}
+
+// meetingJoinURL returns the link of a user to join a meeting, with their own token when JWT
+// authentication is enabled.
+func (p *Plugin) meetingJoinURL(userID string, meeting *Meeting) (string, error) {
+	userConfig, channelConfig, err := p.resolveUserConfig(userID, meeting.ChannelID)
+	if err != nil {
 		return "", err
 	}
+	if !p.getConfiguration().JitsiJWT {
+		return p.getMeetingLink(meeting.Room) + p.meetingURLConfig(meeting, channelConfig, userConfig).Hash(), nil
+	}
 
-	return meetingID, nil
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		return "", appErr
+	}
+	token, _, err := p.issueMeetingToken(user, meeting)
+	if err != nil {
+		return "", err
+	}
+	return p.getMeetingLink(meeting.Room) + "?jwt=" + url.QueryEscape(token) + p.meetingURLConfig(meeting, channelConfig, userConfig).Hash(), nil
+}
+
+// refreshMeetingPost rebuilds the attachment of a running meeting post in place, along with the
+// participants reported by the Jitsi server. Posts created before participants had their own
+// tokens lose the token they shared, which may have expired.
+func (p *Plugin) refreshMeetingPost(meeting *Meeting) error {
+	if meeting.PostID == "" {
+		return nil
+	}
+
+	post, appErr := p.API.GetPost(meeting.PostID)
+	if appErr != nil {
+		return appErr
+	}
+	if post.GetProp("meeting_ended_at") != nil {
+		return nil
+	}
+
+	urlConfigHash, ok := post.GetProp("meeting_url_config").(string)
+	if !ok {
+		channelConfig, err := p.getChannelConfig(meeting.ChannelID)
+		if err != nil {
+			return err
+		}
+		urlConfigHash = p.meetingURLConfig(meeting, channelConfig, nil).Hash()
+	}
+	post.AddProp("attachments", []*model.SlackAttachment{p.meetingAttachment(meeting, urlConfigHash)})
+	post.AddProp("jwt_meeting", p.getConfiguration().JitsiJWT)
+	post.AddProp("meeting_record_id", meeting.ID)
+	if meeting.Participants != nil {
+		present := meeting.PresentParticipants()
+		var userIDs []string
+		for _, participant := range present {
+			if participant.UserID != "" && len(userIDs) < maxParticipantAvatars {
+				userIDs = append(userIDs, participant.UserID)
+			}
+		}
+		post.AddProp("meeting_participants_count", len(present))
+		post.AddProp("meeting_participant_ids", userIDs)
+	}
+	post.DelProp("meeting_jwt")
+	post.DelProp("jwt_meeting_valid_until")
+	if _, appErr := p.API.UpdatePost(post); appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// canManageMeeting reports whether the user is allowed to end or otherwise administer a meeting:
+// only its creator and the channel, team or system admins are.
+func (p *Plugin) canManageMeeting(userID string, meeting *Meeting) bool {
+	return p.isCreatorOrChannelAdmin(userID, meeting.CreatorID, meeting.ChannelID)
+}
+
+func (p *Plugin) isCreatorOrChannelAdmin(userID string, creatorID string, channelID string) bool {
+	if userID == creatorID {
+		return true
+	}
+	return p.API.HasPermissionToChannel(userID, channelID, model.PermissionManageChannelRoles)
+}
+
+// findMeeting looks a meeting up by its record ID or, failing that, by its Jitsi room name,
+// which is the meeting ID shown to users in the meeting post. A room maps to its latest meeting,
+// so the clients use the record ID, and only posts created before they carried it use the room.
+func (p *Plugin) findMeeting(reference string) (*Meeting, error) {
+	meeting, err := p.getMeeting(reference)
+	if errors.Is(err, errMeetingNotFound) {
+		return p.getMeetingByRoom(reference)
+	}
+	return meeting, err
+}
+
+func (p *Plugin) endMeeting(meetingID string) (*Meeting, error) {
+	meeting, err := p.updateMeeting(meetingID, func(meeting *Meeting) error {
+		if meeting.State == MeetingStateEnded {
+			return errMeetingAlreadyEnded
+		}
+		meeting.State = MeetingStateEnded
+		meeting.EndAt = model.GetMillis()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.clearMeetingCallStatuses(meeting)
+
+	if meeting.PostID == "" {
+		return meeting, nil
+	}
+
+	post, appErr := p.API.GetPost(meeting.PostID)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	l := p.b.GetServerLocalizer()
+	endedAt := time.UnixMilli(meeting.EndAt)
+	// Scheduled meetings are posted a few minutes before their start time and may end early.
+	duration := max(endedAt.Sub(meeting.StartTime()).Round(time.Second), 0)
+
+	meetingTypeString := p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "jitsi.start_meeting.meeting_id",
+			Other: "Meeting ID",
+		},
+	})
+	if meeting.Personal {
+		meetingTypeString = p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.start_meeting.personal_meeting_id",
+				Other: "Personal Meeting ID (PMI)",
+			},
+		})
+	}
+
+	meetingEnded := p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "jitsi.end_meeting.meeting_ended",
+			Other: "Meeting ended at {{.Datetime}} (duration {{.Duration}})",
+		},
+		TemplateData: map[string]string{
+			"Datetime": endedAt.Format("Mon Jan 2 15:04:05 -0700 MST 2006"),
+			"Duration": duration.String(),
+		},
+	})
+
+	slackAttachment := model.SlackAttachment{
+		Fallback: meetingEnded,
+		Title:    meeting.Topic,
+		Text:     fmt.Sprintf("%s: %s\n\n%s", meetingTypeString, meeting.Room, meetingEnded),
+	}
+
+	post.AddProp("attachments", []*model.SlackAttachment{&slackAttachment})
+	post.AddProp("meeting_ended_at", endedAt.Unix())
+	if _, appErr := p.API.UpdatePost(post); appErr != nil {
+		return nil, appErr
+	}
+
+	if p.getConfiguration().JitsiMeetingSummary {
+		if err = p.postMeetingSummary(meeting); err != nil {
+			mlog.Warn("Unable to post the meeting summary", mlog.String("meeting_id", meeting.ID), mlog.Err(err))
+		}
+	}
+
+	return meeting, nil
 }
 
 // MarshalBinary default marshaling to JSON.
@@ -506,18 +943,27 @@ func (p *Plugin) askMeetingType(user *model.User, channel *model.Channel, rootID
 	return nil
 }
 
+// getUserConfig returns the settings saved by a user, with no field set if they never did.
 func (p *Plugin) getUserConfig(userID string) (*UserConfig, error) {
+	userConfig, err := p.getStoredUserConfig(userID)
+	if err != nil {
+		return nil, err
+	}
+	if userConfig == nil {
+		return &UserConfig{}, nil
+	}
+	return userConfig, nil
+}
+
+// getStoredUserConfig returns the settings saved by a user, or nil if they never did.
+func (p *Plugin) getStoredUserConfig(userID string) (*UserConfig, error) {
 	data, appErr := p.API.KVGet("config_" + userID)
 	if appErr != nil {
 		return nil, appErr
 	}
 
 	if data == nil {
-		return &UserConfig{
-			Embedded:        p.getConfiguration().JitsiEmbedded,
-			NamingScheme:    p.getConfiguration().JitsiNamingScheme,
-			ShowPrejoinPage: p.getConfiguration().JitsiPrejoinPage,
-		}, nil
+		return nil, nil
 	}
 
 	var userConfig UserConfig
@@ -529,6 +975,35 @@ func (p *Plugin) getUserConfig(userID string) (*UserConfig, error) {
 	return &userConfig, nil
 }
 
+// applyDefaults sets the fields of a user config left unset to the system configuration.
+func (p *Plugin) applyDefaults(userConfig *UserConfig) {
+	config := p.getConfiguration()
+	if userConfig.NamingScheme == nil {
+		userConfig.NamingScheme = model.NewPointer(config.JitsiNamingScheme)
+	}
+	if userConfig.Embedded == nil {
+		userConfig.Embedded = model.NewPointer(config.JitsiEmbedded)
+	}
+	if userConfig.ShowPrejoinPage == nil {
+		userConfig.ShowPrejoinPage = model.NewPointer(config.JitsiPrejoinPage)
+	}
+}
+
+// savedSettings lists the settings of the webapp a user saved.
+func savedSettings(userConfig *UserConfig) []string {
+	settings := []string{}
+	if userConfig.NamingScheme != nil {
+		settings = append(settings, commandArgNamingScheme)
+	}
+	if userConfig.Embedded != nil {
+		settings = append(settings, commandArgEmbedded)
+	}
+	if userConfig.ShowPrejoinPage != nil {
+		settings = append(settings, commandArgShowPrejoinPage)
+	}
+	return settings
+}
+
 func (p *Plugin) setUserConfig(userID string, config *UserConfig) error {
 	b, err := json.Marshal(config)
 	if err != nil {
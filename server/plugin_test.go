@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"net/http"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -48,6 +49,106 @@ func TestSignClaims(t *testing.T) {
 	require.Equal(t, &claims, newClaims)
 }
 
+func TestUpdateJwtUserInfo(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL:           "http://test",
+			JitsiJWT:           true,
+			JitsiAppID:         "test-app-id",
+			JitsiAppSecret:     "test-secret",
+			JitsiLinkValidTime: 30,
+		},
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	config := model.Config{}
+	config.SetDefaults()
+	apiMock.On("GetConfig").Return(&config, nil)
+	mockMeetingStore(&apiMock)
+	mockKVStore(&apiMock, "config_", callStatusKeyPrefix)
+	p.SetAPI(&apiMock)
+
+	meeting := &Meeting{Room: "test-room", ChannelID: "test-channel", CreatorID: "creator"}
+	require.Nil(t, p.createMeeting(meeting))
+	apiMock.On("HasPermissionToChannel", "member", "test-channel", model.PermissionManageChannelRoles).Return(false)
+	apiMock.On("HasPermissionToChannel", "admin", "test-channel", model.PermissionManageChannelRoles).Return(true)
+	apiMock.On("GetChannelMember", "test-channel", "outsider").Return(nil, &model.AppError{StatusCode: http.StatusNotFound})
+	apiMock.On("GetChannelMember", "test-channel", mock.Anything).Return(&model.ChannelMember{}, nil)
+
+	sign := func(t *testing.T, room string, expiresAt time.Time) string {
+		claims := Claims{Room: room}
+		claims.ExpiresAt = jwt.NewNumericDate(expiresAt)
+		token, err := signClaims("test-secret", &claims)
+		require.Nil(t, err)
+		return token
+	}
+
+	enrich := func(t *testing.T, room string, userID string) User {
+		enriched, err := p.updateJwtUserInfo(sign(t, room, time.Now().Add(time.Minute)), &model.User{Id: userID, Username: userID})
+		require.Nil(t, err)
+		newClaims, err := verifyJwt("test-secret", enriched)
+		require.Nil(t, err)
+		require.Equal(t, room, newClaims.Room)
+		require.Equal(t, userID, newClaims.Context.User.ID)
+		return newClaims.Context.User
+	}
+
+	for userID, moderator := range map[string]bool{"creator": true, "admin": true, "member": false} {
+		t.Run(userID, func(t *testing.T) {
+			user := enrich(t, "test-room", userID)
+			require.Equal(t, moderator, user.Moderator)
+			if moderator {
+				require.Equal(t, jwtAffiliationOwner, user.Affiliation)
+			} else {
+				require.Equal(t, jwtAffiliationMember, user.Affiliation)
+			}
+		})
+	}
+
+	t.Run("meeting with a lobby", func(t *testing.T) {
+		require.Nil(t, p.createMeeting(&Meeting{Room: "lobby-room", ChannelID: "test-channel", CreatorID: "creator", Lobby: true}))
+		require.True(t, enrich(t, "lobby-room", "creator").LobbyBypass)
+		require.False(t, enrich(t, "lobby-room", "member").LobbyBypass)
+	})
+
+	t.Run("meeting without record", func(t *testing.T) {
+		user := enrich(t, "unknown-room", "creator")
+		require.False(t, user.Moderator)
+		require.Equal(t, jwtAffiliationMember, user.Affiliation)
+	})
+
+	t.Run("expired token of a running meeting is re-issued", func(t *testing.T) {
+		enriched, err := p.updateJwtUserInfo(sign(t, "test-room", time.Now().Add(-time.Hour)), &model.User{Id: "member", Username: "member"})
+		require.Nil(t, err)
+		newClaims, err := verifyJwt("test-secret", enriched)
+		require.Nil(t, err)
+		require.True(t, newClaims.ExpiresAt.After(time.Now()))
+	})
+
+	t.Run("user outside the channel", func(t *testing.T) {
+		_, err := p.updateJwtUserInfo(sign(t, "test-room", time.Now().Add(time.Minute)), &model.User{Id: "outsider", Username: "outsider"})
+		require.ErrorIs(t, err, errNotChannelMember)
+	})
+
+	t.Run("revoked token", func(t *testing.T) {
+		token, _, err := p.issueMeetingToken(&model.User{Id: "member", Username: "member"}, meeting)
+		require.Nil(t, err)
+		_, err = p.revokeMeetingTokens(meeting.ID)
+		require.Nil(t, err)
+
+		_, err = p.updateJwtUserInfo(token, &model.User{Id: "member", Username: "member"})
+		require.ErrorIs(t, err, errTokenRevoked)
+	})
+
+	t.Run("ended meeting", func(t *testing.T) {
+		_, err := p.endMeeting(meeting.ID)
+		require.Nil(t, err)
+
+		_, err = p.updateJwtUserInfo(sign(t, "test-room", time.Now().Add(time.Minute)), &model.User{Id: "member", Username: "member"})
+		require.ErrorIs(t, err, errMeetingAlreadyEnded)
+	})
+}
+
 func TestStartMeeting(t *testing.T) {
 	p := Plugin{
 		configuration: &configuration{
@@ -60,6 +161,7 @@ func TestStartMeeting(t *testing.T) {
 	config := model.Config{}
 	config.SetDefaults()
 	apiMock.On("GetConfig").Return(&config, nil)
+	mockMeetingStore(&apiMock)
 
 	p.SetAPI(&apiMock)
 
@@ -74,7 +176,7 @@ func TestStartMeeting(t *testing.T) {
 		apiMock.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
 			return strings.HasPrefix(post.Props["meeting_link"].(string), "http://test/")
 		})).Return(&model.Post{}, nil)
-		b, _ := json.Marshal(UserConfig{Embedded: false, NamingScheme: "mattermost"})
+		b, _ := json.Marshal(UserConfig{Embedded: model.NewPointer(false), NamingScheme: model.NewPointer("mattermost")})
 		apiMock.On("KVGet", "config_test-id", mock.Anything).Return(b, nil)
 
 		meetingID, err := p.startMeeting(&testUser, &testChannel, "", "", false, "")
@@ -86,7 +188,7 @@ func TestStartMeeting(t *testing.T) {
 		apiMock.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
 			return strings.HasPrefix(post.Props["meeting_link"].(string), "http://test/")
 		})).Return(&model.Post{}, nil)
-		b, _ := json.Marshal(UserConfig{Embedded: false, NamingScheme: "mattermost"})
+		b, _ := json.Marshal(UserConfig{Embedded: model.NewPointer(false), NamingScheme: model.NewPointer("mattermost")})
 		apiMock.On("KVGet", "config_test-id", mock.Anything).Return(b, nil)
 
 		meetingID, err := p.startMeeting(&testUser, &testChannel, "", "Test topic", false, "")
@@ -98,7 +200,7 @@ func TestStartMeeting(t *testing.T) {
 		apiMock.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
 			return strings.HasPrefix(post.Props["meeting_link"].(string), "http://test/")
 		})).Return(&model.Post{}, nil)
-		b, _ := json.Marshal(UserConfig{Embedded: false, NamingScheme: "mattermost"})
+		b, _ := json.Marshal(UserConfig{Embedded: model.NewPointer(false), NamingScheme: model.NewPointer("mattermost")})
 		apiMock.On("KVGet", "config_test-id", mock.Anything).Return(b, nil)
 
 		meetingID, err := p.startMeeting(&testUser, &testChannel, "test-id", "", false, "")
@@ -113,11 +215,115 @@ func TestStartMeeting(t *testing.T) {
 		apiMock.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
 			return strings.HasPrefix(post.Props["meeting_link"].(string), "http://test/")
 		})).Return(&model.Post{}, nil)
-		b, _ := json.Marshal(UserConfig{Embedded: false, NamingScheme: "mattermost"})
+		b, _ := json.Marshal(UserConfig{Embedded: model.NewPointer(false), NamingScheme: model.NewPointer("mattermost")})
 		apiMock.On("KVGet", "config_test-id", mock.Anything).Return(b, nil)
 
 		meetingID, err := p.startMeeting(&testUser, &testChannel, "test-id", "Test topic", false, "")
 		require.Nil(t, err)
 		require.Equal(t, "test-id", meetingID)
+
+		meeting, err := p.getMeetingByRoom(meetingID)
+		require.Nil(t, err)
+		require.Equal(t, "test-id", meeting.ChannelID)
+		require.Equal(t, "test-id", meeting.CreatorID)
+		require.Equal(t, "Test topic", meeting.Topic)
+		require.Equal(t, MeetingStateActive, meeting.State)
+	})
+}
+
+func TestStartMeetingWithJWT(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL:           "http://test",
+			JitsiJWT:           true,
+			JitsiAppID:         "test-app-id",
+			JitsiAppSecret:     "test-secret",
+			JitsiLinkValidTime: 5,
+		},
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("GetBundlePath").Return("..", nil)
+	config := model.Config{}
+	config.SetDefaults()
+	apiMock.On("GetConfig").Return(&config, nil)
+	mockMeetingStore(&apiMock)
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	testUser := model.User{Id: "test-id", Username: "test-username"}
+	testChannel := model.Channel{Id: "test-id", Type: model.ChannelTypeOpen}
+
+	// The post only carries the room, participants request their own token to join.
+	var post *model.Post
+	apiMock.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		attachment := post.Props["attachments"].([]*model.SlackAttachment)[0]
+		_, hasJWT := post.Props["meeting_jwt"]
+		return post.Props["jwt_meeting"] == true && !hasJWT &&
+			post.Props["meeting_link"] == "http://test/test-room" &&
+			!strings.Contains(attachment.Text, "jwt=") && !strings.Contains(attachment.Fallback, "jwt=") &&
+			len(attachment.Actions) == 1
+	})).Run(func(args mock.Arguments) {
+		post = args.Get(0).(*model.Post)
+	}).Return(&model.Post{Id: "test-post"}, nil)
+
+	before := time.Now()
+	meeting, err := p.startMeetingWithOptions(&testUser, &testChannel, startMeetingOptions{MeetingID: "test-room", Topic: "Test topic"})
+	require.Nil(t, err)
+	require.GreaterOrEqual(t, meeting.JWTExpiresAt, before.Add(5*time.Minute).UnixMilli())
+
+	// The post identifies the meeting record, the room may be reused by later meetings.
+	require.Equal(t, meeting.ID, post.GetProp("meeting_record_id"))
+	attachment := post.Attachments()[0]
+	require.Equal(t, "/plugins/jitsi/api/v1/meetings/"+meeting.ID+"/refresh", attachment.Actions[0].Integration.URL)
+}
+
+func TestEndMeeting(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL: "http://test",
+		},
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("GetBundlePath").Return("..", nil)
+	config := model.Config{}
+	config.SetDefaults()
+	apiMock.On("GetConfig").Return(&config, nil)
+	mockMeetingStore(&apiMock)
+
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	meeting := &Meeting{Room: "test-room", ChannelID: "test-channel", CreatorID: "test-user", Topic: "Test topic", PostID: "test-post"}
+	require.Nil(t, p.createMeeting(meeting))
+
+	apiMock.On("GetPost", "test-post").Return(&model.Post{Id: "test-post", Type: "custom_jitsi"}, nil)
+	apiMock.On("UpdatePost", mock.MatchedBy(func(post *model.Post) bool {
+		attachment := post.GetProp("attachments").([]*model.SlackAttachment)[0]
+		return strings.HasPrefix(attachment.Text, "Meeting ID: test-room\n\nMeeting ended at ") &&
+			!strings.Contains(attachment.Text, "Join Meeting") &&
+			post.GetProp("meeting_ended_at") != nil
+	})).Return(&model.Post{}, nil).Once()
+
+	t.Run("end running meeting", func(t *testing.T) {
+		ended, err := p.endMeeting(meeting.ID)
+		require.Nil(t, err)
+		require.Equal(t, MeetingStateEnded, ended.State)
+		require.NotZero(t, ended.EndAt)
+
+		_, err = p.getRunningChannelMeeting("test-channel")
+		require.ErrorIs(t, err, errMeetingNotFound)
+	})
+
+	t.Run("end already ended meeting", func(t *testing.T) {
+		_, err := p.endMeeting(meeting.ID)
+		require.ErrorIs(t, err, errMeetingAlreadyEnded)
 	})
 }
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/pkg/errors"
+)
+
+const (
+	policyChannelTypesAll    = "all"
+	policyChannelTypesDirect = "direct"
+	policyChannelTypesPublic = "public"
+
+	policyRoleChannelAdmin = "channel_admin"
+	policyRoleTeamAdmin    = "team_admin"
+	policyRoleSystemAdmin  = "system_admin"
+
+	policyGuestsAllow  = "allow"
+	policyGuestsDirect = "direct"
+	policyGuestsBlock  = "block"
+)
+
+// startPolicyError is returned when the meeting policy forbids a user to start a meeting in a
+// channel. Every policy error has a localized message, see startPolicyMessage.
+type startPolicyError struct {
+	reason string
+}
+
+func (e *startPolicyError) Error() string {
+	return "the meeting policy forbids starting the meeting: " + e.reason
+}
+
+var (
+	errPolicyArchivedChannel   = &startPolicyError{"the channel is archived"}
+	errPolicyReadOnlyChannel   = &startPolicyError{"the user cannot post in the channel"}
+	errPolicyDirectOnly        = &startPolicyError{"meetings are restricted to direct and group messages"}
+	errPolicyPublicOnly        = &startPolicyError{"meetings are restricted to public channels"}
+	errPolicyTeamNotAllowed    = &startPolicyError{"the team is not allowed"}
+	errPolicyChannelNotAllowed = &startPolicyError{"the channel is not allowed"}
+	errPolicyGuest             = &startPolicyError{"guests cannot start meetings"}
+	errPolicyGuestDirectOnly   = &startPolicyError{"guests can only start meetings in direct and group messages"}
+	errPolicyChannelAdmins     = &startPolicyError{"only the channel admins can start meetings"}
+	errPolicyTeamAdmins        = &startPolicyError{"only the team admins can start meetings"}
+	errPolicySystemAdmins      = &startPolicyError{"only the system admins can start meetings"}
+)
+
+func isStartPolicyError(err error) bool {
+	var policyErr *startPolicyError
+	return errors.As(err, &policyErr)
+}
+
+// checkStartPolicy checks that the system configuration and the channel settings let a user start
+// a meeting in a channel, either right away or by scheduling it. It returns a startPolicyError
+// when they don't.
+func (p *Plugin) checkStartPolicy(user *model.User, channel *model.Channel, channelConfig *ChannelConfig) error {
+	config := p.getConfiguration()
+	direct := channel.Type == model.ChannelTypeDirect || channel.Type == model.ChannelTypeGroup
+
+	if channel.DeleteAt > 0 {
+		return errPolicyArchivedChannel
+	}
+	if !config.JitsiAllowReadOnlyChannels && !p.API.HasPermissionToChannel(user.Id, channel.Id, model.PermissionCreatePost) {
+		return errPolicyReadOnlyChannel
+	}
+
+	switch config.JitsiMeetingChannelTypes {
+	case policyChannelTypesDirect:
+		if !direct {
+			return errPolicyDirectOnly
+		}
+	case policyChannelTypesPublic:
+		if channel.Type != model.ChannelTypeOpen {
+			return errPolicyPublicOnly
+		}
+	}
+
+	// Direct and group messages don't belong to a team, the allow-lists only restrict team channels.
+	if !direct {
+		if allowed := splitPolicyList(config.JitsiAllowedTeams); len(allowed) > 0 {
+			team, appErr := p.API.GetTeam(channel.TeamId)
+			if appErr != nil {
+				return appErr
+			}
+			if !policyListContains(allowed, team.Id, team.Name) {
+				return errPolicyTeamNotAllowed
+			}
+		}
+		if allowed := splitPolicyList(config.JitsiAllowedChannels); len(allowed) > 0 && !policyListContains(allowed, channel.Id, channel.Name) {
+			return errPolicyChannelNotAllowed
+		}
+	}
+
+	if user.IsGuest() {
+		switch config.JitsiGuestMeetings {
+		case policyGuestsBlock:
+			return errPolicyGuest
+		case policyGuestsDirect:
+			if !direct {
+				return errPolicyGuestDirectOnly
+			}
+		}
+	}
+
+	// The channel and team roles don't exist in direct and group messages.
+	switch config.JitsiRequiredRole {
+	case policyRoleChannelAdmin:
+		if !direct && !p.API.HasPermissionToChannel(user.Id, channel.Id, model.PermissionManageChannelRoles) {
+			return errPolicyChannelAdmins
+		}
+	case policyRoleTeamAdmin:
+		if !direct && !p.API.HasPermissionToTeam(user.Id, channel.TeamId, model.PermissionManageTeam) {
+			return errPolicyTeamAdmins
+		}
+	case policyRoleSystemAdmin:
+		if !p.API.HasPermissionTo(user.Id, model.PermissionManageSystem) {
+			return errPolicySystemAdmins
+		}
+	}
+
+	if !p.canStartMeeting(user.Id, channel.Id, channelConfig) {
+		return errPolicyChannelAdmins
+	}
+
+	return nil
+}
+
+// checkChannelStartPolicy is checkStartPolicy with the settings of the channel.
+func (p *Plugin) checkChannelStartPolicy(user *model.User, channel *model.Channel) error {
+	channelConfig, err := p.getChannelConfig(channel.Id)
+	if err != nil {
+		return err
+	}
+	return p.checkStartPolicy(user, channel, channelConfig)
+}
+
+// splitPolicyList splits a comma separated list of the system configuration.
+func splitPolicyList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// policyListContains tells whether an allow-list names a team or a channel, by ID or by name.
+func policyListContains(list []string, id string, name string) bool {
+	for _, item := range list {
+		if item == id || strings.EqualFold(item, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Plugin) startPolicyMessage(l *i18n.Localizer, err error) string {
+	var message *i18n.Message
+	switch {
+	case errors.Is(err, errPolicyArchivedChannel):
+		message = &i18n.Message{
+			ID:    "jitsi.start_policy.archived_channel",
+			Other: "Meetings cannot be started in archived channels.",
+		}
+	case errors.Is(err, errPolicyReadOnlyChannel):
+		message = &i18n.Message{
+			ID:    "jitsi.start_policy.read_only_channel",
+			Other: "You cannot start meetings in a channel you cannot post in.",
+		}
+	case errors.Is(err, errPolicyDirectOnly):
+		message = &i18n.Message{
+			ID:    "jitsi.start_policy.direct_only",
+			Other: "Meetings can only be started in direct and group messages.",
+		}
+	case errors.Is(err, errPolicyPublicOnly):
+		message = &i18n.Message{
+			ID:    "jitsi.start_policy.public_only",
+			Other: "Meetings can only be started in public channels.",
+		}
+	case errors.Is(err, errPolicyTeamNotAllowed):
+		message = &i18n.Message{
+			ID:    "jitsi.start_policy.team_not_allowed",
+			Other: "Meetings cannot be started in this team.",
+		}
+	case errors.Is(err, errPolicyChannelNotAllowed):
+		message = &i18n.Message{
+			ID:    "jitsi.start_policy.channel_not_allowed",
+			Other: "Meetings cannot be started in this channel.",
+		}
+	case errors.Is(err, errPolicyGuest):
+		message = &i18n.Message{
+			ID:    "jitsi.start_policy.guest",
+			Other: "Guests cannot start meetings.",
+		}
+	case errors.Is(err, errPolicyGuestDirectOnly):
+		message = &i18n.Message{
+			ID:    "jitsi.start_policy.guest_direct_only",
+			Other: "Guests can only start meetings in direct and group messages.",
+		}
+	case errors.Is(err, errPolicyChannelAdmins):
+		message = &i18n.Message{
+			ID:    "jitsi.command.start.forbidden",
+			Other: "Only the channel admins can start meetings in this channel.",
+		}
+	case errors.Is(err, errPolicyTeamAdmins):
+		message = &i18n.Message{
+			ID:    "jitsi.start_policy.team_admins",
+			Other: "Only the team admins can start meetings in this team.",
+		}
+	case errors.Is(err, errPolicySystemAdmins):
+		message = &i18n.Message{
+			ID:    "jitsi.start_policy.system_admins",
+			Other: "Only the system admins can start meetings.",
+		}
+	default:
+		message = &i18n.Message{
+			ID:    "jitsi.start_policy.forbidden",
+			Other: "You are not allowed to start meetings in this channel.",
+		}
+	}
+	return p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{DefaultMessage: message})
+}
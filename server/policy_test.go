@@ -0,0 +1,141 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckStartPolicy(t *testing.T) {
+	member := &model.User{Id: "member", Roles: model.SystemUserRoleId}
+	guest := &model.User{Id: "guest", Roles: model.SystemGuestRoleId}
+	admin := &model.User{Id: "admin", Roles: model.SystemAdminRoleId + " " + model.SystemUserRoleId}
+
+	open := &model.Channel{Id: "open", TeamId: "test-team", Name: "town-square", Type: model.ChannelTypeOpen}
+	private := &model.Channel{Id: "private", TeamId: "test-team", Name: "secrets", Type: model.ChannelTypePrivate}
+	direct := &model.Channel{Id: "direct", Type: model.ChannelTypeDirect}
+	archived := &model.Channel{Id: "archived", TeamId: "test-team", Type: model.ChannelTypeOpen, DeleteAt: 1}
+	readOnly := &model.Channel{Id: "read-only", TeamId: "test-team", Type: model.ChannelTypeOpen}
+
+	apiMock := plugintest.API{}
+	mockKVStore(&apiMock, channelConfigKeyPrefix)
+	for _, user := range []*model.User{member, guest, admin} {
+		for _, channel := range []*model.Channel{open, private, direct} {
+			apiMock.On("HasPermissionToChannel", user.Id, channel.Id, model.PermissionCreatePost).Return(true)
+			apiMock.On("HasPermissionToChannel", user.Id, channel.Id, model.PermissionManageChannelRoles).Return(user == admin)
+		}
+		apiMock.On("HasPermissionToChannel", user.Id, readOnly.Id, model.PermissionCreatePost).Return(user == admin)
+		apiMock.On("HasPermissionToTeam", user.Id, "test-team", model.PermissionManageTeam).Return(user == admin)
+		apiMock.On("HasPermissionTo", user.Id, model.PermissionManageSystem).Return(user == admin)
+	}
+	apiMock.On("GetTeam", "test-team").Return(&model.Team{Id: "test-team", Name: "engineering"}, nil)
+
+	for _, tc := range []struct {
+		name          string
+		configuration configuration
+		channelConfig ChannelConfig
+		user          *model.User
+		channel       *model.Channel
+		err           error
+	}{
+		{name: "default policy", user: member, channel: private},
+		{name: "archived channel", user: admin, channel: archived, err: errPolicyArchivedChannel},
+		{name: "read-only channel", user: member, channel: readOnly, err: errPolicyReadOnlyChannel},
+		{name: "read-only channel allowed", configuration: configuration{JitsiAllowReadOnlyChannels: true}, user: member, channel: readOnly},
+		{name: "direct messages only", configuration: configuration{JitsiMeetingChannelTypes: policyChannelTypesDirect}, user: member, channel: open, err: errPolicyDirectOnly},
+		{name: "direct messages only in a direct message", configuration: configuration{JitsiMeetingChannelTypes: policyChannelTypesDirect}, user: member, channel: direct},
+		{name: "public channels only", configuration: configuration{JitsiMeetingChannelTypes: policyChannelTypesPublic}, user: member, channel: private, err: errPolicyPublicOnly},
+		{name: "public channels only in a direct message", configuration: configuration{JitsiMeetingChannelTypes: policyChannelTypesPublic}, user: member, channel: direct, err: errPolicyPublicOnly},
+		{name: "allowed team name", configuration: configuration{JitsiAllowedTeams: "sales, Engineering"}, user: member, channel: open},
+		{name: "team not allowed", configuration: configuration{JitsiAllowedTeams: "sales"}, user: member, channel: open, err: errPolicyTeamNotAllowed},
+		{name: "team allow-list in a direct message", configuration: configuration{JitsiAllowedTeams: "sales"}, user: member, channel: direct},
+		{name: "allowed channel ID", configuration: configuration{JitsiAllowedChannels: "private,town-square"}, user: member, channel: private},
+		{name: "channel not allowed", configuration: configuration{JitsiAllowedChannels: "town-square"}, user: member, channel: private, err: errPolicyChannelNotAllowed},
+		{name: "guests allowed", user: guest, channel: private},
+		{name: "guests blocked", configuration: configuration{JitsiGuestMeetings: policyGuestsBlock}, user: guest, channel: direct, err: errPolicyGuest},
+		{name: "guests in direct messages only", configuration: configuration{JitsiGuestMeetings: policyGuestsDirect}, user: guest, channel: open, err: errPolicyGuestDirectOnly},
+		{name: "guests in a direct message", configuration: configuration{JitsiGuestMeetings: policyGuestsDirect}, user: guest, channel: direct},
+		{name: "channel admins", configuration: configuration{JitsiRequiredRole: policyRoleChannelAdmin}, user: member, channel: open, err: errPolicyChannelAdmins},
+		{name: "channel admins in a direct message", configuration: configuration{JitsiRequiredRole: policyRoleChannelAdmin}, user: member, channel: direct},
+		{name: "team admins", configuration: configuration{JitsiRequiredRole: policyRoleTeamAdmin}, user: member, channel: open, err: errPolicyTeamAdmins},
+		{name: "team admin", configuration: configuration{JitsiRequiredRole: policyRoleTeamAdmin}, user: admin, channel: open},
+		{name: "system admins", configuration: configuration{JitsiRequiredRole: policyRoleSystemAdmin}, user: member, channel: direct, err: errPolicySystemAdmins},
+		{name: "channel settings", channelConfig: ChannelConfig{WhoCanStart: whoCanStartAdmins}, user: member, channel: open, err: errPolicyChannelAdmins},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := Plugin{configuration: &tc.configuration}
+			p.SetAPI(&apiMock)
+
+			err := p.checkStartPolicy(tc.user, tc.channel, &tc.channelConfig)
+			if tc.err == nil {
+				require.Nil(t, err)
+			} else {
+				require.ErrorIs(t, err, tc.err)
+				require.True(t, isStartPolicyError(err))
+			}
+		})
+	}
+}
+
+func TestStartPolicyRejections(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{JitsiURL: "http://test", JitsiGuestMeetings: policyGuestsBlock},
+		botID:         "test-bot-id",
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("GetBundlePath").Return("..", nil)
+	mockKVStore(&apiMock, "config_", channelConfigKeyPrefix)
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	apiMock.On("GetUser", "guest").Return(&model.User{Id: "guest", Roles: model.SystemGuestRoleId, Locale: "en"}, nil)
+	apiMock.On("GetChannel", "test-channel").Return(&model.Channel{Id: "test-channel", Type: model.ChannelTypeOpen}, nil)
+	apiMock.On("GetChannelMember", "test-channel", "guest").Return(&model.ChannelMember{}, nil)
+	apiMock.On("HasPermissionToChannel", "guest", "test-channel", model.PermissionCreatePost).Return(true)
+
+	t.Run("slash command", func(t *testing.T) {
+		apiMock.On("SendEphemeralPost", "guest", &model.Post{
+			UserId:    "test-bot-id",
+			ChannelId: "test-channel",
+			Message:   "Guests cannot start meetings.",
+		}).Return(nil).Once()
+
+		response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{UserId: "guest", ChannelId: "test-channel", Command: "/jitsi start topic"})
+		require.Equal(t, &model.CommandResponse{}, response)
+		require.Nil(t, err)
+	})
+
+	t.Run("schedule command", func(t *testing.T) {
+		apiMock.On("SendEphemeralPost", "guest", &model.Post{
+			UserId:    "test-bot-id",
+			ChannelId: "test-channel",
+			Message:   "Guests cannot start meetings.",
+		}).Return(nil).Once()
+
+		response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{UserId: "guest", ChannelId: "test-channel", Command: "/jitsi schedule \"Review\" 2099-01-01 10:00 1h"})
+		require.Equal(t, &model.CommandResponse{}, response)
+		require.Nil(t, err)
+	})
+
+	t.Run("API", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/meetings", strings.NewReader(`{"channel_id": "test-channel", "topic": "Review"}`))
+		r.Header.Set("Mattermost-User-Id", "guest")
+		p.ServeHTTP(&plugin.Context{}, w, r)
+
+		require.Equal(t, http.StatusForbidden, w.Code)
+		require.Equal(t, "Guests cannot start meetings.\n", w.Body.String())
+	})
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+const (
+	rateLimitKeyPrefix        = "rate_limit_"
+	userRateLimitKeyPrefix    = rateLimitKeyPrefix + "user_"
+	channelRateLimitKeyPrefix = rateLimitKeyPrefix + "channel_"
+
+	defaultRateLimitWindow = 60
+)
+
+// rateLimitError is returned when a user or a channel started too many meetings in the current
+// rate limit window.
+type rateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *rateLimitError) Error() string {
+	return fmt.Sprintf("too many meetings started, retry after %s", e.RetryAfter)
+}
+
+// RetryAfterSeconds returns the number of seconds to wait for, as sent in the Retry-After header.
+func (e *rateLimitError) RetryAfterSeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+// rateLimitCounter counts the meetings started in a fixed window. The counters are stored in the
+// KV store so that every server of a cluster shares them.
+type rateLimitCounter struct {
+	WindowStart int64 `json:"window_start"`
+	Count       int   `json:"count"`
+}
+
+// checkStartRateLimit counts a meeting started by a user in a channel, unless the user or the
+// channel already reached their limit in the current window, in which case it returns a
+// rateLimitError.
+func (p *Plugin) checkStartRateLimit(userID string, channelID string) error {
+	config := p.getConfiguration()
+	window := time.Duration(config.GetRateLimitWindow()) * time.Second
+	now := time.Now()
+
+	if err := p.takeRateLimit(userRateLimitKeyPrefix+userID, config.JitsiUserRateLimit, window, now); err != nil {
+		return err
+	}
+	return p.takeRateLimit(channelRateLimitKeyPrefix+channelID, config.JitsiChannelRateLimit, window, now)
+}
+
+// takeRateLimit increments the counter stored at key, unless it reached limit in the current
+// window. A limit below 1 disables the counter.
+func (p *Plugin) takeRateLimit(key string, limit int, window time.Duration, now time.Time) error {
+	if limit < 1 {
+		return nil
+	}
+
+	var limitErr *rateLimitError
+	err := p.atomicKVUpdate(key, func(data []byte) ([]byte, error) {
+		limitErr = nil
+		var counter rateLimitCounter
+		if data != nil {
+			if err := json.Unmarshal(data, &counter); err != nil {
+				return nil, err
+			}
+		}
+
+		windowEnd := time.Unix(0, counter.WindowStart).Add(window)
+		if !now.Before(windowEnd) {
+			counter = rateLimitCounter{WindowStart: now.UnixNano()}
+		} else if counter.Count >= limit {
+			limitErr = &rateLimitError{RetryAfter: windowEnd.Sub(now)}
+			return data, nil
+		}
+		counter.Count++
+
+		return json.Marshal(counter)
+	})
+	if err != nil {
+		return err
+	}
+	if limitErr != nil {
+		return limitErr
+	}
+	return nil
+}
+
+func (p *Plugin) rateLimitMessage(l *i18n.Localizer, err *rateLimitError) string {
+	return p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "jitsi.start_meeting.rate_limited",
+			One:   "Too many meetings were started, try again in {{.Count}} second.",
+			Other: "Too many meetings were started, try again in {{.Count}} seconds.",
+		},
+		TemplateData: map[string]int{"Count": err.RetryAfterSeconds()},
+		PluralCount:  err.RetryAfterSeconds(),
+	})
+}
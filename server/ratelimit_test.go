@@ -0,0 +1,117 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTakeRateLimit(t *testing.T) {
+	p := Plugin{}
+	apiMock := plugintest.API{}
+	store := mockKVStore(&apiMock, rateLimitKeyPrefix)
+	p.SetAPI(&apiMock)
+
+	now := time.Now()
+	window := time.Minute
+
+	t.Run("disabled limit", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			require.Nil(t, p.takeRateLimit("rate_limit_user_disabled", 0, window, now))
+		}
+		require.NotContains(t, store, "rate_limit_user_disabled")
+	})
+
+	t.Run("limit reached in the window", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			require.Nil(t, p.takeRateLimit("rate_limit_user_test", 3, window, now.Add(time.Duration(i)*time.Second)))
+		}
+
+		err := p.takeRateLimit("rate_limit_user_test", 3, window, now.Add(20*time.Second))
+		var limitErr *rateLimitError
+		require.ErrorAs(t, err, &limitErr)
+		require.Equal(t, 40*time.Second, limitErr.RetryAfter)
+		require.Equal(t, 40, limitErr.RetryAfterSeconds())
+	})
+
+	t.Run("next window", func(t *testing.T) {
+		require.Nil(t, p.takeRateLimit("rate_limit_user_test", 3, window, now.Add(window)))
+		require.Error(t, p.takeRateLimit("rate_limit_user_test", 1, window, now.Add(window)))
+	})
+}
+
+func TestStartMeetingRateLimits(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL:              "http://test",
+			JitsiNamingScheme:     jitsiNameSchemeUUID,
+			JitsiUserRateLimit:    1,
+			JitsiChannelRateLimit: 2,
+		},
+		botID: "test-bot-id",
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("GetBundlePath").Return("..", nil)
+	config := model.Config{}
+	config.SetDefaults()
+	apiMock.On("GetConfig").Return(&config, nil)
+	mockMeetingStore(&apiMock)
+	mockKVStore(&apiMock, "config_", rateLimitKeyPrefix)
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	for _, userID := range []string{"first", "second", "third"} {
+		apiMock.On("GetUser", userID).Return(&model.User{Id: userID, Locale: "en"}, nil)
+		apiMock.On("GetChannelMember", "test-channel", userID).Return(&model.ChannelMember{}, nil)
+		apiMock.On("HasPermissionToChannel", userID, "test-channel", model.PermissionCreatePost).Return(true)
+	}
+	apiMock.On("GetChannel", "test-channel").Return(&model.Channel{Id: "test-channel", Type: model.ChannelTypeOpen}, nil)
+	apiMock.On("CreatePost", mock.Anything).Return(&model.Post{}, nil).Times(2)
+
+	serve := func(userID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/meetings", strings.NewReader(`{"channel_id": "test-channel", "topic": "Sync"}`))
+		r.Header.Set("Mattermost-User-Id", userID)
+		p.ServeHTTP(&plugin.Context{}, w, r)
+		return w
+	}
+
+	t.Run("user limit", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, serve("first").Code)
+
+		w := serve("first")
+		require.Equal(t, http.StatusTooManyRequests, w.Code)
+		retryAfter := w.Header().Get("Retry-After")
+		require.NotEmpty(t, retryAfter)
+		require.True(t, strings.HasPrefix(w.Body.String(), "Too many meetings were started, try again in "+retryAfter+" second"))
+	})
+
+	t.Run("channel limit", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, serve("second").Code)
+		require.Equal(t, http.StatusTooManyRequests, serve("third").Code)
+	})
+
+	t.Run("slash command", func(t *testing.T) {
+		apiMock.On("SendEphemeralPost", "third", mock.MatchedBy(func(post *model.Post) bool {
+			return post.UserId == "test-bot-id" && strings.HasPrefix(post.Message, "Too many meetings were started, try again in ")
+		})).Return(nil).Once()
+
+		response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{UserId: "third", ChannelId: "test-channel", Command: "/jitsi start Sync"})
+		require.Equal(t, &model.CommandResponse{}, response)
+		require.Nil(t, err)
+	})
+}
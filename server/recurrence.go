@@ -0,0 +1,240 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	recurrenceDaily  = "DAILY"
+	recurrenceWeekly = "WEEKLY"
+
+	maxRecurrenceInterval = 99
+	recurrenceUntilLayout = "20060102T150405Z"
+)
+
+var weekdayCodes = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// RecurrenceRule is the subset of the iCalendar RRULE supported for recurring meetings: daily and
+// weekly frequencies with an interval, a list of week days, and a COUNT or UNTIL limit.
+type RecurrenceRule struct {
+	Frequency string
+	Interval  int
+	ByDay     []time.Weekday
+	Count     int
+	Until     time.Time
+}
+
+// parseRecurrenceRule parses either one of the shorthands daily, weekdays, weekly and
+// weekly:MO,TH or an RRULE such as FREQ=WEEKLY;INTERVAL=2;BYDAY=MO. The week day of start is used
+// for weekly rules without explicit week days.
+func parseRecurrenceRule(value string, start time.Time) (*RecurrenceRule, error) {
+	rule := &RecurrenceRule{Interval: 1}
+
+	lower := strings.ToLower(strings.TrimSpace(value))
+	switch {
+	case lower == "daily":
+		rule.Frequency = recurrenceDaily
+	case lower == "weekdays":
+		rule.Frequency = recurrenceWeekly
+		rule.ByDay = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	case lower == "weekly":
+		rule.Frequency = recurrenceWeekly
+	case strings.HasPrefix(lower, "weekly:"):
+		rule.Frequency = recurrenceWeekly
+		days, err := parseWeekdays(strings.TrimPrefix(lower, "weekly:"))
+		if err != nil {
+			return nil, err
+		}
+		rule.ByDay = days
+	default:
+		if err := rule.parseRRule(strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(value)), "RRULE:")); err != nil {
+			return nil, err
+		}
+	}
+
+	if rule.Frequency == recurrenceWeekly && len(rule.ByDay) == 0 {
+		rule.ByDay = []time.Weekday{start.Weekday()}
+	}
+
+	return rule, nil
+}
+
+func (r *RecurrenceRule) parseRRule(value string) error {
+	for _, part := range strings.Split(value, ";") {
+		key, val, found := strings.Cut(part, "=")
+		if !found {
+			return errors.Errorf("invalid recurrence rule part %q", part)
+		}
+
+		switch key {
+		case "FREQ":
+			if val != recurrenceDaily && val != recurrenceWeekly {
+				return errors.Errorf("unsupported recurrence frequency %q", val)
+			}
+			r.Frequency = val
+		case "INTERVAL":
+			interval, err := strconv.Atoi(val)
+			if err != nil || interval < 1 || interval > maxRecurrenceInterval {
+				return errors.Errorf("invalid recurrence interval %q", val)
+			}
+			r.Interval = interval
+		case "BYDAY":
+			days, err := parseWeekdays(val)
+			if err != nil {
+				return err
+			}
+			r.ByDay = days
+		case "COUNT":
+			count, err := strconv.Atoi(val)
+			if err != nil || count < 1 {
+				return errors.Errorf("invalid recurrence count %q", val)
+			}
+			r.Count = count
+		case "UNTIL":
+			until, err := parseRecurrenceUntil(val)
+			if err != nil {
+				return err
+			}
+			r.Until = until
+		case "WKST":
+			// Weeks always start on Monday.
+		default:
+			return errors.Errorf("unsupported recurrence rule part %q", key)
+		}
+	}
+
+	if r.Frequency == "" {
+		return errors.New("missing recurrence frequency")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return errors.New("recurrence count and until are mutually exclusive")
+	}
+	return nil
+}
+
+func parseRecurrenceUntil(value string) (time.Time, error) {
+	if until, err := time.Parse(recurrenceUntilLayout, value); err == nil {
+		return until, nil
+	}
+
+	until, err := time.Parse("20060102", value)
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid recurrence until %q", value)
+	}
+	// A date only UNTIL includes the whole day.
+	return until.Add(24*time.Hour - time.Second), nil
+}
+
+func parseWeekdays(value string) ([]time.Weekday, error) {
+	var days []time.Weekday
+	for _, code := range strings.Split(value, ",") {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if len(code) > 2 {
+			code = code[:2]
+		}
+
+		found := false
+		for day, weekdayCode := range weekdayCodes {
+			if weekdayCode == code {
+				days = append(days, time.Weekday(day))
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.Errorf("invalid week day %q", code)
+		}
+	}
+	return days, nil
+}
+
+// String returns the rule as an iCalendar RRULE value.
+func (r *RecurrenceRule) String() string {
+	parts := []string{"FREQ=" + r.Frequency}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			days = append(days, weekdayCodes[day])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(recurrenceUntilLayout))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence strictly after the given time, for a recurrence whose first
+// occurrence is first. Occurrences keep the wall clock time of first in its location, so that
+// they are not shifted by daylight saving time changes. The zero time is returned when the rule
+// has no further occurrence. COUNT is not taken into account since it depends on the number of
+// occurrences already held, which is tracked by the caller.
+func (r *RecurrenceRule) Next(first time.Time, after time.Time) time.Time {
+	location := first.Location()
+	from := after.In(location)
+	if from.Before(first) {
+		from = first
+	}
+
+	lookahead := 7*r.Interval + 7
+	for i := 0; i <= lookahead; i++ {
+		candidate := time.Date(from.Year(), from.Month(), from.Day()+i, first.Hour(), first.Minute(), 0, 0, location)
+		if candidate.Before(first) || !candidate.After(after) {
+			continue
+		}
+		if !r.Until.IsZero() && candidate.After(r.Until) {
+			return time.Time{}
+		}
+		if r.matches(first, candidate) {
+			return candidate
+		}
+	}
+
+	return time.Time{}
+}
+
+func (r *RecurrenceRule) matches(first time.Time, candidate time.Time) bool {
+	days := civilDaysBetween(first, candidate)
+
+	if len(r.ByDay) > 0 && !containsWeekday(r.ByDay, candidate.Weekday()) {
+		return false
+	}
+
+	switch r.Frequency {
+	case recurrenceDaily:
+		return days%r.Interval == 0
+	case recurrenceWeekly:
+		// Weeks start on Monday, the iCalendar default.
+		mondayOffset := (int(first.Weekday()) + 6) % 7
+		return ((days+mondayOffset)/7)%r.Interval == 0
+	default:
+		return false
+	}
+}
+
+// civilDaysBetween returns the number of calendar days between the dates of a and b, ignoring
+// the time of the day and daylight saving time changes.
+func civilDaysBetween(a time.Time, b time.Time) int {
+	dateA := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	dateB := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(dateB.Sub(dateA).Hours() / 24)
+}
+
+func containsWeekday(days []time.Weekday, day time.Weekday) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
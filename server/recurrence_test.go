@@ -0,0 +1,106 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRecurrenceRule(t *testing.T) {
+	// Wednesday
+	start := time.Date(2026, 10, 21, 9, 30, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		input string
+		rrule string
+	}{
+		{input: "daily", rrule: "FREQ=DAILY"},
+		{input: "weekdays", rrule: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR"},
+		{input: "weekly", rrule: "FREQ=WEEKLY;BYDAY=WE"},
+		{input: "weekly:mon,thu", rrule: "FREQ=WEEKLY;BYDAY=MO,TH"},
+		{input: "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", rrule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO"},
+		{input: "freq=daily;count=3", rrule: "FREQ=DAILY;COUNT=3"},
+		{input: "FREQ=DAILY;UNTIL=20261031", rrule: "FREQ=DAILY;UNTIL=20261031T235959Z"},
+		{input: "FREQ=WEEKLY;WKST=MO", rrule: "FREQ=WEEKLY;BYDAY=WE"},
+	} {
+		t.Run(tt.input, func(t *testing.T) {
+			rule, err := parseRecurrenceRule(tt.input, start)
+			require.Nil(t, err)
+			require.Equal(t, tt.rrule, rule.String())
+		})
+	}
+
+	for _, input := range []string{
+		"monthly",
+		"weekly:funday",
+		"FREQ=MONTHLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20261031",
+		"INTERVAL=2",
+		"FREQ=DAILY;BYMONTH=1",
+	} {
+		t.Run(input, func(t *testing.T) {
+			_, err := parseRecurrenceRule(input, start)
+			require.NotNil(t, err)
+		})
+	}
+}
+
+func TestRecurrenceRuleNext(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Paris")
+	require.Nil(t, err)
+	// Wednesday
+	first := time.Date(2026, 10, 21, 9, 30, 0, 0, location)
+
+	next := func(t *testing.T, value string, after time.Time) time.Time {
+		rule, err := parseRecurrenceRule(value, first)
+		require.Nil(t, err)
+		return rule.Next(first, after)
+	}
+
+	t.Run("first occurrence", func(t *testing.T) {
+		require.Equal(t, first, next(t, "daily", first.Add(-time.Hour)))
+	})
+
+	t.Run("daily", func(t *testing.T) {
+		require.Equal(t, first.AddDate(0, 0, 1), next(t, "daily", first))
+	})
+
+	t.Run("every other day", func(t *testing.T) {
+		require.Equal(t, first.AddDate(0, 0, 2), next(t, "FREQ=DAILY;INTERVAL=2", first))
+	})
+
+	t.Run("weekdays skip the weekend", func(t *testing.T) {
+		friday := time.Date(2026, 10, 23, 9, 30, 0, 0, location)
+		monday := time.Date(2026, 10, 26, 9, 30, 0, 0, location)
+		require.Equal(t, monday, next(t, "weekdays", friday))
+	})
+
+	t.Run("daylight saving time keeps the wall clock time", func(t *testing.T) {
+		// Summer time ends in Paris on Sunday October 25 2026.
+		saturday := time.Date(2026, 10, 24, 9, 30, 0, 0, location)
+		sunday := next(t, "daily", saturday)
+		require.Equal(t, 9, sunday.Hour())
+		require.Equal(t, 25*time.Hour, sunday.Sub(saturday))
+	})
+
+	t.Run("weekly on several days", func(t *testing.T) {
+		thursday := time.Date(2026, 10, 22, 9, 30, 0, 0, location)
+		require.Equal(t, thursday, next(t, "weekly:mo,th", first))
+	})
+
+	t.Run("every other week", func(t *testing.T) {
+		require.Equal(t, first.AddDate(0, 0, 14), next(t, "FREQ=WEEKLY;INTERVAL=2", first))
+
+		// The Monday of the first week is before the first occurrence, the next one is two
+		// weeks later.
+		monday := time.Date(2026, 11, 2, 9, 30, 0, 0, location)
+		require.Equal(t, monday, next(t, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", first))
+	})
+
+	t.Run("until", func(t *testing.T) {
+		require.Equal(t, first.AddDate(0, 0, 1), next(t, "FREQ=DAILY;UNTIL=20261022", first))
+		require.True(t, next(t, "FREQ=DAILY;UNTIL=20261022", first.AddDate(0, 0, 1)).IsZero())
+	})
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/shared/mlog"
+	"github.com/pkg/errors"
+)
+
+const (
+	recurringMeetingKeyPrefix         = "recurring_meeting_"
+	channelRecurringMeetingsKeyPrefix = "recurring_meetings_channel_"
+	recurringMeetingsKey              = "recurring_meetings"
+)
+
+var (
+	errRecurringMeetingNotFound = errors.New("recurring meeting not found")
+	errNoOccurrence             = errors.New("the recurrence rule has no upcoming occurrence")
+)
+
+// RecurringMeeting is the definition of a meeting taking place regularly in the same Jitsi room.
+// Each occurrence is stored as a scheduled Meeting shortly before it starts, so that its link is
+// posted, with a freshly signed JWT, like any other scheduled meeting.
+type RecurringMeeting struct {
+	ID        string `json:"id"`
+	Room      string `json:"room"`
+	ChannelID string `json:"channel_id"`
+	CreatorID string `json:"creator_id"`
+	Topic     string `json:"topic"`
+	Personal  bool   `json:"personal"`
+	// Rule is the recurrence rule in the iCalendar RRULE format.
+	Rule string `json:"rule"`
+	// Timezone is the location in which the wall clock time of the occurrences is kept.
+	Timezone    string `json:"timezone"`
+	StartAt     int64  `json:"start_at"`
+	Duration    int64  `json:"duration"`
+	NextAt      int64  `json:"next_at"`
+	Occurrences int    `json:"occurrences"`
+	// ExDates are the occurrences cancelled in the calendar the meeting was imported from.
+	ExDates  []int64 `json:"exdates,omitempty"`
+	CreateAt int64   `json:"create_at"`
+}
+
+// Location returns the location of the recurring meeting, falling back to UTC when unknown.
+func (r *RecurringMeeting) Location() *time.Location {
+	location, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// FirstOccurrence returns the start of the first occurrence in the meeting location.
+func (r *RecurringMeeting) FirstOccurrence() time.Time {
+	return time.UnixMilli(r.StartAt).In(r.Location())
+}
+
+// RecurrenceRule parses the recurrence rule of the meeting.
+func (r *RecurringMeeting) RecurrenceRule() (*RecurrenceRule, error) {
+	return parseRecurrenceRule(r.Rule, r.FirstOccurrence())
+}
+
+// isExcluded reports whether the occurrence starting at the given time is cancelled.
+func (r *RecurringMeeting) isExcluded(at int64) bool {
+	for _, exDate := range r.ExDates {
+		if exDate == at {
+			return true
+		}
+	}
+	return false
+}
+
+// advance moves NextAt to the first occurrence after the given time, counting the skipped
+// occurrences. It reports false when the recurrence has no occurrence left.
+func (r *RecurringMeeting) advance(rule *RecurrenceRule, after time.Time) bool {
+	for r.NextAt > 0 && !time.UnixMilli(r.NextAt).After(after) {
+		r.Occurrences++
+		if rule.Count > 0 && r.Occurrences >= rule.Count {
+			r.NextAt = 0
+			break
+		}
+
+		next := rule.Next(r.FirstOccurrence(), time.UnixMilli(r.NextAt))
+		if next.IsZero() {
+			r.NextAt = 0
+			break
+		}
+		r.NextAt = next.UnixMilli()
+	}
+	return r.NextAt > 0
+}
+
+// addRecurringMeeting stores a new recurring meeting starting with the first occurrence of rule
+// at or after startAt. The room is generated once, so that every occurrence uses the same one.
+// The occurrences starting at exDates are skipped.
+func (p *Plugin) addRecurringMeeting(user *model.User, channel *model.Channel, topic string, rule *RecurrenceRule, startAt time.Time, duration time.Duration, exDates []time.Time) (*RecurringMeeting, error) {
+	first := rule.Next(startAt, time.Now())
+	if first.IsZero() {
+		return nil, errNoOccurrence
+	}
+
+	userConfig, _, err := p.resolveUserConfig(user.Id, channel.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	room, personal, err := p.generateMeetingName(user, channel, *userConfig.NamingScheme)
+	if err != nil {
+		return nil, err
+	}
+
+	recurring := &RecurringMeeting{
+		ID:        model.NewId(),
+		Room:      room,
+		ChannelID: channel.Id,
+		CreatorID: user.Id,
+		Topic:     topic,
+		Personal:  personal,
+		Rule:      rule.String(),
+		Timezone:  startAt.Location().String(),
+		StartAt:   first.UnixMilli(),
+		Duration:  duration.Milliseconds(),
+		NextAt:    first.UnixMilli(),
+		CreateAt:  model.GetMillis(),
+	}
+	for _, exDate := range exDates {
+		if !exDate.Before(first) {
+			recurring.ExDates = append(recurring.ExDates, exDate.UnixMilli())
+		}
+	}
+	for recurring.isExcluded(recurring.NextAt) {
+		if !recurring.advance(rule, time.UnixMilli(recurring.NextAt)) {
+			return nil, errNoOccurrence
+		}
+	}
+	if err = p.saveRecurringMeeting(recurring); err != nil {
+		return nil, err
+	}
+
+	if err = p.addToMeetingIndex(channelRecurringMeetingsKeyPrefix+channel.Id, recurring.ID, 0); err != nil {
+		return nil, err
+	}
+	if err = p.addToMeetingIndex(recurringMeetingsKey, recurring.ID, 0); err != nil {
+		return nil, err
+	}
+
+	return recurring, nil
+}
+
+func (p *Plugin) saveRecurringMeeting(recurring *RecurringMeeting) error {
+	b, err := json.Marshal(recurring)
+	if err != nil {
+		return err
+	}
+
+	if appErr := p.API.KVSet(recurringMeetingKeyPrefix+recurring.ID, b); appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+func (p *Plugin) getRecurringMeeting(recurringID string) (*RecurringMeeting, error) {
+	data, appErr := p.API.KVGet(recurringMeetingKeyPrefix + recurringID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if data == nil {
+		return nil, errRecurringMeetingNotFound
+	}
+
+	var recurring RecurringMeeting
+	if err := json.Unmarshal(data, &recurring); err != nil {
+		return nil, err
+	}
+	return &recurring, nil
+}
+
+// getRecurringMeetings loads the recurring meetings of an index, newest first.
+func (p *Plugin) getRecurringMeetings(key string) ([]*RecurringMeeting, error) {
+	ids, err := p.getMeetingIndex(key)
+	if err != nil {
+		return nil, err
+	}
+
+	recurringMeetings := []*RecurringMeeting{}
+	for _, id := range ids {
+		recurring, err := p.getRecurringMeeting(id)
+		if errors.Is(err, errRecurringMeetingNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		recurringMeetings = append(recurringMeetings, recurring)
+	}
+
+	return recurringMeetings, nil
+}
+
+// getChannelRecurringMeetings returns the recurring meetings of a channel, newest first.
+func (p *Plugin) getChannelRecurringMeetings(channelID string) ([]*RecurringMeeting, error) {
+	return p.getRecurringMeetings(channelRecurringMeetingsKeyPrefix + channelID)
+}
+
+// removeRecurringMeeting deletes a recurring meeting. Occurrences already scheduled are kept.
+func (p *Plugin) removeRecurringMeeting(recurring *RecurringMeeting) error {
+	if err := p.removeFromMeetingIndex(recurringMeetingsKey, recurring.ID); err != nil {
+		return err
+	}
+	if err := p.removeFromMeetingIndex(channelRecurringMeetingsKeyPrefix+recurring.ChannelID, recurring.ID); err != nil {
+		return err
+	}
+	if appErr := p.API.KVDelete(recurringMeetingKeyPrefix + recurring.ID); appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// scheduleRecurringOccurrences stores the occurrences of recurring meetings that are about to
+// start as scheduled meetings, and moves every recurring meeting to its next occurrence.
+// Occurrences missed while the plugin was not running are skipped.
+func (p *Plugin) scheduleRecurringOccurrences(now time.Time, reminder time.Duration) {
+	recurringMeetings, err := p.getRecurringMeetings(recurringMeetingsKey)
+	if err != nil {
+		mlog.Error("Unable to get the recurring meetings", mlog.Err(err))
+		return
+	}
+
+	for _, recurring := range recurringMeetings {
+		if err = p.scheduleRecurringOccurrence(recurring, now, reminder); err != nil {
+			mlog.Error("Unable to schedule the recurring meeting occurrence", mlog.String("recurring_id", recurring.ID), mlog.Err(err))
+		}
+	}
+}
+
+func (p *Plugin) scheduleRecurringOccurrence(recurring *RecurringMeeting, now time.Time, reminder time.Duration) error {
+	nextAt := time.UnixMilli(recurring.NextAt)
+	if nextAt.Add(-reminder).After(now) {
+		return nil
+	}
+
+	rule, err := recurring.RecurrenceRule()
+	if err != nil {
+		return err
+	}
+
+	duration := time.Duration(recurring.Duration) * time.Millisecond
+	if nextAt.Add(duration).After(now) && !recurring.isExcluded(recurring.NextAt) {
+		if err = p.createMeeting(&Meeting{
+			Room:        recurring.Room,
+			ChannelID:   recurring.ChannelID,
+			CreatorID:   recurring.CreatorID,
+			Topic:       recurring.Topic,
+			Personal:    recurring.Personal,
+			RecurringID: recurring.ID,
+			State:       MeetingStateScheduled,
+			StartAt:     recurring.NextAt,
+			Duration:    recurring.Duration,
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Skip every occurrence already started, and the ones missed in between.
+	if !recurring.advance(rule, nextAt) || !recurring.advance(rule, now) {
+		return p.removeRecurringMeeting(recurring)
+	}
+	return p.saveRecurringMeeting(recurring)
+}
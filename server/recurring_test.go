@@ -0,0 +1,152 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecurringMeetings(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL:          "http://test",
+			JitsiNamingScheme: "uuid",
+		},
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("KVGet", "config_test-user").Return(nil, nil)
+	mockMeetingStore(&apiMock)
+	p.SetAPI(&apiMock)
+
+	location, err := time.LoadLocation("Europe/Paris")
+	require.Nil(t, err)
+	user := &model.User{Id: "test-user", Username: "test-username"}
+	channel := &model.Channel{Id: "test-channel", Type: model.ChannelTypeOpen}
+
+	add := func(t *testing.T, value string, startAt time.Time) *RecurringMeeting {
+		rule, err := parseRecurrenceRule(value, startAt)
+		require.Nil(t, err)
+		recurring, err := p.addRecurringMeeting(user, channel, "Daily standup", rule, startAt, 15*time.Minute, nil)
+		require.Nil(t, err)
+		return recurring
+	}
+
+	t.Run("add recurring meeting", func(t *testing.T) {
+		startAt := time.Now().In(location).Add(-time.Hour).Truncate(time.Minute)
+		recurring := add(t, "daily", startAt)
+		require.Regexp(t, "^[0-9a-f-]{36}$", recurring.Room)
+		require.Equal(t, "FREQ=DAILY", recurring.Rule)
+		require.Equal(t, "Europe/Paris", recurring.Timezone)
+		// The start time already passed today, the first occurrence is tomorrow.
+		require.Equal(t, startAt.AddDate(0, 0, 1).UnixMilli(), recurring.StartAt)
+		require.Equal(t, recurring.StartAt, recurring.NextAt)
+
+		recurringMeetings, err := p.getChannelRecurringMeetings("test-channel")
+		require.Nil(t, err)
+		require.Equal(t, []*RecurringMeeting{recurring}, recurringMeetings)
+
+		require.Nil(t, p.removeRecurringMeeting(recurring))
+		recurringMeetings, err = p.getChannelRecurringMeetings("test-channel")
+		require.Nil(t, err)
+		require.Empty(t, recurringMeetings)
+		_, err = p.getRecurringMeeting(recurring.ID)
+		require.ErrorIs(t, err, errRecurringMeetingNotFound)
+	})
+
+	t.Run("rule without upcoming occurrence", func(t *testing.T) {
+		rule, err := parseRecurrenceRule("FREQ=DAILY;UNTIL=20200101", time.Now())
+		require.Nil(t, err)
+		_, err = p.addRecurringMeeting(user, channel, "Daily standup", rule, time.Now(), time.Hour, nil)
+		require.ErrorIs(t, err, errNoOccurrence)
+	})
+
+	t.Run("schedule the upcoming occurrence in the same room", func(t *testing.T) {
+		now := time.Now()
+		recurring := add(t, "daily", now.In(location).Add(3*time.Minute).Truncate(time.Minute))
+
+		p.scheduleRecurringOccurrences(now, 5*time.Minute)
+
+		scheduled, err := p.getIndexedMeetings(scheduledMeetingsKey, 0, nil)
+		require.Nil(t, err)
+		require.Len(t, scheduled, 1)
+		require.Equal(t, recurring.Room, scheduled[0].Room)
+		require.Equal(t, recurring.ID, scheduled[0].RecurringID)
+		require.Equal(t, recurring.StartAt, scheduled[0].StartAt)
+		require.Equal(t, MeetingStateScheduled, scheduled[0].State)
+
+		updated, err := p.getRecurringMeeting(recurring.ID)
+		require.Nil(t, err)
+		require.Equal(t, 1, updated.Occurrences)
+		require.Equal(t, recurring.FirstOccurrence().AddDate(0, 0, 1).UnixMilli(), updated.NextAt)
+
+		// The next occurrence is not scheduled before it is due.
+		p.scheduleRecurringOccurrences(now, 5*time.Minute)
+		scheduled, err = p.getIndexedMeetings(scheduledMeetingsKey, 0, nil)
+		require.Nil(t, err)
+		require.Len(t, scheduled, 1)
+
+		require.Nil(t, p.removeRecurringMeeting(updated))
+		require.Nil(t, p.removeFromMeetingIndex(scheduledMeetingsKey, scheduled[0].ID))
+	})
+
+	t.Run("missed occurrences are skipped", func(t *testing.T) {
+		now := time.Now()
+		recurring := add(t, "daily", now.In(location).Add(time.Minute).Truncate(time.Minute))
+
+		// The plugin did not run for three days.
+		later := now.Add(3 * 24 * time.Hour)
+		p.scheduleRecurringOccurrences(later, 5*time.Minute)
+
+		scheduled, err := p.getIndexedMeetings(scheduledMeetingsKey, 0, nil)
+		require.Nil(t, err)
+		require.Empty(t, scheduled)
+
+		updated, err := p.getRecurringMeeting(recurring.ID)
+		require.Nil(t, err)
+		require.Equal(t, 3, updated.Occurrences)
+		require.True(t, time.UnixMilli(updated.NextAt).After(later))
+
+		require.Nil(t, p.removeRecurringMeeting(updated))
+	})
+
+	t.Run("excluded occurrences are not scheduled", func(t *testing.T) {
+		now := time.Now()
+		recurring := add(t, "daily", now.In(location).Add(3*time.Minute).Truncate(time.Minute))
+		recurring.ExDates = []int64{recurring.NextAt}
+		require.Nil(t, p.saveRecurringMeeting(recurring))
+
+		p.scheduleRecurringOccurrences(now, 5*time.Minute)
+
+		scheduled, err := p.getIndexedMeetings(scheduledMeetingsKey, 0, nil)
+		require.Nil(t, err)
+		require.Empty(t, scheduled)
+
+		updated, err := p.getRecurringMeeting(recurring.ID)
+		require.Nil(t, err)
+		require.Equal(t, 1, updated.Occurrences)
+		require.Equal(t, recurring.FirstOccurrence().AddDate(0, 0, 1).UnixMilli(), updated.NextAt)
+
+		require.Nil(t, p.removeRecurringMeeting(updated))
+	})
+
+	t.Run("recurring meeting is removed after the last occurrence", func(t *testing.T) {
+		now := time.Now()
+		recurring := add(t, "FREQ=DAILY;COUNT=1", now.In(location).Add(3*time.Minute).Truncate(time.Minute))
+
+		p.scheduleRecurringOccurrences(now, 5*time.Minute)
+
+		scheduled, err := p.getIndexedMeetings(scheduledMeetingsKey, 0, nil)
+		require.Nil(t, err)
+		require.Len(t, scheduled, 1)
+
+		_, err = p.getRecurringMeeting(recurring.ID)
+		require.ErrorIs(t, err, errRecurringMeetingNotFound)
+		recurringMeetings, err := p.getRecurringMeetings(recurringMeetingsKey)
+		require.Nil(t, err)
+		require.Empty(t, recurringMeetings)
+	})
+}
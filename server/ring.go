@@ -0,0 +1,370 @@
+package main
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
+	"github.com/mattermost/mattermost/server/public/shared/mlog"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ringEvent is published to a user being called, and ringStopEvent to the user and the caller
+	// once the user answered or missed the call.
+	ringEvent     = "ring"
+	ringStopEvent = "ring_stop"
+
+	RingStateRinging  = "ringing"
+	RingStateAccepted = "accepted"
+	RingStateDeclined = "declined"
+	RingStateMissed   = "missed"
+
+	ringingMeetingsKey = "meetings_ringing"
+	ringJobKey         = "ring_timeouts_job"
+	ringJobInterval    = 5 * time.Second
+	defaultRingTimeout = 30
+)
+
+var errNotRung = errors.New("the user was not called")
+
+// MeetingRing is a member of a direct or group message channel called when a meeting started in
+// the channel. PostID is the post of the Jitsi bot notifying the user.
+type MeetingRing struct {
+	UserID string `json:"user_id"`
+	PostID string `json:"post_id,omitempty"`
+	State  string `json:"state"`
+}
+
+// updateRingJob runs the background job turning the calls nobody answered into missed calls
+// only while ringing is enabled, so that the plugin does not poll the KV store otherwise. Calls
+// still ringing when it is disabled are handled once it is enabled again.
+func (p *Plugin) updateRingJob() error {
+	p.ringJobLock.Lock()
+	defer p.ringJobLock.Unlock()
+
+	enabled := p.getConfiguration().JitsiRingDirectMessages
+	if enabled && p.ringJob == nil {
+		job, err := cluster.Schedule(p.API, ringJobKey, cluster.MakeWaitForInterval(ringJobInterval), p.runRingTimeouts)
+		if err != nil {
+			return errors.Wrap(err, "failed to schedule the ring timeouts job")
+		}
+		p.ringJob = job
+	} else if !enabled && p.ringJob != nil {
+		p.closeRingJob()
+	}
+	return nil
+}
+
+func (p *Plugin) stopRingJob() {
+	p.ringJobLock.Lock()
+	defer p.ringJobLock.Unlock()
+
+	if p.ringJob != nil {
+		p.closeRingJob()
+	}
+}
+
+func (p *Plugin) closeRingJob() {
+	if err := p.ringJob.Close(); err != nil {
+		p.API.LogWarn("failed to close the ring timeouts job", "error", err.Error())
+	}
+	p.ringJob = nil
+}
+
+// ringChannelMembers calls the other members of the direct or group message channel a meeting
+// started in, with a notification of the Jitsi bot and a websocket event. Bots and users who do
+// not want to be disturbed are skipped.
+func (p *Plugin) ringChannelMembers(caller *model.User, channel *model.Channel, meeting *Meeting) error {
+	var rings []*MeetingRing
+	for page := 0; ; page++ {
+		members, appErr := p.API.GetChannelMembers(channel.Id, page, channelMembersPerPage)
+		if appErr != nil {
+			return appErr
+		}
+		for _, member := range members {
+			if member.UserId == caller.Id || member.UserId == p.botID {
+				continue
+			}
+			ring, err := p.ringUser(caller, member.UserId, meeting)
+			if err != nil {
+				mlog.Warn("Unable to call the user", mlog.String("user_id", member.UserId), mlog.String("meeting_id", meeting.ID), mlog.Err(err))
+				continue
+			}
+			if ring != nil {
+				rings = append(rings, ring)
+			}
+		}
+		if len(members) < channelMembersPerPage {
+			break
+		}
+	}
+	if len(rings) == 0 {
+		return nil
+	}
+
+	ringUntil := time.Now().Add(time.Duration(p.getConfiguration().GetRingTimeout()) * time.Second).UnixMilli()
+	if _, err := p.updateMeeting(meeting.ID, func(meeting *Meeting) error {
+		meeting.Rings = rings
+		meeting.RingUntil = ringUntil
+		return nil
+	}); err != nil {
+		return err
+	}
+	return p.addToMeetingIndex(ringingMeetingsKey, meeting.ID, 0)
+}
+
+// ringUser notifies a user of an incoming call, unless they are a bot or do not want to be
+// disturbed, in which case it returns nil.
+func (p *Plugin) ringUser(caller *model.User, userID string, meeting *Meeting) (*MeetingRing, error) {
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if user.IsBot || user.DeleteAt > 0 {
+		return nil, nil
+	}
+	status, appErr := p.API.GetUserStatus(userID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if status.Status == model.StatusDnd {
+		return nil, nil
+	}
+
+	channel, appErr := p.API.GetDirectChannel(userID, p.botID)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	l := p.b.GetUserLocalizer(userID)
+	apiURL := *p.API.GetConfig().ServiceSettings.SiteURL + "/plugins/jitsi/api/v1/meetings/" + url.PathEscape(meeting.ID) + "/ring"
+	post := &model.Post{
+		UserId:    p.botID,
+		ChannelId: channel.Id,
+		Message: p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.ring.calling",
+				Other: "@{{.Caller}} is calling you.",
+			},
+			TemplateData: map[string]string{"Caller": caller.Username},
+		}),
+	}
+	post.AddProp("attachments", []*model.SlackAttachment{{
+		Title: meeting.Topic,
+		Actions: []*model.PostAction{{
+			Id:    "accept",
+			Type:  model.PostActionTypeButton,
+			Style: "success",
+			Name: p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "jitsi.ring.accept",
+					Other: "Accept",
+				},
+			}),
+			Integration: &model.PostActionIntegration{URL: apiURL + "/accept"},
+		}, {
+			Id:    "decline",
+			Type:  model.PostActionTypeButton,
+			Style: "danger",
+			Name: p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "jitsi.ring.decline",
+					Other: "Decline",
+				},
+			}),
+			Integration: &model.PostActionIntegration{URL: apiURL + "/decline"},
+		}},
+	}})
+	post.AddProp("meeting_ring_id", meeting.ID)
+	createdPost, appErr := p.API.CreatePost(post)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	p.API.PublishWebSocketEvent(ringEvent, map[string]any{
+		"meeting_id": meeting.ID,
+		"room":       meeting.Room,
+		"channel_id": meeting.ChannelID,
+		"post_id":    meeting.PostID,
+		"caller_id":  caller.Id,
+		"timeout":    p.getConfiguration().GetRingTimeout(),
+	}, &model.WebsocketBroadcast{UserId: userID})
+
+	return &MeetingRing{UserID: userID, PostID: createdPost.Id, State: RingStateRinging}, nil
+}
+
+// answerRing records the answer of a called user and updates their notification. Users can still
+// accept a call they missed while the meeting runs.
+func (p *Plugin) answerRing(meetingID string, userID string, state string) (*Meeting, error) {
+	var ring *MeetingRing
+	meeting, err := p.updateMeeting(meetingID, func(meeting *Meeting) error {
+		ring = nil
+		if meeting.State == MeetingStateEnded {
+			return errMeetingAlreadyEnded
+		}
+		for _, r := range meeting.Rings {
+			if r.UserID == userID {
+				ring = r
+			}
+		}
+		if ring == nil {
+			return errNotRung
+		}
+		ring.State = state
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	l := p.b.GetUserLocalizer(userID)
+	message := p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "jitsi.ring.accepted",
+			Other: "You accepted the call.",
+		},
+	})
+	if state == RingStateDeclined {
+		message = p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.ring.declined",
+				Other: "You declined the call.",
+			},
+		})
+		p.API.SendEphemeralPost(meeting.CreatorID, &model.Post{
+			UserId:    p.botID,
+			ChannelId: meeting.ChannelID,
+			RootId:    meeting.RootID,
+			Message: p.b.LocalizeWithConfig(p.b.GetUserLocalizer(meeting.CreatorID), &i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "jitsi.ring.declined_by",
+					Other: "@{{.Username}} declined the call.",
+				},
+				TemplateData: map[string]string{"Username": p.username(userID)},
+			}),
+		})
+	}
+	p.updateRingPost(ring, meeting, message)
+	p.publishRingStop(meeting, ring)
+	return meeting, nil
+}
+
+// runRingTimeouts turns the calls which were not answered in time, or whose meeting ended, into
+// missed calls.
+func (p *Plugin) runRingTimeouts() {
+	meetings, err := p.getIndexedMeetings(ringingMeetingsKey, 0, nil)
+	if err != nil {
+		mlog.Error("Unable to get the ringing meetings", mlog.Err(err))
+		return
+	}
+
+	now := model.GetMillis()
+	for _, meeting := range meetings {
+		if meeting.RingUntil > now && meeting.State != MeetingStateEnded {
+			continue
+		}
+
+		var missed []*MeetingRing
+		updated, err := p.updateMeeting(meeting.ID, func(meeting *Meeting) error {
+			missed = nil
+			for _, ring := range meeting.Rings {
+				if ring.State == RingStateRinging {
+					ring.State = RingStateMissed
+					missed = append(missed, ring)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			mlog.Error("Unable to update the missed calls", mlog.String("meeting_id", meeting.ID), mlog.Err(err))
+			continue
+		}
+
+		for _, ring := range missed {
+			p.sendMissedCall(updated, ring)
+		}
+
+		if err = p.removeFromMeetingIndex(ringingMeetingsKey, meeting.ID); err != nil {
+			mlog.Error("Unable to remove the meeting from the ringing meetings", mlog.String("meeting_id", meeting.ID), mlog.Err(err))
+		}
+	}
+}
+
+// sendMissedCall tells a user they missed a call, with a link to the meeting if it still runs.
+func (p *Plugin) sendMissedCall(meeting *Meeting, ring *MeetingRing) {
+	l := p.b.GetUserLocalizer(ring.UserID)
+	message := p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "jitsi.ring.missed",
+			Other: "You missed a call from @{{.Caller}}.",
+		},
+		TemplateData: map[string]string{"Caller": p.username(meeting.CreatorID)},
+	})
+	p.updateRingPost(ring, meeting, message)
+	p.publishRingStop(meeting, ring)
+
+	channel, appErr := p.API.GetDirectChannel(ring.UserID, p.botID)
+	if appErr != nil {
+		mlog.Warn("Unable to get the direct channel of the missed call", mlog.String("user_id", ring.UserID), mlog.Err(appErr))
+		return
+	}
+	if meeting.State != MeetingStateEnded && meeting.PostID != "" {
+		message += " " + p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.ring.missed_join",
+				Other: "[Join the meeting]({{.PostURL}})",
+			},
+			TemplateData: map[string]string{"PostURL": *p.API.GetConfig().ServiceSettings.SiteURL + "/_redirect/pl/" + meeting.PostID},
+		})
+	}
+	post := &model.Post{
+		UserId:    p.botID,
+		ChannelId: channel.Id,
+		Message:   message,
+	}
+	post.AddProp("meeting_missed_call_id", meeting.ID)
+	if _, appErr = p.API.CreatePost(post); appErr != nil {
+		mlog.Warn("Unable to post the missed call", mlog.String("user_id", ring.UserID), mlog.Err(appErr))
+	}
+}
+
+// updateRingPost replaces the actions of the notification of a call with the outcome of the call.
+func (p *Plugin) updateRingPost(ring *MeetingRing, meeting *Meeting, message string) {
+	if ring.PostID == "" {
+		return
+	}
+	post, appErr := p.API.GetPost(ring.PostID)
+	if appErr != nil {
+		mlog.Warn("Unable to get the call notification", mlog.String("post_id", ring.PostID), mlog.Err(appErr))
+		return
+	}
+	post.AddProp("attachments", []*model.SlackAttachment{{
+		Title: meeting.Topic,
+		Text:  message,
+	}})
+	if _, appErr = p.API.UpdatePost(post); appErr != nil {
+		mlog.Warn("Unable to update the call notification", mlog.String("post_id", ring.PostID), mlog.Err(appErr))
+	}
+}
+
+// publishRingStop stops the ringing on the clients of the called user, and tells the caller.
+func (p *Plugin) publishRingStop(meeting *Meeting, ring *MeetingRing) {
+	payload := map[string]any{
+		"meeting_id": meeting.ID,
+		"user_id":    ring.UserID,
+		"state":      ring.State,
+	}
+	p.API.PublishWebSocketEvent(ringStopEvent, payload, &model.WebsocketBroadcast{UserId: ring.UserID})
+	p.API.PublishWebSocketEvent(ringStopEvent, payload, &model.WebsocketBroadcast{UserId: meeting.CreatorID})
+}
+
+func (p *Plugin) username(userID string) string {
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		return userID
+	}
+	return user.Username
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRingChannelMembers(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{JitsiURL: "http://test", JitsiRingDirectMessages: true, JitsiRingTimeout: 20},
+		botID:         "test-bot-id",
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("GetBundlePath").Return("..", nil)
+	config := model.Config{}
+	config.SetDefaults()
+	config.ServiceSettings.SiteURL = model.NewPointer("http://mattermost")
+	apiMock.On("GetConfig").Return(&config, nil)
+	mockMeetingStore(&apiMock)
+	store := mockKVStore(&apiMock, "config_", ringingMeetingsKey)
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	caller := &model.User{Id: "caller", Username: "carol", Locale: "en"}
+	apiMock.On("GetUser", "caller").Return(caller, nil)
+	apiMock.On("GetUser", "callee").Return(&model.User{Id: "callee", Username: "alice", Locale: "en"}, nil)
+	apiMock.On("GetUser", "busy").Return(&model.User{Id: "busy", Username: "bob", Locale: "en"}, nil)
+	apiMock.On("GetUser", "other-bot").Return(&model.User{Id: "other-bot", IsBot: true}, nil)
+	apiMock.On("GetUserStatus", "callee").Return(&model.Status{UserId: "callee", Status: model.StatusOnline}, nil)
+	apiMock.On("GetUserStatus", "busy").Return(&model.Status{UserId: "busy", Status: model.StatusDnd}, nil)
+	apiMock.On("GetChannelMembers", "test-group", 0, channelMembersPerPage).Return(model.ChannelMembers{
+		{UserId: "caller"}, {UserId: "callee"}, {UserId: "busy"}, {UserId: "other-bot"},
+	}, nil)
+	apiMock.On("GetDirectChannel", "callee", "test-bot-id").Return(&model.Channel{Id: "callee-bot-dm"}, nil)
+
+	meeting := &Meeting{Room: "test-room", ChannelID: "test-group", CreatorID: "caller", Topic: "Call", PostID: "meeting-post", State: MeetingStateActive}
+	require.Nil(t, p.createMeeting(meeting))
+
+	ringPost := &model.Post{Id: "ring-post", ChannelId: "callee-bot-dm"}
+	apiMock.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.ChannelId == "callee-bot-dm" && post.GetProp("meeting_ring_id") == meeting.ID
+	})).Return(func(post *model.Post) *model.Post {
+		require.Equal(t, "@carol is calling you.", post.Message)
+		actions := post.Attachments()[0].Actions
+		require.Len(t, actions, 2)
+		require.Equal(t, "http://mattermost/plugins/jitsi/api/v1/meetings/"+meeting.ID+"/ring/accept", actions[0].Integration.URL)
+		require.Equal(t, "http://mattermost/plugins/jitsi/api/v1/meetings/"+meeting.ID+"/ring/decline", actions[1].Integration.URL)
+		ringPost.Props = post.Props
+		return ringPost
+	}, nil).Once()
+	apiMock.On("PublishWebSocketEvent", ringEvent, mock.MatchedBy(func(payload map[string]any) bool {
+		return payload["meeting_id"] == meeting.ID && payload["caller_id"] == "caller" && payload["timeout"] == 20
+	}), &model.WebsocketBroadcast{UserId: "callee"}).Return().Once()
+
+	require.Nil(t, p.ringChannelMembers(caller, &model.Channel{Id: "test-group", Type: model.ChannelTypeGroup}, meeting))
+
+	stored, err := p.getMeeting(meeting.ID)
+	require.Nil(t, err)
+	require.Equal(t, []*MeetingRing{{UserID: "callee", PostID: "ring-post", State: RingStateRinging}}, stored.Rings)
+	require.Greater(t, stored.RingUntil, model.GetMillis())
+	require.Contains(t, string(store[ringingMeetingsKey]), meeting.ID)
+
+	apiMock.On("GetPost", "ring-post").Return(func(string) *model.Post { return ringPost.Clone() }, nil)
+	apiMock.On("UpdatePost", mock.MatchedBy(func(post *model.Post) bool { return post.Id == "ring-post" })).Return(func(post *model.Post) *model.Post {
+		ringPost = post
+		return post
+	}, nil)
+
+	answer := func(userID string, answer string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/meetings/"+meeting.ID+"/ring/"+answer, strings.NewReader(`{"post_id": "ring-post"}`))
+		r.Header.Set("Mattermost-User-Id", userID)
+		p.ServeHTTP(&plugin.Context{}, w, r)
+		return w
+	}
+
+	t.Run("users who were not called cannot answer", func(t *testing.T) {
+		require.Equal(t, http.StatusForbidden, answer("busy", "accept").Code)
+		require.Equal(t, http.StatusNotFound, answer("callee", "maybe").Code)
+	})
+
+	t.Run("decline", func(t *testing.T) {
+		apiMock.On("PublishWebSocketEvent", ringStopEvent, map[string]any{"meeting_id": meeting.ID, "user_id": "callee", "state": RingStateDeclined}, mock.Anything).Return().Twice()
+		apiMock.On("SendEphemeralPost", "caller", &model.Post{
+			UserId:    "test-bot-id",
+			ChannelId: "test-group",
+			Message:   "@alice declined the call.",
+		}).Return(nil).Once()
+
+		require.Equal(t, http.StatusOK, answer("callee", "decline").Code)
+		require.Equal(t, "You declined the call.", ringPost.Attachments()[0].Text)
+		require.Empty(t, ringPost.Attachments()[0].Actions)
+	})
+
+	t.Run("accept", func(t *testing.T) {
+		apiMock.On("PublishWebSocketEvent", ringStopEvent, map[string]any{"meeting_id": meeting.ID, "user_id": "callee", "state": RingStateAccepted}, mock.Anything).Return().Twice()
+
+		w := answer("callee", "accept")
+		require.Equal(t, http.StatusOK, w.Code)
+		var response model.PostActionIntegrationResponse
+		require.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Equal(t, "[Join Meeting](http://test/test-room#config.subject=%22Call%22&config.callDisplayName=%22Call%22)", response.EphemeralText)
+		require.Equal(t, "You accepted the call.", ringPost.Attachments()[0].Text)
+	})
+
+	t.Run("missed call", func(t *testing.T) {
+		_, err := p.updateMeeting(meeting.ID, func(meeting *Meeting) error {
+			meeting.Rings[0].State = RingStateRinging
+			meeting.RingUntil = model.GetMillis() - 1
+			return nil
+		})
+		require.Nil(t, err)
+
+		apiMock.On("PublishWebSocketEvent", ringStopEvent, map[string]any{"meeting_id": meeting.ID, "user_id": "callee", "state": RingStateMissed}, mock.Anything).Return().Twice()
+		apiMock.On("CreatePost", &model.Post{
+			UserId:    "test-bot-id",
+			ChannelId: "callee-bot-dm",
+			Message:   "You missed a call from @carol. [Join the meeting](http://mattermost/_redirect/pl/meeting-post)",
+			Props:     model.StringInterface{"meeting_missed_call_id": meeting.ID},
+		}).Return(&model.Post{}, nil).Once()
+
+		p.runRingTimeouts()
+
+		stored, err := p.getMeeting(meeting.ID)
+		require.Nil(t, err)
+		require.Equal(t, RingStateMissed, stored.Rings[0].State)
+		require.Equal(t, "You missed a call from @carol.", ringPost.Attachments()[0].Text)
+		require.NotContains(t, string(store[ringingMeetingsKey]), meeting.ID)
+	})
+}
+
+func TestUpdateRingJob(t *testing.T) {
+	p := Plugin{configuration: &configuration{}}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	p.SetAPI(&apiMock)
+
+	// Nothing polls the KV store while ringing is disabled.
+	require.Nil(t, p.updateRingJob())
+	require.Nil(t, p.ringJob)
+	p.stopRingJob()
+}
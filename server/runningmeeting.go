@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+const (
+	joinRunningMeetingChannel = "channel"
+	joinRunningMeetingThread  = "thread"
+
+	defaultJoinRunningMeetingWindow = 10
+	startMeetingLockKeyPrefix       = "start_meeting_"
+	startMeetingLockTimeout         = 10 * time.Second
+)
+
+// joinRunningMeetingKey returns the key of the channel or thread a meeting started in, depending
+// on JitsiJoinRunningMeeting, or an empty key when meetings are never joined instead of started.
+func (p *Plugin) joinRunningMeetingKey(channelID string, rootID string) string {
+	switch p.getConfiguration().JitsiJoinRunningMeeting {
+	case joinRunningMeetingChannel:
+		return channelID
+	case joinRunningMeetingThread:
+		return channelID + "_" + rootID
+	default:
+		return ""
+	}
+}
+
+// lockMeetingStart serializes the meetings started in a channel or thread across the cluster, so
+// that concurrent clicks on the start button agree on the same meeting. The returned function
+// releases the lock, and does nothing once it was released.
+func (p *Plugin) lockMeetingStart(channelID string, rootID string) (func(), error) {
+	key := p.joinRunningMeetingKey(channelID, rootID)
+	if key == "" {
+		return func() {}, nil
+	}
+
+	mutex, err := cluster.NewMutex(p.API, startMeetingLockKeyPrefix+key)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), startMeetingLockTimeout)
+	defer cancel()
+	if err = mutex.LockWithContext(ctx); err != nil {
+		return nil, err
+	}
+	var once sync.Once
+	return func() { once.Do(mutex.Unlock) }, nil
+}
+
+// getJoinableMeeting returns the meeting started in a channel or thread within the last
+// JitsiJoinRunningMeetingWindow minutes that has not ended, which a new meeting joins instead of
+// splitting the channel in two rooms.
+func (p *Plugin) getJoinableMeeting(channelID string, rootID string) (*Meeting, error) {
+	config := p.getConfiguration()
+	if p.joinRunningMeetingKey(channelID, rootID) == "" {
+		return nil, errMeetingNotFound
+	}
+
+	meetings, err := p.getChannelMeetings(channelID, runningMeetingLookup)
+	if err != nil {
+		return nil, err
+	}
+
+	since := time.Now().Add(-time.Duration(config.GetJoinRunningMeetingWindow()) * time.Minute)
+	for _, meeting := range meetings {
+		if meeting.State != MeetingStateActive && meeting.State != MeetingStateExpired {
+			continue
+		}
+		if config.JitsiJoinRunningMeeting == joinRunningMeetingThread && meeting.RootID != rootID {
+			continue
+		}
+		if meeting.StartTime().After(since) {
+			return meeting, nil
+		}
+	}
+
+	return nil, errMeetingNotFound
+}
+
+// sendMeetingAlreadyRunning tells a user starting a meeting to join the running one instead.
+func (p *Plugin) sendMeetingAlreadyRunning(userID string, meeting *Meeting, channelID string, rootID string) {
+	l := p.b.GetUserLocalizer(userID)
+	p.API.SendEphemeralPost(userID, &model.Post{
+		UserId:    p.botID,
+		ChannelId: channelID,
+		RootId:    rootID,
+		Message: p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.start_meeting.already_running",
+				Other: "A meeting is already running, [join it here]({{.PostURL}}).",
+			},
+			TemplateData: map[string]string{
+				"PostURL": *p.API.GetConfig().ServiceSettings.SiteURL + "/_redirect/pl/" + meeting.PostID,
+			},
+		}),
+	})
+}
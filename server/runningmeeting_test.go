@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi/experimental/telemetry"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetJoinableMeeting(t *testing.T) {
+	p := Plugin{configuration: &configuration{JitsiJoinRunningMeetingWindow: 10}}
+	apiMock := plugintest.API{}
+	mockMeetingStore(&apiMock)
+	p.SetAPI(&apiMock)
+
+	now := time.Now()
+	for _, meeting := range []*Meeting{
+		{Room: "old", ChannelID: "test-channel", CreateAt: now.Add(-time.Hour).UnixMilli()},
+		{Room: "thread", ChannelID: "test-channel", RootID: "test-root", CreateAt: now.Add(-5 * time.Minute).UnixMilli()},
+		{Room: "ended", ChannelID: "test-channel", State: MeetingStateEnded, CreateAt: now.Add(-time.Minute).UnixMilli()},
+	} {
+		require.Nil(t, p.createMeeting(meeting))
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		_, err := p.getJoinableMeeting("test-channel", "")
+		require.ErrorIs(t, err, errMeetingNotFound)
+	})
+
+	t.Run("channel", func(t *testing.T) {
+		p.configuration.JitsiJoinRunningMeeting = joinRunningMeetingChannel
+		meeting, err := p.getJoinableMeeting("test-channel", "")
+		require.Nil(t, err)
+		require.Equal(t, "thread", meeting.Room)
+	})
+
+	t.Run("thread", func(t *testing.T) {
+		p.configuration.JitsiJoinRunningMeeting = joinRunningMeetingThread
+		meeting, err := p.getJoinableMeeting("test-channel", "test-root")
+		require.Nil(t, err)
+		require.Equal(t, "thread", meeting.Room)
+
+		_, err = p.getJoinableMeeting("test-channel", "")
+		require.ErrorIs(t, err, errMeetingNotFound)
+	})
+
+	t.Run("window", func(t *testing.T) {
+		p.configuration.JitsiJoinRunningMeetingWindow = 2
+		_, err := p.getJoinableMeeting("test-channel", "test-root")
+		require.ErrorIs(t, err, errMeetingNotFound)
+	})
+}
+
+func TestStartMeetingJoinsRunningMeeting(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL:                "http://test",
+			JitsiNamingScheme:       jitsiNameSchemeUUID,
+			JitsiJoinRunningMeeting: joinRunningMeetingChannel,
+		},
+		botID: "test-bot-id",
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("GetBundlePath").Return("..", nil)
+	config := model.Config{}
+	config.SetDefaults()
+	config.ServiceSettings.SiteURL = model.NewPointer("http://mattermost")
+	apiMock.On("GetConfig").Return(&config, nil)
+	mockMeetingStore(&apiMock)
+	store := mockKVStore(&apiMock, "config_", "mutex_")
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	for _, userID := range []string{"first", "second"} {
+		apiMock.On("GetUser", userID).Return(&model.User{Id: userID, Locale: "en"}, nil)
+		apiMock.On("GetChannelMember", "test-channel", userID).Return(&model.ChannelMember{}, nil)
+		apiMock.On("HasPermissionToChannel", userID, "test-channel", model.PermissionCreatePost).Return(true)
+	}
+	apiMock.On("GetChannel", "test-channel").Return(&model.Channel{Id: "test-channel", Type: model.ChannelTypeOpen}, nil)
+	apiMock.On("CreatePost", mock.Anything).Return(&model.Post{Id: "meeting-post"}, nil).Once()
+
+	serve := func(userID string) string {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/meetings", strings.NewReader(`{"channel_id": "test-channel"}`))
+		r.Header.Set("Mattermost-User-Id", userID)
+		p.ServeHTTP(&plugin.Context{}, w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response map[string]string
+		require.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response["meeting_id"]
+	}
+
+	meetingID := serve("first")
+	require.NotEmpty(t, meetingID)
+
+	t.Run("API", func(t *testing.T) {
+		apiMock.On("SendEphemeralPost", "second", &model.Post{
+			UserId:    "test-bot-id",
+			ChannelId: "test-channel",
+			Message:   "A meeting is already running, [join it here](http://mattermost/_redirect/pl/meeting-post).",
+		}).Return(nil).Once()
+
+		require.Equal(t, meetingID, serve("second"))
+	})
+
+	t.Run("slash command", func(t *testing.T) {
+		apiMock.On("SendEphemeralPost", "second", &model.Post{
+			UserId:    "test-bot-id",
+			ChannelId: "test-channel",
+			Message:   "A meeting is already running, [join it here](http://mattermost/_redirect/pl/meeting-post).",
+		}).Return(nil).Once()
+
+		response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{UserId: "second", ChannelId: "test-channel", Command: "/jitsi start"})
+		require.Equal(t, &model.CommandResponse{}, response)
+		require.Nil(t, err)
+	})
+
+	t.Run("explicit options start a new meeting", func(t *testing.T) {
+		p.tracker = telemetry.NewTracker(nil, "", "", "", "", "", telemetry.TrackerConfig{}, nil)
+		apiMock.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.GetProp("meeting_topic") == "Sync"
+		})).Return(&model.Post{Id: "sync-post"}, nil).Once()
+
+		response, appErr := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{UserId: "second", ChannelId: "test-channel", Command: "/jitsi start Sync"})
+		require.Equal(t, &model.CommandResponse{}, response)
+		require.Nil(t, appErr)
+
+		running, err := p.getRunningChannelMeeting("test-channel")
+		require.Nil(t, err)
+		require.Equal(t, "Sync", running.Topic)
+	})
+
+	t.Run("the start lock is released", func(t *testing.T) {
+		require.NotContains(t, store, "mutex_start_meeting_test-channel")
+	})
+
+	t.Run("the start lock is released before the password is sent", func(t *testing.T) {
+		unlocked, unlockedBeforeSend := false, false
+		apiMock.On("CreatePost", mock.Anything).Return(&model.Post{Id: "protected-post"}, nil).Once()
+		apiMock.On("GetChannelMembers", "test-channel", 0, channelMembersPerPage).Run(func(mock.Arguments) {
+			unlockedBeforeSend = unlocked
+		}).Return(model.ChannelMembers{}, nil).Once()
+
+		_, err := p.startMeetingWithOptions(&model.User{Id: "first"}, &model.Channel{Id: "test-channel", Type: model.ChannelTypeOpen}, startMeetingOptions{
+			Password: "secret",
+			Unlock:   func() { unlocked = true },
+		})
+		require.Nil(t, err)
+		p.passwordSends.Wait()
+		require.True(t, unlockedBeforeSend)
+	})
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/mattermost/mattermost/server/public/shared/mlog"
+	"github.com/pkg/errors"
+)
+
+const schedulerJobKey = "scheduled_meetings_job"
+const schedulerInterval = time.Minute
+const defaultScheduledMeetingDuration = time.Hour
+
+var errStartInThePast = errors.New("the meeting start time is in the past")
+
+// startScheduler starts the background job posting scheduled meetings. The job is backed by the
+// KV store, so that only one node of a cluster runs it at a time and it resumes after restarts.
+func (p *Plugin) startScheduler() error {
+	job, err := cluster.Schedule(p.API, schedulerJobKey, cluster.MakeWaitForRoundedInterval(schedulerInterval), p.runScheduledMeetings)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule the meetings job")
+	}
+	p.schedulerJob = job
+	return nil
+}
+
+func (p *Plugin) stopScheduler() {
+	if p.schedulerJob == nil {
+		return
+	}
+	if err := p.schedulerJob.Close(); err != nil {
+		p.API.LogWarn("failed to close the scheduled meetings job", "error", err.Error())
+	}
+	p.schedulerJob = nil
+}
+
+// generateMeetingName returns a new meeting name following the given naming scheme, and whether
+// it is a personal meeting name.
+func (p *Plugin) generateMeetingName(user *model.User, channel *model.Channel, namingScheme string) (string, bool, error) {
+	switch namingScheme {
+	case jitsiNameSchemeUUID:
+		return generateUUIDName(), false, nil
+	case jitsiNameSchemeMattermost:
+		if channel.Type == model.ChannelTypeDirect || channel.Type == model.ChannelTypeGroup {
+			return generatePersonalMeetingName(user.Username), true, nil
+		}
+		team, appErr := p.API.GetTeam(channel.TeamId)
+		if appErr != nil {
+			return "", false, appErr
+		}
+		return generateTeamChannelName(team.Name, channel.Name), false, nil
+	default:
+		return generateEnglishTitleName(), false, nil
+	}
+}
+
+// scheduleMeeting stores a meeting starting in the future and announces it in the channel. The
+// meeting name is generated right away so that it can be shared before the meeting starts.
+func (p *Plugin) scheduleMeeting(user *model.User, channel *model.Channel, topic string, startAt time.Time, duration time.Duration, rootID string) (*Meeting, error) {
+	if !startAt.After(time.Now()) {
+		return nil, errStartInThePast
+	}
+
+	userConfig, _, err := p.resolveUserConfig(user.Id, channel.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	room, personal, err := p.generateMeetingName(user, channel, *userConfig.NamingScheme)
+	if err != nil {
+		return nil, err
+	}
+
+	meeting := &Meeting{
+		Room:      room,
+		ChannelID: channel.Id,
+		RootID:    rootID,
+		CreatorID: user.Id,
+		Topic:     topic,
+		Personal:  personal,
+		State:     MeetingStateScheduled,
+		StartAt:   startAt.UnixMilli(),
+		Duration:  duration.Milliseconds(),
+	}
+	if err = p.createMeeting(meeting); err != nil {
+		return nil, err
+	}
+
+	l := p.b.GetServerLocalizer()
+	post := &model.Post{
+		UserId:    p.botID,
+		ChannelId: channel.Id,
+		RootId:    rootID,
+		Message: p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.schedule_meeting.announcement",
+				Other: "@{{.Username}} scheduled **{{.Topic}}** on {{.Datetime}} for {{.Duration}}.\nMeeting ID: `{{.MeetingID}}`. The meeting link will be posted here {{.Minutes}} minutes before the meeting starts. [Add to calendar]({{.InviteURL}})",
+			},
+			TemplateData: map[string]string{
+				"Username":  user.Username,
+				"Topic":     topic,
+				"Datetime":  startAt.In(user.GetTimezoneLocation()).Format("Mon Jan 2 15:04 MST 2006"),
+				"Duration":  duration.String(),
+				"MeetingID": room,
+				"Minutes":   strconv.Itoa(p.getConfiguration().JitsiScheduleReminderTime),
+				"InviteURL": p.getMeetingInviteURL(meeting.ID),
+			},
+		}),
+	}
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		mlog.Warn("Unable to announce the scheduled meeting", mlog.String("meeting_id", meeting.ID), mlog.Err(appErr))
+	}
+
+	return meeting, nil
+}
+
+// runScheduledMeetings posts the meeting link of every scheduled meeting that is about to start,
+// including the upcoming occurrences of recurring meetings.
+func (p *Plugin) runScheduledMeetings() {
+	now := time.Now()
+	reminder := time.Duration(p.getConfiguration().JitsiScheduleReminderTime) * time.Minute
+
+	p.scheduleRecurringOccurrences(now, reminder)
+
+	meetings, err := p.getIndexedMeetings(scheduledMeetingsKey, 0, nil)
+	if err != nil {
+		mlog.Error("Unable to get the scheduled meetings", mlog.Err(err))
+		return
+	}
+
+	for _, meeting := range meetings {
+		switch {
+		case meeting.State != MeetingStateScheduled:
+			// The meeting was cancelled with /jitsi end before it started.
+		case meeting.StartTime().Add(-reminder).After(now):
+			continue
+		case meeting.StartTime().Add(meeting.PlannedDuration()).Before(now):
+			// The plugin was not running while the meeting should have taken place.
+			if _, err = p.updateMeeting(meeting.ID, func(m *Meeting) error {
+				m.State = MeetingStateEnded
+				m.EndAt = model.GetMillis()
+				return nil
+			}); err != nil {
+				mlog.Error("Unable to end the missed scheduled meeting", mlog.String("meeting_id", meeting.ID), mlog.Err(err))
+				continue
+			}
+		default:
+			if err = p.startScheduledMeeting(meeting); err != nil {
+				var limitErr *rateLimitError
+				if errors.As(err, &limitErr) {
+					// The meeting is started on a later run, until its planned end.
+					mlog.Debug("The scheduled meeting is rate limited", mlog.String("meeting_id", meeting.ID), mlog.Err(err))
+					continue
+				}
+				mlog.Error("Unable to start the scheduled meeting", mlog.String("meeting_id", meeting.ID), mlog.Err(err))
+				continue
+			}
+		}
+
+		if err = p.removeFromMeetingIndex(scheduledMeetingsKey, meeting.ID); err != nil {
+			mlog.Error("Unable to remove the meeting from the scheduled meetings", mlog.String("meeting_id", meeting.ID), mlog.Err(err))
+		}
+	}
+}
+
+func (p *Plugin) startScheduledMeeting(meeting *Meeting) error {
+	user, appErr := p.API.GetUser(meeting.CreatorID)
+	if appErr != nil {
+		return appErr
+	}
+
+	channel, appErr := p.API.GetChannel(meeting.ChannelID)
+	if appErr != nil {
+		return appErr
+	}
+
+	// The policy may have changed since the meeting was scheduled, or the channel been archived.
+	if err := p.checkChannelStartPolicy(user, channel); err != nil {
+		if !isStartPolicyError(err) {
+			return err
+		}
+		return p.skipScheduledMeeting(meeting, user, err)
+	}
+	if err := p.checkStartRateLimit(user.Id, channel.Id); err != nil {
+		return err
+	}
+
+	_, err := p.startMeetingWithOptions(user, channel, startMeetingOptions{
+		MeetingID:          meeting.Room,
+		Topic:              meeting.Topic,
+		RootID:             meeting.RootID,
+		ScheduledMeetingID: meeting.ID,
+		AsBot:              true,
+	})
+	return err
+}
+
+// skipScheduledMeeting ends a scheduled meeting the policy no longer lets its creator start, and
+// tells them why in a direct message of the Jitsi bot.
+func (p *Plugin) skipScheduledMeeting(meeting *Meeting, user *model.User, policyErr error) error {
+	if _, err := p.updateMeeting(meeting.ID, func(m *Meeting) error {
+		m.State = MeetingStateEnded
+		m.EndAt = model.GetMillis()
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	channel, appErr := p.API.GetDirectChannel(user.Id, p.botID)
+	if appErr != nil {
+		return appErr
+	}
+	l := p.b.GetUserLocalizer(user.Id)
+	if _, appErr = p.API.CreatePost(&model.Post{
+		UserId:    p.botID,
+		ChannelId: channel.Id,
+		Message: p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.schedule_meeting.skipped",
+				Other: "Your scheduled meeting **{{.Topic}}** was not started: {{.Reason}}",
+			},
+			TemplateData: map[string]string{
+				"Topic":  meeting.Topic,
+				"Reason": p.startPolicyMessage(l, policyErr),
+			},
+		}),
+	}); appErr != nil {
+		mlog.Warn("Unable to notify the user of the skipped meeting", mlog.String("meeting_id", meeting.ID), mlog.Err(appErr))
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleMeeting(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL:                  "http://test",
+			JitsiNamingScheme:         "uuid",
+			JitsiScheduleReminderTime: 5,
+		},
+		botID: "test-bot-id",
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("GetBundlePath").Return("..", nil)
+	config := model.Config{}
+	config.SetDefaults()
+	apiMock.On("GetConfig").Return(&config, nil)
+	apiMock.On("KVGet", "config_test-user").Return(nil, nil)
+	mockMeetingStore(&apiMock)
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	user := &model.User{Id: "test-user", Username: "test-username"}
+	channel := &model.Channel{Id: "test-channel", Type: model.ChannelTypeOpen}
+
+	t.Run("start in the past", func(t *testing.T) {
+		_, err := p.scheduleMeeting(user, channel, "Sprint review", time.Now().Add(-time.Minute), time.Hour, "")
+		require.ErrorIs(t, err, errStartInThePast)
+	})
+
+	t.Run("start in the future", func(t *testing.T) {
+		apiMock.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.UserId == "test-bot-id" && post.ChannelId == "test-channel"
+		})).Return(&model.Post{}, nil).Once()
+
+		startAt := time.Now().Add(24 * time.Hour).Truncate(time.Minute)
+		meeting, err := p.scheduleMeeting(user, channel, "Sprint review", startAt, 30*time.Minute, "")
+		require.Nil(t, err)
+		require.Regexp(t, "^[0-9a-f-]{36}$", meeting.Room)
+		require.Equal(t, MeetingStateScheduled, meeting.State)
+		require.Equal(t, startAt.UnixMilli(), meeting.StartAt)
+		require.Equal(t, 30*time.Minute, meeting.PlannedDuration())
+
+		scheduled, err := p.getIndexedMeetings(scheduledMeetingsKey, 0, nil)
+		require.Nil(t, err)
+		require.Equal(t, []*Meeting{meeting}, scheduled)
+	})
+}
+
+func TestRunScheduledMeetings(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL:                  "http://test",
+			JitsiScheduleReminderTime: 5,
+		},
+		botID: "test-bot-id",
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("GetBundlePath").Return("..", nil)
+	config := model.Config{}
+	config.SetDefaults()
+	apiMock.On("GetConfig").Return(&config, nil)
+	mockMeetingStore(&apiMock)
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	now := time.Now()
+	schedule := func(room string, startAt time.Time, state string) *Meeting {
+		channelID := "test-channel"
+		if room == "denied" {
+			channelID = "archived-channel"
+		}
+		meeting := &Meeting{Room: room, ChannelID: channelID, CreatorID: "test-user", Topic: room, State: MeetingStateScheduled, StartAt: startAt.UnixMilli(), Duration: time.Hour.Milliseconds()}
+		require.Nil(t, p.createMeeting(meeting))
+		if state != MeetingStateScheduled {
+			_, err := p.updateMeeting(meeting.ID, func(m *Meeting) error {
+				m.State = state
+				return nil
+			})
+			require.Nil(t, err)
+		}
+		return meeting
+	}
+	due := schedule("due", now.Add(3*time.Minute), MeetingStateScheduled)
+	future := schedule("future", now.Add(24*time.Hour), MeetingStateScheduled)
+	missed := schedule("missed", now.Add(-3*time.Hour), MeetingStateScheduled)
+	cancelled := schedule("cancelled", now.Add(time.Minute), MeetingStateEnded)
+	denied := schedule("denied", now.Add(time.Minute), MeetingStateScheduled)
+
+	apiMock.On("GetUser", "test-user").Return(&model.User{Id: "test-user", Username: "test-username"}, nil)
+	apiMock.On("GetChannel", "test-channel").Return(&model.Channel{Id: "test-channel", Type: model.ChannelTypeOpen}, nil)
+	apiMock.On("GetChannel", "archived-channel").Return(&model.Channel{Id: "archived-channel", Type: model.ChannelTypeOpen, DeleteAt: 1}, nil)
+	apiMock.On("HasPermissionToChannel", "test-user", "test-channel", model.PermissionCreatePost).Return(true)
+	apiMock.On("GetDirectChannel", "test-user", "test-bot-id").Return(&model.Channel{Id: "test-dm"}, nil)
+	apiMock.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.ChannelId == "test-dm" && strings.Contains(post.Message, "**denied** was not started")
+	})).Return(&model.Post{Id: "denied-post"}, nil).Once()
+	apiMock.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.UserId == "test-bot-id" && post.Type == "custom_jitsi" && post.GetProp("meeting_id") == "due"
+	})).Return(&model.Post{Id: "due-post"}, nil).Once()
+
+	p.runScheduledMeetings()
+
+	meeting, err := p.getMeeting(due.ID)
+	require.Nil(t, err)
+	require.Equal(t, MeetingStateActive, meeting.State)
+	require.Equal(t, "due-post", meeting.PostID)
+
+	meeting, err = p.getMeeting(future.ID)
+	require.Nil(t, err)
+	require.Equal(t, MeetingStateScheduled, meeting.State)
+
+	meeting, err = p.getMeeting(missed.ID)
+	require.Nil(t, err)
+	require.Equal(t, MeetingStateEnded, meeting.State)
+	require.Empty(t, meeting.PostID)
+
+	meeting, err = p.getMeeting(cancelled.ID)
+	require.Nil(t, err)
+	require.Equal(t, MeetingStateEnded, meeting.State)
+
+	meeting, err = p.getMeeting(denied.ID)
+	require.Nil(t, err)
+	require.Equal(t, MeetingStateEnded, meeting.State)
+	require.Empty(t, meeting.PostID)
+
+	scheduled, err := p.getIndexedMeetings(scheduledMeetingsKey, 0, nil)
+	require.Nil(t, err)
+	require.Len(t, scheduled, 1)
+	require.Equal(t, future.ID, scheduled[0].ID)
+}
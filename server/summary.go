@@ -0,0 +1,142 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// meetingAttendance lists who attended a meeting: the Mattermost users, identified by the user ID
+// of their JWT, and the number of guests.
+type meetingAttendance struct {
+	UserIDs []string
+	Guests  int
+}
+
+// getMeetingAttendance returns the distinct attendees of a meeting. Guests rejoining the meeting
+// get a new occupant, so they are told apart by their name.
+func getMeetingAttendance(meeting *Meeting) meetingAttendance {
+	var attendance meetingAttendance
+	users := map[string]bool{}
+	guests := map[string]bool{}
+	for _, participant := range meeting.Participants {
+		if participant.UserID != "" {
+			if !users[participant.UserID] {
+				users[participant.UserID] = true
+				attendance.UserIDs = append(attendance.UserIDs, participant.UserID)
+			}
+			continue
+		}
+
+		guest := participant.Name
+		if guest == "" {
+			guest = participant.OccupantID
+		}
+		guests[guest] = true
+	}
+	attendance.Guests = len(guests)
+	return attendance
+}
+
+// postMeetingSummary replies to the post of an ended meeting with its start and end times, its
+// duration and its attendees, when the Jitsi server reported them.
+func (p *Plugin) postMeetingSummary(meeting *Meeting) error {
+	if meeting.PostID == "" {
+		return nil
+	}
+
+	location := time.UTC
+	if creator, appErr := p.API.GetUser(meeting.CreatorID); appErr == nil {
+		location = creator.GetTimezoneLocation()
+	}
+
+	l := p.b.GetServerLocalizer()
+	startedAt := meeting.StartTime()
+	endedAt := time.UnixMilli(meeting.EndAt)
+	lines := []string{
+		p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.meeting_summary.title",
+				Other: "#### Summary of {{.Topic}}",
+			},
+			TemplateData: map[string]string{"Topic": meeting.Topic},
+		}),
+		p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "jitsi.meeting_summary.times",
+				Other: "* Started on {{.Start}}, ended on {{.End}} (duration {{.Duration}})",
+			},
+			TemplateData: map[string]string{
+				"Start":    startedAt.In(location).Format("Mon Jan 2 15:04 MST 2006"),
+				"End":      endedAt.In(location).Format("Mon Jan 2 15:04 MST 2006"),
+				"Duration": max(endedAt.Sub(startedAt).Round(time.Second), 0).String(),
+			},
+		}),
+	}
+
+	if len(meeting.Participants) > 0 {
+		attendance := getMeetingAttendance(meeting)
+		if len(attendance.UserIDs) > 0 {
+			lines = append(lines, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "jitsi.meeting_summary.participants",
+					Other: "* Participants: {{.Participants}}",
+				},
+				TemplateData: map[string]string{"Participants": strings.Join(p.participantNames(meeting, attendance.UserIDs), ", ")},
+			}))
+		}
+		if attendance.Guests > 0 {
+			lines = append(lines, p.b.LocalizeWithConfig(l, &i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "jitsi.meeting_summary.guests",
+					One:   "* {{.Count}} guest",
+					Other: "* {{.Count}} guests",
+				},
+				TemplateData: map[string]string{"Count": strconv.Itoa(attendance.Guests)},
+				PluralCount:  attendance.Guests,
+			}))
+		}
+	}
+
+	// Replies go to the root of the thread the meeting post is part of.
+	rootID := meeting.RootID
+	if rootID == "" {
+		rootID = meeting.PostID
+	}
+	post := &model.Post{
+		UserId:    p.botID,
+		ChannelId: meeting.ChannelID,
+		RootId:    rootID,
+		Message:   strings.Join(lines, "\n"),
+	}
+	post.AddProp("meeting_summary_id", meeting.ID)
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// participantNames returns the display names of the Mattermost users who attended a meeting,
+// sorted, falling back to the name they had in the meeting for the users who no longer exist.
+// The names are not mentions, so that the summary does not notify every attendee.
+func (p *Plugin) participantNames(meeting *Meeting, userIDs []string) []string {
+	names := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if user, appErr := p.API.GetUser(userID); appErr == nil {
+			names = append(names, user.GetDisplayName(model.ShowNicknameFullName))
+			continue
+		}
+		for _, participant := range meeting.Participants {
+			if participant.UserID == userID && participant.Name != "" {
+				names = append(names, participant.Name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
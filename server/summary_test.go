@@ -0,0 +1,113 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMeetingAttendance(t *testing.T) {
+	meeting := &Meeting{Participants: []*MeetingParticipant{
+		{OccupantID: "a/1", UserID: "member"},
+		{OccupantID: "b/1", Name: "Visitor"},
+		{OccupantID: "a/2", UserID: "member"},
+		{OccupantID: "b/2", Name: "Visitor"},
+		{OccupantID: "c/1"},
+		{OccupantID: "d/1", UserID: "creator"},
+	}}
+
+	require.Equal(t, meetingAttendance{UserIDs: []string{"member", "creator"}, Guests: 2}, getMeetingAttendance(meeting))
+}
+
+func TestPostMeetingSummary(t *testing.T) {
+	p := Plugin{configuration: &configuration{JitsiURL: "http://test", JitsiMeetingSummary: true}, botID: "test-bot-id"}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	apiMock.On("GetBundlePath").Return("..", nil)
+	config := model.Config{}
+	config.SetDefaults()
+	apiMock.On("GetConfig").Return(&config, nil)
+	mockMeetingStore(&apiMock)
+	p.SetAPI(&apiMock)
+
+	i18nBundle, err := i18n.InitBundle(p.API, filepath.Join("assets", "i18n"))
+	require.Nil(t, err)
+	p.b = i18nBundle
+
+	apiMock.On("GetUser", "creator").Return(&model.User{Id: "creator", Username: "carol", Timezone: model.StringMap{
+		"useAutomaticTimezone": "false",
+		"manualTimezone":       "Europe/Paris",
+	}}, nil)
+	apiMock.On("GetUser", "member").Return(&model.User{Id: "member", Username: "alice", FirstName: "Alice", LastName: "Liddell"}, nil)
+	apiMock.On("GetUser", "deleted").Return(nil, &model.AppError{})
+
+	startAt := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+
+	t.Run("with participants", func(t *testing.T) {
+		meeting := &Meeting{
+			ID:        "test-meeting",
+			ChannelID: "test-channel",
+			CreatorID: "creator",
+			Topic:     "Retrospective",
+			PostID:    "meeting-post",
+			CreateAt:  startAt.UnixMilli(),
+			EndAt:     startAt.Add(45*time.Minute + 30*time.Second).UnixMilli(),
+			Participants: []*MeetingParticipant{
+				{OccupantID: "a/1", UserID: "member", Name: "Alice"},
+				{OccupantID: "b/1", UserID: "deleted", Name: "Bob"},
+				{OccupantID: "c/1", UserID: "creator", Name: "Carol"},
+				{OccupantID: "d/1", Name: "Visitor"},
+			},
+		}
+		apiMock.On("CreatePost", &model.Post{
+			UserId:    "test-bot-id",
+			ChannelId: "test-channel",
+			RootId:    "meeting-post",
+			Message:   "#### Summary of Retrospective\n* Started on Wed Oct 14 11:00 CEST 2026, ended on Wed Oct 14 11:45 CEST 2026 (duration 45m30s)\n* Participants: Alice Liddell, Bob, carol\n* 1 guest",
+			Props:     model.StringInterface{"meeting_summary_id": "test-meeting"},
+		}).Return(&model.Post{}, nil).Once()
+
+		require.Nil(t, p.postMeetingSummary(meeting))
+	})
+
+	t.Run("without participants in a thread", func(t *testing.T) {
+		meeting := &Meeting{
+			ID:        "thread-meeting",
+			ChannelID: "test-channel",
+			RootID:    "thread-root",
+			CreatorID: "creator",
+			Topic:     "Standup",
+			PostID:    "meeting-post",
+			CreateAt:  startAt.UnixMilli(),
+			EndAt:     startAt.Add(15 * time.Minute).UnixMilli(),
+		}
+		apiMock.On("CreatePost", &model.Post{
+			UserId:    "test-bot-id",
+			ChannelId: "test-channel",
+			RootId:    "thread-root",
+			Message:   "#### Summary of Standup\n* Started on Wed Oct 14 11:00 CEST 2026, ended on Wed Oct 14 11:15 CEST 2026 (duration 15m0s)",
+			Props:     model.StringInterface{"meeting_summary_id": "thread-meeting"},
+		}).Return(&model.Post{}, nil).Once()
+
+		require.Nil(t, p.postMeetingSummary(meeting))
+	})
+
+	t.Run("posted when the meeting ends", func(t *testing.T) {
+		meeting := &Meeting{Room: "test-room", ChannelID: "test-channel", CreatorID: "creator", Topic: "Sync", PostID: "sync-post"}
+		require.Nil(t, p.createMeeting(meeting))
+		apiMock.On("GetPost", "sync-post").Return(&model.Post{Id: "sync-post"}, nil).Once()
+		apiMock.On("UpdatePost", mock.Anything).Return(&model.Post{}, nil).Once()
+		apiMock.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.RootId == "sync-post" && post.GetProp("meeting_summary_id") == meeting.ID
+		})).Return(&model.Post{}, nil).Once()
+
+		_, err := p.endMeeting(meeting.ID)
+		require.Nil(t, err)
+	})
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/cristalhq/jwt/v2"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	jwtAffiliationOwner  = "owner"
+	jwtAffiliationMember = "member"
+
+	// Values of the feature settings. By default the feature is left out of the token, and the
+	// Jitsi server decides.
+	jwtFeatureDefault    = ""
+	jwtFeatureEveryone   = "everyone"
+	jwtFeatureModerators = "moderators"
+	jwtFeatureNobody     = "nobody"
+
+	issuedTokenKeyPrefix   = "token_"
+	meetingTokensKeyPrefix = "tokens_meeting_"
+
+	// maxMeetingTokens is the number of most recent tokens kept in the per meeting index. Older
+	// tokens are still revoked with their meeting, only the revocation count leaves them out.
+	maxMeetingTokens = 1000
+
+	// jwtClockSkew backdates the nbf claim, so that Jitsi servers whose clock runs slightly behind
+	// accept the tokens right away.
+	jwtClockSkew = 30 * time.Second
+
+	// issuedTokenRetention is how long token records are kept once the tokens expired.
+	issuedTokenRetention = 24 * time.Hour
+
+	tokenStatusValid   = "valid"
+	tokenStatusExpired = "expired"
+	tokenStatusRevoked = "revoked"
+)
+
+// IssuedToken is the server side record of a JWT minted by the plugin, identified by its jti claim.
+type IssuedToken struct {
+	ID        string `json:"id"`
+	MeetingID string `json:"meeting_id,omitempty"`
+	Room      string `json:"room"`
+	UserID    string `json:"user_id"`
+	IssuedAt  int64  `json:"issued_at"`
+	ExpiresAt int64  `json:"expires_at"`
+	RevokedAt int64  `json:"revoked_at,omitempty"`
+}
+
+// TokenStatus is returned by the token status endpoint.
+type TokenStatus struct {
+	ID        string `json:"jti"`
+	Status    string `json:"status"`
+	ExpiresAt int64  `json:"expires_at,omitempty"`
+	RevokedAt int64  `json:"revoked_at,omitempty"`
+}
+
+// Status returns whether the token was revoked or expired, or is still valid.
+func (t *IssuedToken) Status(now time.Time) string {
+	if t.RevokedAt > 0 {
+		return tokenStatusRevoked
+	}
+	if t.ExpiresAt > 0 && now.UnixMilli() >= t.ExpiresAt {
+		return tokenStatusExpired
+	}
+	return tokenStatusValid
+}
+
+var (
+	errTokenNotFound = errors.New("token not found")
+	errTokenRevoked  = errors.New("token revoked")
+)
+
+// MeetingTokenResponse is returned by the meeting token endpoint.
+type MeetingTokenResponse struct {
+	JWT       string `json:"jwt"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// jwtUser returns the user context of a meeting JWT. The full name and the email address are left
+// out when the privacy settings hide them from other users.
+func (p *Plugin) jwtUser(user *model.User) User {
+	sanitizedUser := user.DeepCopy()
+
+	config := p.API.GetConfig()
+	if config.PrivacySettings.ShowFullName == nil || !*config.PrivacySettings.ShowFullName {
+		sanitizedUser.FirstName = ""
+		sanitizedUser.LastName = ""
+	}
+	if config.PrivacySettings.ShowEmailAddress == nil || !*config.PrivacySettings.ShowEmailAddress {
+		sanitizedUser.Email = ""
+	}
+
+	return User{
+		Avatar: fmt.Sprintf("%s/api/v4/users/%s/image?_=%d", *config.ServiceSettings.SiteURL, sanitizedUser.Id, sanitizedUser.LastPictureUpdate),
+		Name:   sanitizedUser.GetDisplayName(model.ShowNicknameFullName),
+		Email:  sanitizedUser.Email,
+		ID:     sanitizedUser.Id,
+	}
+}
+
+// meetingJWTUser returns the user context of a JWT for a meeting. The meeting creator and the
+// channel, team and system admins moderate the meeting and bypass its lobby, other users join as
+// regular participants.
+func (p *Plugin) meetingJWTUser(user *model.User, meeting *Meeting) User {
+	jwtUser := p.jwtUser(user)
+	jwtUser.Affiliation = jwtAffiliationMember
+	if meeting != nil && p.canManageMeeting(user.Id, meeting) {
+		jwtUser.Moderator = true
+		jwtUser.Affiliation = jwtAffiliationOwner
+		jwtUser.LobbyBypass = meeting.Lobby
+	}
+	return jwtUser
+}
+
+// jwtFeatures returns the context.features claim, which enables or disables Jitsi features for
+// the user. Features without a setting are left out.
+func (p *Plugin) jwtFeatures(moderator bool) map[string]string {
+	config := p.getConfiguration()
+	settings := map[string]string{
+		"recording":     config.JitsiJWTRecording,
+		"livestreaming": config.JitsiJWTLivestreaming,
+		"transcription": config.JitsiJWTTranscription,
+		"outbound-call": config.JitsiJWTOutboundCall,
+	}
+
+	var features map[string]string
+	for feature, setting := range settings {
+		if setting == jwtFeatureDefault {
+			continue
+		}
+		if features == nil {
+			features = map[string]string{}
+		}
+		enabled := setting == jwtFeatureEveryone || (setting == jwtFeatureModerators && moderator)
+		features[feature] = strconv.FormatBool(enabled)
+	}
+	return features
+}
+
+// getJWTSubject returns the sub claim of the tokens: the configured tenant, or the Jitsi server
+// host name.
+func (p *Plugin) getJWTSubject() string {
+	if tenant := p.getConfiguration().JitsiJWTTenant; tenant != "" {
+		return tenant
+	}
+
+	// Error check is done in configuration.IsValid()
+	jURL, _ := url.Parse(p.getConfiguration().GetJitsiURL())
+	return jURL.Hostname()
+}
+
+// signMeetingClaims signs the claims of a token given to a user, after assigning it a unique ID
+// recorded server side.
+func (p *Plugin) signMeetingClaims(claims *Claims, userID string, meetingID string) (string, error) {
+	now := time.Now()
+	claims.ID = model.NewId()
+	if p.getConfiguration().JitsiJWTTimeClaims {
+		claims.IssuedAt = jwt.NewNumericDate(now)
+		claims.NotBefore = jwt.NewNumericDate(now.Add(-jwtClockSkew))
+	}
+	claims.Context.Features = p.jwtFeatures(claims.Context.User.Moderator)
+
+	token, err := p.signToken(claims)
+	if err != nil {
+		return "", err
+	}
+
+	issued := &IssuedToken{
+		ID:        claims.ID,
+		MeetingID: meetingID,
+		Room:      claims.Room,
+		UserID:    userID,
+		IssuedAt:  now.UnixMilli(),
+	}
+	if claims.ExpiresAt != nil {
+		issued.ExpiresAt = claims.ExpiresAt.UnixMilli()
+	}
+	if err := p.saveIssuedToken(issued); err != nil {
+		return "", err
+	}
+	if meetingID != "" {
+		if err := p.addToMeetingIndex(meetingTokensKeyPrefix+meetingID, issued.ID, maxMeetingTokens); err != nil {
+			return "", err
+		}
+	}
+
+	return token, nil
+}
+
+// saveIssuedToken stores the record of a token until a while after the token expires.
+func (p *Plugin) saveIssuedToken(issued *IssuedToken) error {
+	expiry := issuedTokenRetention
+	if issued.ExpiresAt > 0 {
+		expiry += time.Until(time.UnixMilli(issued.ExpiresAt))
+	}
+
+	b, err := json.Marshal(issued)
+	if err != nil {
+		return err
+	}
+	if appErr := p.API.KVSetWithExpiry(issuedTokenKeyPrefix+issued.ID, b, int64(expiry.Seconds())); appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// getIssuedToken returns the record of a token by its ID, or errTokenNotFound once the record
+// expired. Tokens issued before the tokens of their meeting were revoked are revoked too.
+func (p *Plugin) getIssuedToken(tokenID string) (*IssuedToken, error) {
+	data, appErr := p.API.KVGet(issuedTokenKeyPrefix + tokenID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if data == nil {
+		return nil, errTokenNotFound
+	}
+
+	var issued IssuedToken
+	if err := json.Unmarshal(data, &issued); err != nil {
+		return nil, err
+	}
+
+	if issued.RevokedAt == 0 && issued.MeetingID != "" {
+		meeting, err := p.getMeeting(issued.MeetingID)
+		if err != nil && !errors.Is(err, errMeetingNotFound) {
+			return nil, err
+		}
+		if meeting != nil && meeting.TokensRevokedAt >= issued.IssuedAt {
+			issued.RevokedAt = meeting.TokensRevokedAt
+		}
+	}
+	return &issued, nil
+}
+
+// signMeetingToken signs a JWT granting a single user access to a meeting room until validUntil.
+func (p *Plugin) signMeetingToken(user *model.User, meeting *Meeting, validUntil time.Time) (string, error) {
+	claims := Claims{}
+	claims.Issuer = p.getConfiguration().JitsiAppID
+	claims.Audience = []string{p.getConfiguration().JitsiAppID}
+	claims.ExpiresAt = jwt.NewNumericDate(validUntil)
+	claims.Subject = p.getJWTSubject()
+	claims.Room = meeting.Room
+	claims.Context.User = p.meetingJWTUser(user, meeting)
+
+	return p.signMeetingClaims(&claims, user.Id, meeting.ID)
+}
+
+// issueMeetingToken mints a short-lived JWT for a user joining a meeting. Meeting posts only carry
+// the room, so every participant fetches their own token right before joining. The meeting record
+// tracks the expiry of the latest token, which keeps it active while people keep joining.
+func (p *Plugin) issueMeetingToken(user *model.User, meeting *Meeting) (string, time.Time, error) {
+	if meeting.State == MeetingStateEnded {
+		return "", time.Time{}, errMeetingAlreadyEnded
+	}
+
+	validUntil := time.Now().Add(time.Duration(p.getConfiguration().JitsiLinkValidTime) * time.Minute)
+	token, err := p.signMeetingToken(user, meeting, validUntil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	_, err = p.updateMeeting(meeting.ID, func(meeting *Meeting) error {
+		if meeting.State == MeetingStateEnded {
+			return errMeetingAlreadyEnded
+		}
+		meeting.JWTExpiresAt = max(meeting.JWTExpiresAt, validUntil.UnixMilli())
+		return nil
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, validUntil, nil
+}
+
+// revokeMeetingTokens revokes every token issued for a meeting so far and returns how many of
+// its most recent tokens were still valid. Channel members can still request new tokens while the
+// meeting is running.
+func (p *Plugin) revokeMeetingTokens(meetingID string) (int, error) {
+	tokenIDs, err := p.getMeetingIndex(meetingTokensKeyPrefix + meetingID)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	revoked := 0
+	for _, tokenID := range tokenIDs {
+		issued, err := p.getIssuedToken(tokenID)
+		if errors.Is(err, errTokenNotFound) {
+			// The record expired along with the token.
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if issued.Status(now) == tokenStatusValid {
+			revoked++
+		}
+	}
+
+	// Tokens are checked against the revocation time of their meeting, which also covers the
+	// tokens that left the capped index.
+	if _, err := p.updateMeeting(meetingID, func(meeting *Meeting) error {
+		meeting.TokensRevokedAt = now.UnixMilli()
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	return revoked, nil
+}
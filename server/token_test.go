@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTFeatures(t *testing.T) {
+	p := Plugin{configuration: &configuration{}}
+	require.Nil(t, p.jwtFeatures(true))
+
+	p.configuration = &configuration{
+		JitsiJWTRecording:     jwtFeatureModerators,
+		JitsiJWTLivestreaming: jwtFeatureNobody,
+		JitsiJWTTranscription: jwtFeatureEveryone,
+	}
+	require.Equal(t, map[string]string{"recording": "true", "livestreaming": "false", "transcription": "true"}, p.jwtFeatures(true))
+	require.Equal(t, map[string]string{"recording": "false", "livestreaming": "false", "transcription": "true"}, p.jwtFeatures(false))
+
+	p.configuration.JitsiJWT = true
+	p.configuration.JitsiAppID = "test-app-id"
+	p.configuration.JitsiAppSecret = "test-secret"
+	p.configuration.JitsiJWTOutboundCall = "admins"
+	require.NotNil(t, p.configuration.IsValid())
+}
+
+func TestSignMeetingToken(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL:           "http://test",
+			JitsiJWT:           true,
+			JitsiAppID:         "test-app-id",
+			JitsiAppSecret:     "test-secret",
+			JitsiJWTTimeClaims: true,
+			JitsiJWTTenant:     "test-tenant",
+			JitsiJWTRecording:  jwtFeatureModerators,
+		},
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	config := model.Config{}
+	config.SetDefaults()
+	apiMock.On("GetConfig").Return(&config, nil)
+	store := mockMeetingStore(&apiMock)
+	p.SetAPI(&apiMock)
+
+	meeting := &Meeting{Room: "test-room", ChannelID: "test-channel", CreatorID: "creator"}
+	require.Nil(t, p.createMeeting(meeting))
+
+	now := time.Now().Truncate(time.Second)
+	validUntil := now.Add(5 * time.Minute)
+	token, err := p.signMeetingToken(&model.User{Id: "creator", Username: "creator"}, meeting, validUntil)
+	require.Nil(t, err)
+
+	claims, err := verifyJwt("test-secret", token)
+	require.Nil(t, err)
+	require.NotEmpty(t, claims.ID)
+	require.Equal(t, "test-tenant", claims.Subject)
+	require.False(t, claims.IssuedAt.Before(now))
+	require.Equal(t, claims.IssuedAt.Add(-jwtClockSkew), claims.NotBefore.Time)
+	require.Equal(t, map[string]string{"recording": "true"}, claims.Context.Features)
+
+	issued, err := p.getIssuedToken(claims.ID)
+	require.Nil(t, err)
+	require.Equal(t, meeting.ID, issued.MeetingID)
+	require.Equal(t, "test-room", issued.Room)
+	require.Equal(t, "creator", issued.UserID)
+	require.Equal(t, validUntil.UnixMilli(), issued.ExpiresAt)
+	require.Equal(t, `["`+claims.ID+`"]`, string(store[meetingTokensKeyPrefix+meeting.ID]))
+
+	_, err = p.getIssuedToken("unknown")
+	require.ErrorIs(t, err, errTokenNotFound)
+
+	t.Run("meeting link of the tenant", func(t *testing.T) {
+		require.Equal(t, "http://test/test-tenant/test-room", p.getMeetingLink("test-room"))
+	})
+
+	t.Run("without time claims and tenant", func(t *testing.T) {
+		p.configuration = p.configuration.Clone()
+		p.configuration.JitsiJWTTimeClaims = false
+		p.configuration.JitsiJWTTenant = ""
+		apiMock.On("HasPermissionToChannel", "member", "test-channel", model.PermissionManageChannelRoles).Return(false)
+
+		token, err := p.signMeetingToken(&model.User{Id: "member", Username: "member"}, meeting, validUntil)
+		require.Nil(t, err)
+		claims, err := verifyJwt("test-secret", token)
+		require.Nil(t, err)
+		require.Equal(t, "test", claims.Subject)
+		require.Nil(t, claims.IssuedAt)
+		require.Nil(t, claims.NotBefore)
+		require.Equal(t, "http://test/test-room", p.getMeetingLink("test-room"))
+	})
+}
+
+func TestMeetingAccessOptions(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL:       "http://test",
+			JitsiJWT:       true,
+			JitsiAppID:     "test-app-id",
+			JitsiAppSecret: "test-secret",
+		},
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	config := model.Config{}
+	config.SetDefaults()
+	apiMock.On("GetConfig").Return(&config, nil)
+	apiMock.On("HasPermissionToChannel", "member", "test-channel", model.PermissionManageChannelRoles).Return(false)
+	mockMeetingStore(&apiMock)
+	p.SetAPI(&apiMock)
+
+	meeting := &Meeting{Room: "test-room", ChannelID: "test-channel", CreatorID: "creator", Lobby: true, Password: "s3cret"}
+	require.Nil(t, p.createMeeting(meeting))
+
+	// The lobby and the password are advisory: the link only makes participants knock on a lobby
+	// a moderator enabled, and the password never leaves Mattermost.
+	hash := p.meetingURLConfig(meeting, nil, nil).Hash()
+	require.Equal(t, "#config.lobby.autoKnock=true", hash)
+
+	for userID, moderator := range map[string]bool{"creator": true, "member": false} {
+		token, err := p.signMeetingToken(&model.User{Id: userID, Username: userID}, meeting, time.Now().Add(time.Minute))
+		require.Nil(t, err)
+		claims, err := verifyJwt("test-secret", token)
+		require.Nil(t, err)
+		require.Equal(t, moderator, claims.Context.User.Moderator)
+		require.Equal(t, moderator, claims.Context.User.LobbyBypass)
+
+		payload, err := json.Marshal(claims)
+		require.Nil(t, err)
+		require.NotContains(t, string(payload), "s3cret")
+	}
+}
+
+func TestRevokeMeetingTokens(t *testing.T) {
+	p := Plugin{
+		configuration: &configuration{
+			JitsiURL:       "http://test",
+			JitsiJWT:       true,
+			JitsiAppID:     "test-app-id",
+			JitsiAppSecret: "test-secret",
+		},
+	}
+	apiMock := plugintest.API{}
+	defer apiMock.AssertExpectations(t)
+	config := model.Config{}
+	config.SetDefaults()
+	apiMock.On("GetConfig").Return(&config, nil)
+	store := mockMeetingStore(&apiMock)
+	p.SetAPI(&apiMock)
+
+	meeting := &Meeting{Room: "test-room", ChannelID: "test-channel", CreatorID: "creator"}
+	require.Nil(t, p.createMeeting(meeting))
+
+	user := &model.User{Id: "creator", Username: "creator"}
+	sign := func() string {
+		token, err := p.signMeetingToken(user, meeting, time.Now().Add(time.Minute))
+		require.Nil(t, err)
+		claims, err := verifyJwt("test-secret", token)
+		require.Nil(t, err)
+		return claims.ID
+	}
+	oldest := sign()
+	latest := sign()
+	// The oldest token left the capped index.
+	store[meetingTokensKeyPrefix+meeting.ID] = []byte(`["` + latest + `"]`)
+
+	revoked, err := p.revokeMeetingTokens(meeting.ID)
+	require.Nil(t, err)
+	require.Equal(t, 1, revoked)
+	for _, tokenID := range []string{oldest, latest} {
+		issued, err := p.getIssuedToken(tokenID)
+		require.Nil(t, err)
+		require.Equal(t, tokenStatusRevoked, issued.Status(time.Now()))
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	issued, err := p.getIssuedToken(sign())
+	require.Nil(t, err)
+	require.Equal(t, tokenStatusValid, issued.Status(time.Now()))
+}
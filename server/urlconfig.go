@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// URLConfig is the configuration passed in the hash of a Jitsi meeting link, which overrides the
+// config.js and interface_config.js values of the Jitsi deployment for whoever opens the link.
+// Fields left unset keep the value of the Jitsi deployment.
+type URLConfig struct {
+	Subject             string
+	CallDisplayName     string
+	StartWithAudioMuted *bool
+	StartWithVideoMuted *bool
+	StartAudioOnly      *bool
+	DisableDeepLinking  *bool
+	DefaultLanguage     string
+	ToolbarButtons      []string
+	LobbyAutoKnock      bool
+	E2EE                bool
+}
+
+// Apply overrides the fields of c set in overrides.
+func (c *URLConfig) Apply(overrides *URLConfig) {
+	if overrides.Subject != "" {
+		c.Subject = overrides.Subject
+	}
+	if overrides.CallDisplayName != "" {
+		c.CallDisplayName = overrides.CallDisplayName
+	}
+	if overrides.StartWithAudioMuted != nil {
+		c.StartWithAudioMuted = overrides.StartWithAudioMuted
+	}
+	if overrides.StartWithVideoMuted != nil {
+		c.StartWithVideoMuted = overrides.StartWithVideoMuted
+	}
+	if overrides.StartAudioOnly != nil {
+		c.StartAudioOnly = overrides.StartAudioOnly
+	}
+	if overrides.DisableDeepLinking != nil {
+		c.DisableDeepLinking = overrides.DisableDeepLinking
+	}
+	if overrides.DefaultLanguage != "" {
+		c.DefaultLanguage = overrides.DefaultLanguage
+	}
+	if overrides.ToolbarButtons != nil {
+		c.ToolbarButtons = overrides.ToolbarButtons
+	}
+	c.LobbyAutoKnock = c.LobbyAutoKnock || overrides.LobbyAutoKnock
+	c.E2EE = c.E2EE || overrides.E2EE
+}
+
+// Hash returns the hash of a meeting link carrying the configuration, starting with #, or an
+// empty string when nothing is set. Values are JSON encoded, as Jitsi parses them as JSON.
+func (c *URLConfig) Hash() string {
+	var params urlConfigParams
+	params.add("config.subject", c.Subject)
+	params.add("config.callDisplayName", c.CallDisplayName)
+	params.add("config.startWithAudioMuted", c.StartWithAudioMuted)
+	params.add("config.startWithVideoMuted", c.StartWithVideoMuted)
+	params.add("config.startAudioOnly", c.StartAudioOnly)
+	params.add("config.disableDeepLinking", c.DisableDeepLinking)
+	if c.DisableDeepLinking != nil {
+		// Older Jitsi deployments promote their mobile apps through the interface config.
+		params.add("interfaceConfig.MOBILE_APP_PROMO", !*c.DisableDeepLinking)
+	}
+	params.add("config.defaultLanguage", c.DefaultLanguage)
+	if c.ToolbarButtons != nil {
+		// Older Jitsi deployments read the toolbar buttons from the interface config.
+		params.add("config.toolbarButtons", c.ToolbarButtons)
+		params.add("interfaceConfig.TOOLBAR_BUTTONS", c.ToolbarButtons)
+	}
+	// Joining a lobby only works once a moderator enabled it, Jitsi has no setting turning it on.
+	if c.LobbyAutoKnock {
+		params.add("config.lobby.autoKnock", true)
+	}
+	if c.E2EE {
+		params.add("config.e2ee.disabled", false)
+	}
+
+	if len(params) == 0 {
+		return ""
+	}
+	return "#" + strings.Join(params, "&")
+}
+
+type urlConfigParams []string
+
+// add appends a parameter unless its value is empty or nil.
+func (params *urlConfigParams) add(key string, value any) {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return
+		}
+	case *bool:
+		if v == nil {
+			return
+		}
+	}
+
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		// Strings, booleans and string slices always encode.
+		return
+	}
+	*params = append(*params, key+"="+encodeURIComponent(strings.TrimSuffix(b.String(), "\n")))
+}
+
+// encodeURIComponent escapes a value like the JavaScript function of the same name, which is
+// what Jitsi reverses when parsing the hash. Unlike url.QueryEscape, spaces are not turned into +.
+func encodeURIComponent(value string) string {
+	return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+}
+
+// parseToolbarButtons parses a comma separated list of Jitsi toolbar buttons.
+func parseToolbarButtons(value string) []string {
+	var buttons []string
+	for _, button := range strings.Split(value, ",") {
+		if button = strings.TrimSpace(button); button != "" {
+			buttons = append(buttons, button)
+		}
+	}
+	return buttons
+}
+
+// meetingURLConfig returns the configuration of the links of a meeting: the system-wide defaults,
+// overridden by the settings of the channel and of the user opening the link, when given, then by
+// the meeting options.
+func (p *Plugin) meetingURLConfig(meeting *Meeting, channelConfig *ChannelConfig, userConfig *UserConfig) *URLConfig {
+	config := p.getConfiguration()
+	urlConfig := &URLConfig{
+		DefaultLanguage: config.JitsiDefaultLanguage,
+		ToolbarButtons:  parseToolbarButtons(config.JitsiToolbarButtons),
+	}
+	// Disabled system settings are left to the Jitsi deployment.
+	if config.JitsiStartWithAudioMuted {
+		urlConfig.StartWithAudioMuted = model.NewPointer(true)
+	}
+	if config.JitsiStartWithVideoMuted {
+		urlConfig.StartWithVideoMuted = model.NewPointer(true)
+	}
+	if config.JitsiStartAudioOnly {
+		urlConfig.StartAudioOnly = model.NewPointer(true)
+	}
+	if config.JitsiDisableDeepLinking {
+		urlConfig.DisableDeepLinking = model.NewPointer(true)
+	}
+
+	if channelConfig != nil {
+		urlConfig.Apply(&URLConfig{
+			StartWithAudioMuted: channelConfig.StartWithAudioMuted,
+			StartWithVideoMuted: channelConfig.StartWithVideoMuted,
+		})
+	}
+	if userConfig != nil {
+		urlConfig.Apply(&URLConfig{
+			StartWithAudioMuted: userConfig.StartWithAudioMuted,
+			StartWithVideoMuted: userConfig.StartWithVideoMuted,
+			StartAudioOnly:      userConfig.StartAudioOnly,
+		})
+	}
+
+	urlConfig.Apply(&URLConfig{
+		Subject:         meeting.Topic,
+		CallDisplayName: meeting.Topic,
+		LobbyAutoKnock:  meeting.Lobby,
+		E2EE:            meeting.E2EE,
+	})
+	return urlConfig
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLConfigHash(t *testing.T) {
+	t.Run("empty configuration", func(t *testing.T) {
+		require.Equal(t, "", (&URLConfig{}).Hash())
+	})
+
+	t.Run("escaping", func(t *testing.T) {
+		config := &URLConfig{Subject: `Q&A "café" 100% #1+2`}
+		require.Equal(t, "#config.subject=%22Q%26A%20%5C%22caf%C3%A9%5C%22%20100%25%20%231%2B2%22", config.Hash())
+	})
+
+	t.Run("every field", func(t *testing.T) {
+		config := &URLConfig{
+			Subject:             "Daily",
+			CallDisplayName:     "Daily call",
+			StartWithAudioMuted: model.NewPointer(true),
+			StartWithVideoMuted: model.NewPointer(false),
+			StartAudioOnly:      model.NewPointer(true),
+			DisableDeepLinking:  model.NewPointer(true),
+			DefaultLanguage:     "fr",
+			ToolbarButtons:      []string{"microphone", "camera"},
+			LobbyAutoKnock:      true,
+			E2EE:                true,
+		}
+		require.Equal(t, "#config.subject=%22Daily%22"+
+			"&config.callDisplayName=%22Daily%20call%22"+
+			"&config.startWithAudioMuted=true"+
+			"&config.startWithVideoMuted=false"+
+			"&config.startAudioOnly=true"+
+			"&config.disableDeepLinking=true"+
+			"&interfaceConfig.MOBILE_APP_PROMO=false"+
+			"&config.defaultLanguage=%22fr%22"+
+			"&config.toolbarButtons=%5B%22microphone%22%2C%22camera%22%5D"+
+			"&interfaceConfig.TOOLBAR_BUTTONS=%5B%22microphone%22%2C%22camera%22%5D"+
+			"&config.lobby.autoKnock=true"+
+			"&config.e2ee.disabled=false", config.Hash())
+	})
+}
+
+func TestParseToolbarButtons(t *testing.T) {
+	require.Nil(t, parseToolbarButtons(""))
+	require.Nil(t, parseToolbarButtons(" , "))
+	require.Equal(t, []string{"microphone", "camera", "hangup"}, parseToolbarButtons("microphone, camera,,hangup "))
+}
+
+func TestMeetingURLConfig(t *testing.T) {
+	p := Plugin{configuration: &configuration{
+		JitsiStartWithAudioMuted: true,
+		JitsiDisableDeepLinking:  true,
+		JitsiDefaultLanguage:     "de",
+		JitsiToolbarButtons:      "microphone,hangup",
+	}}
+	meeting := &Meeting{Topic: "Sync", Lobby: true}
+
+	t.Run("system settings", func(t *testing.T) {
+		config := p.meetingURLConfig(meeting, nil, nil)
+		require.Equal(t, &URLConfig{
+			Subject:             "Sync",
+			CallDisplayName:     "Sync",
+			StartWithAudioMuted: model.NewPointer(true),
+			DisableDeepLinking:  model.NewPointer(true),
+			DefaultLanguage:     "de",
+			ToolbarButtons:      []string{"microphone", "hangup"},
+			LobbyAutoKnock:      true,
+		}, config)
+	})
+
+	t.Run("user settings override system settings", func(t *testing.T) {
+		config := p.meetingURLConfig(meeting, nil, &UserConfig{
+			StartWithAudioMuted: model.NewPointer(false),
+			StartAudioOnly:      model.NewPointer(true),
+		})
+		require.Equal(t, model.NewPointer(false), config.StartWithAudioMuted)
+		require.Equal(t, model.NewPointer(true), config.StartAudioOnly)
+		require.Nil(t, config.StartWithVideoMuted)
+		require.Equal(t, "de", config.DefaultLanguage)
+	})
+
+	t.Run("channel settings override system settings", func(t *testing.T) {
+		channelConfig := &ChannelConfig{StartWithAudioMuted: model.NewPointer(false), StartWithVideoMuted: model.NewPointer(true)}
+		config := p.meetingURLConfig(meeting, channelConfig, nil)
+		require.Equal(t, model.NewPointer(false), config.StartWithAudioMuted)
+		require.Equal(t, model.NewPointer(true), config.StartWithVideoMuted)
+
+		config = p.meetingURLConfig(meeting, channelConfig, &UserConfig{StartWithVideoMuted: model.NewPointer(false)})
+		require.Equal(t, model.NewPointer(false), config.StartWithVideoMuted)
+	})
+
+	t.Run("disabled system settings are left to Jitsi", func(t *testing.T) {
+		p := Plugin{configuration: &configuration{}}
+		require.Equal(t, "", p.meetingURLConfig(&Meeting{}, &ChannelConfig{}, &UserConfig{}).Hash())
+	})
+}
@@ -23,7 +23,8 @@ export function startMeeting(channelId: string, personal: boolean = false, topic
                 parent_id: '',
                 original_id: '',
                 reply_count: 0,
-                message: 'We could not start a meeting at this time.',
+                // The server explains why the meeting policy or the rate limits rejected the meeting.
+                message: (error.status_code === 403 || error.status_code === 429) && error.message ? error.message.trim() : 'We could not start a meeting at this time.',
                 type: 'system_ephemeral',
                 props: {},
                 metadata: {
@@ -55,10 +56,10 @@ export function startMeeting(channelId: string, personal: boolean = false, topic
     };
 }
 
-export function enrichMeetingJwt(meetingJwt: string): ActionFunc {
+export function getMeetingToken(meetingId: string): ActionFunc {
     return async (): Promise<ActionResult> => {
         try {
-            const data = await Client.enrichMeetingJwt(meetingJwt);
+            const data = await Client.getMeetingToken(meetingId);
             return {data};
         } catch (error) {
             return {error};
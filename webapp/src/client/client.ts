@@ -16,8 +16,8 @@ export default class Client {
         return this.doPost(`${this.url}/api/v1/meetings`, {channel_id: channelId, personal, topic, meeting_id: meetingId});
     };
 
-    enrichMeetingJwt = async (meetingJwt: string) => {
-        return this.doPost(`${this.url}/api/v1/meetings/enrich`, {jwt: meetingJwt});
+    getMeetingToken = async (meetingId: string) => {
+        return this.doGet(`${this.url}/api/v1/meetings/${encodeURIComponent(meetingId)}/token`);
     };
 
     setUserStatus = async (userId: string | null, status: string) => {
@@ -28,6 +28,27 @@ export default class Client {
         return this.doPost(`${this.url}/api/v1/config`, {});
     };
 
+    doGet = async (url: string, headers: any = {}) => {
+        const options = {
+            method: 'get',
+            headers
+        };
+
+        const response = await fetch(url, Client4.getOptions(options));
+
+        if (response.ok) {
+            return response.json();
+        }
+
+        const text = await response.text();
+
+        throw new ClientError(Client4.url, {
+            message: text || '',
+            status_code: response.status,
+            url
+        });
+    };
+
     doPost = async (url: string, body: any, headers: any = {}) => {
         const options = {
             method: 'post',
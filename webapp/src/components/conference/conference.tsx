@@ -23,6 +23,9 @@ type Props = {
     jwt: string | null,
     showPrejoinPage: boolean,
     meetingEmbedded?: boolean,
+    startWithAudioMuted?: boolean,
+    startWithVideoMuted?: boolean,
+    startAudioOnly?: boolean,
     actions: {
         openJitsiMeeting: (post: Post | null, jwt: string | null) => void
         setUserStatus: (userId: string, status: string) => void
@@ -89,6 +92,31 @@ export default class Conference extends React.PureComponent<Props, State> {
         }
     };
 
+    // parseURLConfig turns the hash built by the server, such as
+    // #config.startWithAudioMuted=true&interfaceConfig.MOBILE_APP_PROMO=false, into the
+    // overwrites of the Jitsi external API.
+    parseURLConfig = (hash: string): {config: any, interfaceConfig: any} => {
+        const overwrites: {[key: string]: any} = {config: {}, interfaceConfig: {}};
+        for (const param of hash.replace(/^#/, '').split('&')) {
+            const [key, value] = param.split('=');
+            const path = key.split('.');
+            if (!value || !(path[0] in overwrites) || path.length < 2) {
+                continue;
+            }
+            let target = overwrites[path[0]];
+            for (const name of path.slice(1, -1)) {
+                target[name] = target[name] || {};
+                target = target[name];
+            }
+            try {
+                target[path[path.length - 1]] = JSON.parse(decodeURIComponent(value));
+            } catch (err) {
+                // Ignore the values Jitsi would not parse either.
+            }
+        }
+        return {config: overwrites.config, interfaceConfig: overwrites.interfaceConfig};
+    };
+
     initJitsi = (post: Post) => {
         const vw = this.getViewportWidth();
         const vh = this.getViewportHeight();
@@ -98,8 +126,9 @@ export default class Conference extends React.PureComponent<Props, State> {
         const noSSL = url.protocol === 'http:';
 
         const domain = url.host;
+        const urlConfig = this.parseURLConfig(post.props.meeting_url_config || '');
         const options = {
-            roomName: post.props.meeting_id,
+            roomName: post.props.meeting_tenant ? `${post.props.meeting_tenant}/${post.props.meeting_id}` : post.props.meeting_id,
             width: this.state.minimized ? MINIMIZED_WIDTH : vw,
             height: this.state.minimized ? MINIMIZED_HEIGHT : vh,
             jwt: this.props.jwt,
@@ -112,9 +141,17 @@ export default class Conference extends React.PureComponent<Props, State> {
             userInfo: {
                 displayName: this.props.currentUser.username
             },
+            interfaceConfigOverwrite: urlConfig.interfaceConfig,
             configOverwrite: {
+                ...urlConfig.config,
+
                 // Disable the pre-join page
-                prejoinPageEnabled: this.props.meetingEmbedded && this.props.showPrejoinPage
+                prejoinPageEnabled: this.props.meetingEmbedded && this.props.showPrejoinPage,
+                ...(post.props.meeting_lobby ? {lobby: {autoKnock: true}} : {}),
+                ...(post.props.meeting_e2ee ? {e2ee: {disabled: false}} : {}),
+                ...(typeof this.props.startWithAudioMuted === 'boolean' ? {startWithAudioMuted: this.props.startWithAudioMuted} : {}),
+                ...(typeof this.props.startWithVideoMuted === 'boolean' ? {startWithVideoMuted: this.props.startWithVideoMuted} : {}),
+                ...(typeof this.props.startAudioOnly === 'boolean' ? {startAudioOnly: this.props.startAudioOnly} : {})
             }
         };
         this.api = new (window as any).JitsiMeetExternalAPI(domain, options);
@@ -11,13 +11,21 @@ import Conference from './conference';
 
 function mapStateToProps(state: GlobalState) {
     const config = state[`plugins-${manifest.id}` as plugin].config;
+    const post = state[`plugins-${manifest.id}` as plugin].openMeeting;
+
+    // The settings of the channel apply unless the user saved their own.
+    const userSettings = config.user_settings || [];
+    const props: {[key: string]: any} = post?.props || {};
 
     return {
         currentUser: getCurrentUser(state),
-        post: state[`plugins-${manifest.id}` as plugin].openMeeting,
+        post,
         jwt: state[`plugins-${manifest.id}` as plugin].openMeetingJwt,
-        showPrejoinPage: config.show_prejoin_page,
-        meetingEmbedded: config.embedded
+        showPrejoinPage: !userSettings.includes('show_prejoin_page') && typeof props.meeting_show_prejoin_page === 'boolean' ? props.meeting_show_prejoin_page : config.show_prejoin_page,
+        meetingEmbedded: !userSettings.includes('embedded') && typeof props.meeting_embedded === 'boolean' ? props.meeting_embedded : config.embedded,
+        startWithAudioMuted: config.start_with_audio_muted,
+        startWithVideoMuted: config.start_with_video_muted,
+        startAudioOnly: config.start_audio_only
     };
 }
 
@@ -8,7 +8,7 @@ import {Post} from 'mattermost-redux/types/posts';
 
 import {GlobalState, plugin} from 'types';
 import {displayUsernameForUser} from 'utils/user_utils';
-import {enrichMeetingJwt, openJitsiMeeting, setUserStatus} from 'actions';
+import {getMeetingToken, openJitsiMeeting, setUserStatus} from 'actions';
 import manifest from 'manifest';
 import {PostTypeJitsi} from './post_type_jitsi';
 
@@ -27,12 +27,16 @@ function mapStateToProps(state: GlobalState, ownProps: OwnProps) {
         theme: getTheme(state),
         creatorName: displayUsernameForUser(creator, state.entities.general.config),
         useMilitaryTime: getBool(state, 'display_settings', 'use_military_time', false),
-        meetingEmbedded: Boolean(config.embedded)
+        // The settings of the channel apply unless the user saved their own.
+        meetingEmbedded: Boolean(!config.user_settings?.includes('embedded') && typeof post.props.meeting_embedded === 'boolean' ? post.props.meeting_embedded : config.embedded),
+        startWithAudioMuted: config.start_with_audio_muted,
+        startWithVideoMuted: config.start_with_video_muted,
+        startAudioOnly: config.start_audio_only
     };
 }
 
 type Actions = {
-    enrichMeetingJwt: (jwt: string) => Promise<ActionResult>,
+    getMeetingToken: (meetingId: string) => Promise<ActionResult>,
     openJitsiMeeting: (post: Post | null, jwt: string | null) => ActionResult,
     setUserStatus: (userId: string, status: string) => Promise<ActionResult>,
 }
@@ -40,7 +44,7 @@ type Actions = {
 function mapDispatchToProps(dispatch: Dispatch<GenericAction>) {
     return {
         actions: bindActionCreators<ActionCreatorsMapObject<ActionFunc>, Actions>({
-            enrichMeetingJwt,
+            getMeetingToken,
             openJitsiMeeting,
             setUserStatus
         }, dispatch)
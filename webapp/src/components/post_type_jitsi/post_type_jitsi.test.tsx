@@ -33,18 +33,17 @@ describe('PostTypeJitsi', () => {
             reactions: []
         },
         props: {
-            jwt_meeting_valid_until: 123,
             meeting_link: 'http://test-meeting-link/test',
             jwt_meeting: true,
-            meeting_jwt: 'xxxxxxxxxxxx',
             meeting_topic: 'Test topic',
             meeting_id: 'test',
+            meeting_record_id: 'test-record-id',
             meeting_personal: false
         }
     };
 
     const actions = {
-        enrichMeetingJwt: jest.fn().mockImplementation(() => Promise.resolve({data: {jwt: 'test-enriched-jwt'}})),
+        getMeetingToken: jest.fn().mockImplementation(() => Promise.resolve({data: {jwt: 'test-user-jwt', expires_at: Date.now() + 60000}})),
         openJitsiMeeting: jest.fn(),
         setUserStatus: jest.fn().mockImplementation(() => Promise.resolve({data: {user_id: 'test-user-id', status: Constants.DND}}))
     };
@@ -68,27 +67,27 @@ describe('PostTypeJitsi', () => {
     };
 
     it('should render null if the post type is null', () => {
-        defaultProps.actions.enrichMeetingJwt.mockClear();
+        defaultProps.actions.getMeetingToken.mockClear();
         const props = {...defaultProps};
         delete props.post;
         const wrapper = shallow(
             <PostTypeJitsi {...props}/>
         );
         expect(wrapper).toMatchSnapshot();
-        expect(defaultProps.actions.enrichMeetingJwt).not.toBeCalled();
+        expect(defaultProps.actions.getMeetingToken).not.toBeCalled();
     });
 
-    it('should render a post if the post type is not null, and should try to enrich the token', () => {
-        defaultProps.actions.enrichMeetingJwt.mockClear();
+    it('should render a post if the post type is not null, and should not request a token before joining', () => {
+        defaultProps.actions.getMeetingToken.mockClear();
         const wrapper = shallow(
             <PostTypeJitsi {...defaultProps}/>
         );
-        expect(defaultProps.actions.enrichMeetingJwt).toBeCalled();
+        expect(defaultProps.actions.getMeetingToken).not.toBeCalled();
         expect(wrapper).toMatchSnapshot();
     });
 
-    it('should render a post without token if there is no jwt token, and shouldn\'t try to enrich the token', () => {
-        defaultProps.actions.enrichMeetingJwt.mockClear();
+    it('should render a post without token if there is no jwt token, and shouldn\'t request a token', () => {
+        defaultProps.actions.getMeetingToken.mockClear();
         const props = {
             ...defaultProps,
             post: {
@@ -104,7 +103,7 @@ describe('PostTypeJitsi', () => {
             <PostTypeJitsi {...props}/>
         );
         expect(wrapper).toMatchSnapshot();
-        expect(defaultProps.actions.enrichMeetingJwt).not.toBeCalled();
+        expect(defaultProps.actions.getMeetingToken).not.toBeCalled();
     });
 
     it('should render the default topic if the topic is empty', () => {
@@ -147,7 +146,14 @@ describe('PostTypeJitsi', () => {
         defaultProps.actions.openJitsiMeeting.mockClear();
         const props = {
             ...defaultProps,
-            meetingEmbedded: true
+            meetingEmbedded: true,
+            post: {
+                ...defaultProps.post,
+                props: {
+                    ...defaultProps.post.props,
+                    jwt_meeting: false
+                }
+            }
         };
 
         const wrapper = shallow(<PostTypeJitsi {...props}/>);
@@ -157,11 +163,58 @@ describe('PostTypeJitsi', () => {
         expect(event.preventDefault).toBeCalled();
     });
 
+    it('should request a token before calling the action to open jitsi if embedded is true', async () => {
+        defaultProps.actions.openJitsiMeeting.mockClear();
+        defaultProps.actions.getMeetingToken.mockClear();
+        const props = {
+            ...defaultProps,
+            meetingEmbedded: true
+        };
+
+        const wrapper = shallow(<PostTypeJitsi {...props}/>);
+        await (wrapper.instance() as PostTypeJitsi).getMeetingJwt();
+        wrapper.find('a.btn-primary').simulate('click', {preventDefault: jest.fn()});
+        await new Promise((resolve) => setTimeout(resolve, 0));
+        expect(defaultProps.actions.getMeetingToken).toBeCalledWith('test-record-id');
+        expect(defaultProps.actions.openJitsiMeeting).toBeCalledWith(props.post, 'test-user-jwt');
+    });
+
+    it('should request the token by room for posts without a meeting record id', async () => {
+        defaultProps.actions.getMeetingToken.mockClear();
+        const props = {
+            ...defaultProps,
+            post: {
+                ...defaultProps.post,
+                props: {
+                    ...defaultProps.post.props,
+                    meeting_record_id: undefined
+                }
+            }
+        };
+
+        const wrapper = shallow(<PostTypeJitsi {...props}/>);
+        await (wrapper.instance() as PostTypeJitsi).getMeetingJwt();
+        expect(defaultProps.actions.getMeetingToken).toBeCalledWith('test');
+    });
+
+    it('should add the user token to the meeting link once received', async () => {
+        const wrapper = shallow(<PostTypeJitsi {...defaultProps}/>);
+        await (wrapper.instance() as PostTypeJitsi).getMeetingJwt();
+        expect(wrapper.find('a.btn-primary').prop('href')).toMatch(/^http:\/\/test-meeting-link\/test\?jwt=test-user-jwt#/);
+    });
+
     it('should not prevent the default link behavior and should not call the action to open jitsi if embedded is false', () => {
         defaultProps.actions.openJitsiMeeting.mockClear();
         const props = {
             ...defaultProps,
-            meetingEmbedded: false
+            meetingEmbedded: false,
+            post: {
+                ...defaultProps.post,
+                props: {
+                    ...defaultProps.post.props,
+                    jwt_meeting: false
+                }
+            }
         };
 
         const wrapper = shallow(<PostTypeJitsi {...props}/>);
@@ -170,4 +223,21 @@ describe('PostTypeJitsi', () => {
         expect(defaultProps.actions.openJitsiMeeting).not.toBeCalled();
         expect(event.preventDefault).not.toBeCalled();
     });
+
+    it('should not render the join button once the meeting ended', () => {
+        const props = {
+            ...defaultProps,
+            post: {
+                ...defaultProps.post,
+                props: {
+                    ...defaultProps.post.props,
+                    meeting_ended_at: 456
+                }
+            }
+        };
+
+        const wrapper = shallow(<PostTypeJitsi {...props}/>);
+        expect(wrapper.find('a.btn-primary').exists()).toBe(false);
+        expect(wrapper.find({id: 'jitsi.meeting-ended-at'}).exists()).toBe(true);
+    });
 });
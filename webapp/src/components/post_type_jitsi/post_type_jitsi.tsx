@@ -5,6 +5,7 @@ import {Theme} from 'mattermost-redux/types/preferences';
 import {ActionResult} from 'mattermost-redux/types/actions';
 import Constants from 'mattermost-redux/constants/general';
 import {UserProfile} from 'mattermost-redux/types/users';
+import {Client4} from 'mattermost-redux/client';
 
 import Svgs from 'constants/svgs';
 
@@ -17,8 +18,11 @@ export type Props = {
     creatorName: string,
     useMilitaryTime: boolean,
     meetingEmbedded: boolean,
+    startWithAudioMuted?: boolean,
+    startWithVideoMuted?: boolean,
+    startAudioOnly?: boolean,
     actions: {
-        enrichMeetingJwt: (jwt: string) => Promise<ActionResult>,
+        getMeetingToken: (meetingId: string) => Promise<ActionResult>,
         openJitsiMeeting: (post: Post | null, jwt: string | null) => ActionResult,
         setUserStatus: (userId: string, status: string) => Promise<ActionResult>,
     }
@@ -26,6 +30,7 @@ export type Props = {
 
 type State = {
     meetingJwt?: string,
+    meetingJwtExpiresAt?: number,
 }
 
 export class PostTypeJitsi extends React.PureComponent<Props, State> {
@@ -35,50 +40,121 @@ export class PostTypeJitsi extends React.PureComponent<Props, State> {
         this.state = {};
     }
 
-    componentDidMount() {
+    hasValidMeetingJwt = (): boolean => {
+        return Boolean(this.state.meetingJwt && this.state.meetingJwtExpiresAt && this.state.meetingJwtExpiresAt > Date.now());
+    };
+
+    // The post only carries the room, so every user requests their own short-lived token when
+    // they join, and a new one once it expired. The server takes the request as the user joining
+    // the meeting, to set their in-call status.
+    getMeetingJwt = async (): Promise<string | null> => {
         const {post} = this.props;
-        if (post && post.props.jwt_meeting) {
-            this.props.actions.enrichMeetingJwt(post.props.meeting_jwt).then((response: any) => {
-                if (response.data) {
-                    this.setState({meetingJwt: response.data.jwt});
-                }
-            });
+        if (!post) {
+            return null;
+        }
+        if (this.hasValidMeetingJwt()) {
+            return this.state.meetingJwt || null;
+        }
+
+        // Posts created before they identified the meeting record only carry the room.
+        const response: any = await this.props.actions.getMeetingToken(post.props.meeting_record_id || post.props.meeting_id);
+        if (response.data) {
+            this.setState({meetingJwt: response.data.jwt, meetingJwtExpiresAt: response.data.expires_at});
+            return response.data.jwt;
         }
-    }
+
+        // Posts created before per-user tokens carry a token shared by everyone.
+        return post.props.meeting_jwt || null;
+    };
+
+    buildMeetingLink = (jwt: string | null): string => {
+        const props = this.props.post ? this.props.post.props : {};
+
+        let meetingLink = props.meeting_link;
+        if (jwt) {
+            meetingLink += '?jwt=' + encodeURIComponent(jwt);
+        }
+
+        // The server builds the hash from the system settings and the meeting options, posts
+        // created before only carry the meeting options.
+        const params = [];
+        if (typeof props.meeting_url_config === 'string') {
+            if (props.meeting_url_config) {
+                params.push(props.meeting_url_config.substring(1));
+            }
+        } else {
+            params.push(`config.callDisplayName=${encodeURIComponent(`"${props.meeting_topic || props.default_meeting_topic}"`)}`);
+            if (props.meeting_lobby) {
+                params.push('config.lobby.autoKnock=true');
+            }
+            if (props.meeting_e2ee) {
+                params.push('config.e2ee.disabled=false');
+            }
+        }
+        params.push(`userInfo.displayName=${encodeURIComponent(`"${this.props.currentUser.username}"`)}`);
+
+        // The settings of the user come last to override the system settings.
+        const userSettings: {[key: string]: boolean | undefined} = {
+            startWithAudioMuted: this.props.startWithAudioMuted,
+            startWithVideoMuted: this.props.startWithVideoMuted,
+            startAudioOnly: this.props.startAudioOnly
+        };
+        for (const [key, value] of Object.entries(userSettings)) {
+            if (typeof value === 'boolean') {
+                params.push(`config.${key}=${value}`);
+            }
+        }
+        return meetingLink + '#' + params.join('&');
+    };
 
     openJitsiMeeting = (e: React.MouseEvent) => {
+        const {post} = this.props;
+        if (!post) {
+            return;
+        }
+
         if (this.props.meetingEmbedded) {
             e.preventDefault();
-            if (this.props.post) {
-                // could be improved by using an enum in the future for the status
-                this.props.actions.setUserStatus(this.props.currentUser.id, Constants.DND);
-                this.props.actions.openJitsiMeeting(this.props.post, this.state.meetingJwt || this.props.post.props.meeting_jwt || null);
+
+            // could be improved by using an enum in the future for the status
+            this.props.actions.setUserStatus(this.props.currentUser.id, Constants.DND);
+            if (post.props.jwt_meeting) {
+                this.getMeetingJwt().then((jwt) => {
+                    this.props.actions.openJitsiMeeting(post, jwt);
+                });
+            } else {
+                this.props.actions.openJitsiMeeting(post, null);
             }
-        } else if (this.state.meetingJwt) {
+        } else if (post.props.jwt_meeting && !this.hasValidMeetingJwt()) {
             e.preventDefault();
-            if (this.props.post) {
-                const props = this.props.post.props;
-                let meetingLink = props.meeting_link + '?jwt=' + (this.state.meetingJwt);
-                meetingLink += `#config.callDisplayName=${encodeURIComponent(`"${props.meeting_topic || props.default_meeting_topic}"`)}`;
-                window.open(meetingLink, '_blank');
-            }
+
+            // The window is opened right away, browsers block the ones opened once the token is received.
+            const meetingWindow = window.open('', '_blank');
+            this.getMeetingJwt().then((jwt) => {
+                const meetingLink = this.buildMeetingLink(jwt);
+                if (meetingWindow) {
+                    meetingWindow.location.href = meetingLink;
+                } else {
+                    window.open(meetingLink, '_blank');
+                }
+            });
         }
     };
 
-    renderUntilDate = (post: Post, style: any): React.ReactNode => {
+    renderMeetingEnded = (post: Post, style: any): React.ReactNode => {
         const props = post.props;
 
-        if (props.jwt_meeting) {
-            const date = new Date(props.jwt_meeting_valid_until * 1000);
-            let dateStr = props.jwt_meeting_valid_until;
+        if (props.meeting_ended_at) {
+            const date = new Date(props.meeting_ended_at * 1000);
+            let dateStr = props.meeting_ended_at;
             if (!isNaN(date.getTime())) {
                 dateStr = date.toString();
             }
             return (
                 <div style={style.validUntil}>
                     <FormattedMessage
-                        id='jitsi.link-valid-until'
-                        defaultMessage=' Meeting link valid until: '
+                        id='jitsi.meeting-ended-at'
+                        defaultMessage='Meeting ended at: '
                     />
                     <b>{dateStr}</b>
                 </div>
@@ -87,6 +163,33 @@ export class PostTypeJitsi extends React.PureComponent<Props, State> {
         return null;
     };
 
+    renderParticipants = (post: Post, style: any): React.ReactNode => {
+        const props = post.props;
+
+        // The participants are only known when the Jitsi server sends its events.
+        if (props.meeting_ended_at || !props.meeting_participants_count) {
+            return null;
+        }
+        const userIds: string[] = props.meeting_participant_ids || [];
+        return (
+            <div style={style.participants}>
+                {userIds.map((userId) => (
+                    <img
+                        key={userId}
+                        alt=''
+                        style={style.avatar}
+                        src={Client4.getProfilePictureUrl(userId, 0)}
+                    />
+                ))}
+                <FormattedMessage
+                    id='jitsi.participants-in-call'
+                    defaultMessage='{count, plural, one {# person} other {# people}} in the call'
+                    values={{count: props.meeting_participants_count}}
+                />
+            </div>
+        );
+    };
+
     render() {
         const style = getStyle(this.props.theme);
         const post = this.props.post;
@@ -95,14 +198,7 @@ export class PostTypeJitsi extends React.PureComponent<Props, State> {
         }
 
         const props = post.props;
-
-        let meetingLink = props.meeting_link;
-        if (props.jwt_meeting) {
-            meetingLink += '?jwt=' + encodeURIComponent(props.meeting_jwt);
-        }
-
-        meetingLink += `#config.callDisplayName=${encodeURIComponent(`"${props.meeting_topic || props.default_meeting_topic}"`)}`;
-        meetingLink += `&userInfo.displayName=${encodeURIComponent(`"${this.props.currentUser.username}"`)}`;
+        const meetingLink = this.buildMeetingLink(props.jwt_meeting && this.hasValidMeetingJwt() ? this.state.meetingJwt || null : null);
 
         const preText = (
             <FormattedMessage
@@ -159,26 +255,29 @@ export class PostTypeJitsi extends React.PureComponent<Props, State> {
                             </span>
                             <div>
                                 <div style={style.body}>
-                                    <div>
-                                        <a
-                                            className='btn btn-lg btn-primary'
-                                            style={style.button}
-                                            target='_blank'
-                                            rel='noopener noreferrer'
-                                            onClick={this.openJitsiMeeting}
-                                            href={meetingLink}
-                                        >
-                                            <i
-                                                style={style.buttonIcon}
-                                                dangerouslySetInnerHTML={{__html: Svgs.VIDEO_CAMERA_3}}
-                                            />
-                                            <FormattedMessage
-                                                id='jitsi.join-meeting'
-                                                defaultMessage='JOIN MEETING'
-                                            />
-                                        </a>
-                                    </div>
-                                    {this.renderUntilDate(post, style)}
+                                    {!props.meeting_ended_at && (
+                                        <div>
+                                            <a
+                                                className='btn btn-lg btn-primary'
+                                                style={style.button}
+                                                target='_blank'
+                                                rel='noopener noreferrer'
+                                                onClick={this.openJitsiMeeting}
+                                                href={meetingLink}
+                                            >
+                                                <i
+                                                    style={style.buttonIcon}
+                                                    dangerouslySetInnerHTML={{__html: Svgs.VIDEO_CAMERA_3}}
+                                                />
+                                                <FormattedMessage
+                                                    id='jitsi.join-meeting'
+                                                    defaultMessage='JOIN MEETING'
+                                                />
+                                            </a>
+                                        </div>
+                                    )}
+                                    {this.renderParticipants(post, style)}
+                                    {this.renderMeetingEnded(post, style)}
                                 </div>
                             </div>
                         </div>
@@ -239,6 +338,17 @@ const getStyle = makeStyleFromTheme((theme) => {
         },
         validUntil: {
             marginTop: '10px'
+        },
+        participants: {
+            alignItems: 'center',
+            display: 'flex',
+            marginTop: '10px'
+        },
+        avatar: {
+            borderRadius: '50%',
+            height: '24px',
+            marginRight: '4px',
+            width: '24px'
         }
     };
 });
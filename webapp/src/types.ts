@@ -14,7 +14,15 @@ export type GlobalState = ReduxGlobalState & {
             // eslint-disable-next-line camelcase
             naming_scheme?: 'ask' | 'words' | 'mattermost' | 'uuid',
             // eslint-disable-next-line camelcase
-            show_prejoin_page: boolean
+            show_prejoin_page: boolean,
+            // eslint-disable-next-line camelcase
+            start_with_audio_muted?: boolean,
+            // eslint-disable-next-line camelcase
+            start_with_video_muted?: boolean,
+            // eslint-disable-next-line camelcase
+            start_audio_only?: boolean,
+            // eslint-disable-next-line camelcase
+            user_settings?: string[]
         }
     }
 }